@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"stadia-backend/config"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+)
+
+// runBacktest replays seasons from the data directory and prints the report
+func runBacktest(args []string) error {
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory with season files (default <data_dir>/seasons from config)")
+	seasons := flags.String("seasons", "", "comma-separated season names (default all)")
+	method := flags.String("method", string(models.MethodAuto), "prediction method: auto, monte_carlo, exact or heuristic")
+	preset := flags.String("preset", "", "match model preset (default simulation.default_preset from config)")
+	asJSON := flags.Bool("json", false, "print the full report as JSON")
+	flags.Parse(args)
+
+	if *dir == "" {
+		*dir = config.GetSeasonsDir()
+	}
+	model, err := services.CurrentSimulationSettings().Preset(*preset)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if *seasons != "" {
+		names = strings.Split(*seasons, ",")
+	}
+
+	loaded, err := services.LoadSeasons(*dir, names)
+	if err != nil {
+		return err
+	}
+
+	backtestService := services.NewBacktestService()
+	backtestService.SetParams(model)
+	report, err := backtestService.Run(context.Background(), loaded, models.PredictionMethod(*method))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, report)
+	}
+
+	printBacktest(report)
+	return nil
+}
+
+// printBacktest prints a backtest report as text tables
+func printBacktest(report *models.BacktestReport) {
+	fmt.Printf("Method: %s, model: %s (%.0f ms)\n\n", report.Method, report.Model.Preset, report.RuntimeMs)
+
+	fmt.Printf("%-24s %-6s %-22s %9s %9s %9s %9s %9s\n",
+		"Season", "Weeks", "Champion", "Brier", "LogLoss", "RPS", "M.Brier", "M.RPS")
+	for _, season := range report.Seasons {
+		fmt.Printf("%-24s %-6d %-22s %9.4f %9.4f %9s %9.4f %9s\n",
+			season.Season, season.Weeks, season.Champion,
+			season.Outright.Brier, season.Outright.LogLoss, formatScore(season.Outright.RPS),
+			season.Matches.Brier, formatScore(season.Matches.RPS))
+	}
+	fmt.Printf("%-24s %-6s %-22s %9.4f %9.4f %9s %9.4f %9s\n\n",
+		"Overall", "", "",
+		report.Outright.Brier, report.Outright.LogLoss, formatScore(report.Outright.RPS),
+		report.Matches.Brier, formatScore(report.Matches.RPS))
+
+	printCalibration("Championship calibration", report.OutrightCalibration)
+	printCalibration("Match calibration", report.MatchCalibration)
+}
+
+// printCalibration prints calibration buckets as a table
+func printCalibration(title string, buckets []models.CalibrationBucket) {
+	fmt.Println(title)
+	fmt.Printf("  %-11s %9s %10s %9s\n", "Range", "Forecasts", "Predicted", "Observed")
+	for _, bucket := range buckets {
+		fmt.Printf("  %3.0f%%-%4.0f%% %9d %9.1f%% %8.1f%%\n",
+			bucket.Lower*100, bucket.Upper*100, bucket.Forecasts,
+			bucket.MeanPredicted*100, bucket.ObservedFrequency*100)
+	}
+	fmt.Println()
+}
+
+// formatScore formats an optional score
+func formatScore(score *float64) string {
+	if score == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", *score)
+}
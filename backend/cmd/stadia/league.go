@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+	"text/tabwriter"
+)
+
+// teamsFile is a JSON teams file: the body of POST /api/league/initialize or a
+// plain list of teams
+type teamsFile struct {
+	Teams []teamEntry `json:"teams"`
+}
+
+// teamEntry is a team in a JSON teams file
+type teamEntry struct {
+	Name    string `json:"name"`
+	Power   int    `json:"power"`
+	Logo    string `json:"logo"`
+	Country string `json:"country"`
+}
+
+// runInit starts a new league and saves it to the state file
+func runInit(args []string) error {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	state := flags.String("state", defaultStateFile, "league state file to create")
+	teams := flags.String("teams", "", "teams file: CSV with name and power columns, or JSON")
+	competition := flags.String("competition", "", "real competition file to import instead of teams")
+	format := flags.String("format", "", "competition format: openfootball-json, openfootball-txt or football-data (default from the file extension)")
+	name := flags.String("name", "", "league name for an imported competition (default from the file)")
+	seed := flags.Int64("seed", 0, "seed for match simulation (default random)")
+	preset := flags.String("preset", "", "match model preset (default simulation.default_preset from config)")
+	flags.Parse(args)
+
+	model, err := services.CurrentSimulationSettings().Preset(*preset)
+	if err != nil {
+		return err
+	}
+
+	service := services.NewLeagueService()
+	switch {
+	case *teams != "" && *competition != "":
+		return errors.New("use either -teams or -competition")
+	case *teams != "":
+		if err := initTeams(service, *teams); err != nil {
+			return err
+		}
+	case *competition != "":
+		competitionFormat := services.CompetitionFormat(*format)
+		if competitionFormat == "" {
+			competitionFormat = services.CompetitionFormatForFile(*competition)
+		}
+		if competitionFormat == "" {
+			return errors.New("-format is required for this file extension")
+		}
+		file, err := os.Open(*competition)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if _, err := service.ImportCompetition(context.Background(), file, competitionFormat, *name); err != nil {
+			return err
+		}
+	default:
+		return errors.New("-teams or -competition is required")
+	}
+
+	if err := service.SetModel(context.Background(), model); err != nil {
+		return err
+	}
+	if *seed != 0 {
+		service.Reseed(*seed)
+	}
+	if err := saveLeague(*state, service); err != nil {
+		return err
+	}
+
+	league := service.GetLeague()
+	fmt.Printf("%s: %d teams, %d weeks, %d played (seed %d, %s model)\n",
+		league.Name, len(league.Teams), league.TotalWeeks, league.CurrentWeek, league.Seed, league.Settings.Model.Preset)
+	fmt.Printf("Saved to %s\n", *state)
+	return nil
+}
+
+// initTeams initializes the league from a CSV or JSON teams file
+func initTeams(service *services.LeagueService, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		_, err := service.ImportTeamsCSV(file)
+		return err
+	}
+
+	var entries []teamEntry
+	decoder := json.NewDecoder(file)
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return fmt.Errorf("invalid teams file: %w", err)
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		var wrapped teamsFile
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return fmt.Errorf("invalid teams file: %w", err)
+		}
+		entries = wrapped.Teams
+	}
+
+	teams := make([]*models.Team, len(entries))
+	for i, entry := range entries {
+		if entry.Name == "" {
+			return fmt.Errorf("team %d has no name", i+1)
+		}
+		if entry.Power < 1 || entry.Power > 100 {
+			return fmt.Errorf("%s: power must be between 1 and 100", entry.Name)
+		}
+		teams[i] = models.NewTeam(entry.Name, entry.Power, entry.Logo)
+		teams[i].Country = entry.Country
+	}
+	return service.InitializeLeague(teams)
+}
+
+// runPlay plays weeks and saves the league
+func runPlay(args []string) error {
+	flags := flag.NewFlagSet("play", flag.ExitOnError)
+	state := flags.String("state", defaultStateFile, "league state file")
+	weeks := flags.Int("weeks", 1, "number of weeks to play")
+	all := flags.Bool("all", false, "play the rest of the season")
+	flags.Parse(args)
+
+	service, err := loadLeague(*state)
+	if err != nil {
+		return err
+	}
+	league := service.GetLeague()
+	if *all {
+		*weeks = league.TotalWeeks - league.CurrentWeek
+	}
+	if *weeks < 1 {
+		return errors.New("there are no weeks to play")
+	}
+
+	for i := 0; i < *weeks; i++ {
+		if err := service.PlayNextWeek(context.Background()); err != nil {
+			return err
+		}
+		printWeek(league.CurrentWeek, league.GetMatchesByWeek(league.CurrentWeek))
+	}
+
+	if err := saveLeague(*state, service); err != nil {
+		return err
+	}
+	printStandings(service.GetStandingsTable())
+	return nil
+}
+
+// printWeek prints the results of a week
+func printWeek(week int, matches []*models.Match) {
+	fmt.Printf("Week %d\n", week)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, match := range matches {
+		fmt.Fprintf(w, "  %s\t%d - %d\t%s\n", match.HomeTeamName, match.HomeScore, match.AwayScore, match.AwayTeamName)
+	}
+	w.Flush()
+	fmt.Println()
+}
+
+// runStandings prints the league table
+func runStandings(args []string) error {
+	flags := flag.NewFlagSet("standings", flag.ExitOnError)
+	state := flags.String("state", defaultStateFile, "league state file")
+	asJSON := flags.Bool("json", false, "print the standings as JSON")
+	flags.Parse(args)
+
+	service, err := loadLeague(*state)
+	if err != nil {
+		return err
+	}
+
+	standings := service.GetStandingsTable()
+	if *asJSON {
+		return writeJSON(os.Stdout, standings)
+	}
+
+	league := service.GetLeague()
+	fmt.Printf("%s, week %d of %d\n\n", league.Name, league.CurrentWeek, league.TotalWeeks)
+	printStandings(standings)
+	return nil
+}
+
+// printStandings prints a league table
+func printStandings(standings []models.Standing) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Pos\tTeam\tP\tW\tD\tL\tGF\tGA\tGD\tPts\t")
+	for _, standing := range standings {
+		team := standing.Team
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%+d\t%d\t\n",
+			standing.Position, team.Name, team.Played, team.Won, team.Drawn, team.Lost,
+			team.GoalsFor, team.GoalsAgainst, team.GoalDifference(), team.Points)
+	}
+	w.Flush()
+}
+
+// runPredict prints the championship predictions
+func runPredict(args []string) error {
+	flags := flag.NewFlagSet("predict", flag.ExitOnError)
+	state := flags.String("state", defaultStateFile, "league state file")
+	method := flags.String("method", string(models.MethodAuto), "prediction method: auto, monte_carlo, exact or heuristic")
+	asJSON := flags.Bool("json", false, "print the predictions as JSON")
+	flags.Parse(args)
+
+	service, err := loadLeague(*state)
+	if err != nil {
+		return err
+	}
+
+	predictions, err := service.CalculatePredictions(context.Background(), models.PredictionMethod(*method))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(os.Stdout, predictions)
+	}
+
+	fmt.Printf("Championship after week %d (%s, %.0f ms)\n\n", predictions.Week, predictions.Method, predictions.RuntimeMs)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, prediction := range predictions.Predictions {
+		fmt.Fprintf(w, "%s\t%6.2f%%\t%s\n", prediction.TeamName, prediction.Probability, bar(prediction.Probability))
+	}
+	w.Flush()
+	return nil
+}
+
+// bar draws a percentage as a bar of up to 40 characters
+func bar(percent float64) string {
+	return strings.Repeat("#", int(percent*40/100+0.5))
+}
+
+// runExport writes a dataset or the whole league to a file
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	state := flags.String("state", defaultStateFile, "league state file")
+	dataset := flags.String("dataset", string(models.ExportStandings), "data to export: standings, matches, predictions or league")
+	format := flags.String("format", string(models.FormatCSV), "file format: csv or jsonl (league is always JSON)")
+	week := flags.Int("week", -1, "export a single week instead of the whole season")
+	output := flags.String("o", "", "output file (default standard output)")
+	flags.Parse(args)
+
+	service, err := loadLeague(*state)
+	if err != nil {
+		return err
+	}
+
+	if *dataset == "league" {
+		return writeFile(*output, func(w io.Writer) error {
+			return writeJSON(w, service.ExportSnapshot())
+		})
+	}
+
+	var exportWeek *int
+	if *week >= 0 {
+		exportWeek = week
+	}
+	table, err := service.Export(models.ExportDataset(*dataset), exportWeek)
+	if err != nil {
+		return err
+	}
+	return writeFile(*output, func(w io.Writer) error {
+		return services.WriteExport(w, table, models.ExportFormat(*format))
+	})
+}
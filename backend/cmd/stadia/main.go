@@ -0,0 +1,140 @@
+// Command stadia runs Stadia leagues and tools from the command line.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"stadia-backend/config"
+	"stadia-backend/services"
+)
+
+const usage = `Usage: stadia <command> [flags]
+
+Commands:
+  init       Start a league from a teams file or a real competition
+  play       Play the next week, several weeks or the rest of the season
+  standings  Print the league table
+  predict    Print the championship predictions
+  simulate   Simulate the rest of the season many times and summarise the outcomes
+  export     Write standings, matches, predictions or the whole league to a file
+  backtest   Replay historical seasons and score the predictions
+
+The league is kept in a state file between commands (default ` + defaultStateFile + `).
+Run "stadia <command> -h" for the flags of a command.
+`
+
+// defaultStateFile is the league state file used when -state is not given
+const defaultStateFile = "stadia-league.json"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// The output is the report; only problems are logged
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	if help := os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help"; !help {
+		if err := loadConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "init":
+		err = runInit(os.Args[2:])
+	case "play":
+		err = runPlay(os.Args[2:])
+	case "standings":
+		err = runStandings(os.Args[2:])
+	case "predict":
+		err = runPredict(os.Args[2:])
+	case "simulate":
+		err = runSimulate(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "backtest":
+		err = runBacktest(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		var importErr *services.ImportError
+		if errors.As(err, &importErr) {
+			for _, row := range importErr.Rows {
+				fmt.Fprintf(os.Stderr, "  row %d: %s\n", row.Row, row.Message)
+			}
+		}
+		os.Exit(1)
+	}
+}
+
+// loadConfig reads the server configuration, as the server does at startup,
+// for its data directory, number of simulations and model presets
+func loadConfig() error {
+	if err := config.Load("."); err != nil {
+		return err
+	}
+	services.SetSimulationSettings(services.SimulationSettings{
+		Simulations:   config.AppConfig.Simulation.Simulations,
+		DefaultPreset: config.AppConfig.Simulation.DefaultPreset,
+		Presets:       config.AppConfig.Simulation.ModelPresets(),
+	})
+	return nil
+}
+
+// loadLeague restores the league saved in a state file
+func loadLeague(path string) (*services.LeagueService, error) {
+	service := services.NewLeagueService()
+	err := service.LoadSnapshotFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no league in %s: run \"stadia init\" first", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// saveLeague writes the league to a state file as a snapshot
+func saveLeague(path string, service *services.LeagueService) error {
+	return service.SaveSnapshotFile(path, "")
+}
+
+// writeFile creates a file and writes it with write, or writes to standard
+// output when path is empty or "-"
+func writeFile(path string, write func(io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeJSON writes a value as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
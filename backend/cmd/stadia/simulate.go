@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strconv"
+	"text/tabwriter"
+)
+
+// runSimulate simulates the rest of the season many times and prints or writes
+// the outcome distribution. The league itself is not changed.
+func runSimulate(args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	state := flags.String("state", defaultStateFile, "league state file")
+	seasons := flags.Int("n", 10000, "number of seasons to simulate")
+	seed := flags.Int64("seed", 0, "seed for the simulations (default derived from the league)")
+	fromStart := flags.Bool("from-start", false, "replay the whole season from week 0 instead of the current standings")
+	qualifying := flags.Int("qualify", 0, "positions that qualify (default 2)")
+	format := flags.String("format", "text", "output format: text, json, csv or jsonl")
+	output := flags.String("o", "", "output file (default standard output)")
+	flags.Parse(args)
+
+	service, err := loadLeague(*state)
+	if err != nil {
+		return err
+	}
+
+	summary, err := service.SimulateSeasons(models.SeasonSimulationOptions{
+		Seasons:          *seasons,
+		FromStart:        *fromStart,
+		Seed:             *seed,
+		QualifyingPlaces: *qualifying,
+	})
+	if err != nil {
+		return err
+	}
+
+	var write func(io.Writer, *models.SeasonSimulationSummary) error
+	switch *format {
+	case "text":
+		write = printSeasonSummary
+	case "json":
+		write = func(w io.Writer, summary *models.SeasonSimulationSummary) error { return writeJSON(w, summary) }
+	case string(models.FormatCSV), string(models.FormatJSONLines):
+		write = func(w io.Writer, summary *models.SeasonSimulationSummary) error {
+			return services.WriteExport(w, services.SeasonSummaryTable(summary), models.ExportFormat(*format))
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	return writeFile(*output, func(w io.Writer) error { return write(w, summary) })
+}
+
+// printSeasonSummary prints a season simulation summary as text tables
+func printSeasonSummary(out io.Writer, summary *models.SeasonSimulationSummary) error {
+	fmt.Fprintf(out, "%d seasons from week %d (seed %d, %.0f ms)\n\n",
+		summary.Seasons, summary.Week, summary.Seed, summary.RuntimeMs)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "Team\tChampion\tTop %d\tAvg pos\tPoints\t5-95%%\tGD\t", summary.QualifyingPlaces)
+	for p := range summary.Teams {
+		fmt.Fprintf(w, "%s\t", ordinal(p+1))
+	}
+	fmt.Fprintln(w)
+	for _, team := range summary.Teams {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%.1f%%\t%.2f\t%.1f\t%d-%d\t%+.1f\t",
+			team.TeamName, team.ChampionProbability, team.QualificationProbability, team.AveragePosition,
+			team.Points.Mean, team.Points.P5, team.Points.P95, team.GoalDifference.Mean)
+		for _, percent := range team.Positions {
+			fmt.Fprintf(w, "%.1f%%\t", percent)
+		}
+		fmt.Fprintln(w)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	winning := summary.WinningPoints
+	_, err := fmt.Fprintf(out, "\nWinning points: %.1f on average, median %d, %d to %d\n",
+		winning.Mean, winning.Median, winning.Min, winning.Max)
+	return err
+}
+
+// ordinal formats a position as 1st, 2nd, 3rd and so on
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
@@ -1,17 +1,33 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
+	"os"
 	"path/filepath"
+	"slices"
+	"stadia-backend/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	App App `mapstructure:"app"`
-	DB  DB  `mapstructure:"db"`
+	App        App        `mapstructure:"app"`
+	DB         DB         `mapstructure:"db"`
+	Jobs       Jobs       `mapstructure:"jobs"`
+	Auth       Auth       `mapstructure:"auth"`
+	Metrics    Metrics    `mapstructure:"metrics"`
+	Log        Log        `mapstructure:"log"`
+	Tracing    Tracing    `mapstructure:"tracing"`
+	Simulation Simulation `mapstructure:"simulation"`
 }
 
 // App contains application-specific configuration
@@ -21,6 +37,33 @@ type App struct {
 	Version        string   `mapstructure:"version"`
 	Name           string   `mapstructure:"name"`
 	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	DataDir        string   `mapstructure:"data_dir"`
+	StateFile      string   `mapstructure:"state_file"` // League snapshot restored at startup and saved at shutdown; disabled when empty
+
+	// HTTP server limits
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"` // How long shutdown waits for requests and background jobs
+	DrainDelay        time.Duration `mapstructure:"drain_delay"`      // How long /readyz fails before shutdown starts
+
+	// Request limits
+	MaxBodyBytes   int64     `mapstructure:"max_body_bytes"`  // Largest request body; unlimited when 0
+	MaxTeams       int       `mapstructure:"max_teams"`       // Most teams a league may have; unlimited when 0
+	TrustedProxies []string  `mapstructure:"trusted_proxies"` // Proxies whose X-Forwarded-For gives the client IP
+	RateLimit      RateLimit `mapstructure:"rate_limit"`
+}
+
+// RateLimit contains per-client token bucket limits. Clients are told apart
+// by API key, or else by IP.
+type RateLimit struct {
+	Enabled        bool    `mapstructure:"enabled"`
+	Rate           float64 `mapstructure:"rate"`            // Requests per second to every API route
+	Burst          int     `mapstructure:"burst"`           // Requests allowed at once
+	ExpensiveRate  float64 `mapstructure:"expensive_rate"`  // Requests per second to simulation and prediction routes
+	ExpensiveBurst int     `mapstructure:"expensive_burst"` // Requests allowed at once to those routes
 }
 
 // DB contains database configuration
@@ -28,50 +71,315 @@ type DB struct {
 	URL string `mapstructure:"url"`
 }
 
+// Jobs contains background job configuration
+type Jobs struct {
+	Workers   int           `mapstructure:"workers"`    // Jobs run at the same time
+	QueueSize int           `mapstructure:"queue_size"` // Jobs waiting for a worker
+	Timeout   time.Duration `mapstructure:"timeout"`    // Default and longest run time of a job
+	Retention time.Duration `mapstructure:"retention"`  // How long finished jobs are kept
+}
+
+// Auth contains user account and token configuration
+type Auth struct {
+	Enabled  bool          `mapstructure:"enabled"`   // Require bearer tokens and enforce league ownership
+	Secret   string        `mapstructure:"secret"`    // Key that signs and validates tokens, at least 32 bytes
+	Issuer   string        `mapstructure:"issuer"`    // Issuer written to and required in tokens
+	TokenTTL time.Duration `mapstructure:"token_ttl"` // How long an issued token is valid
+	Admin    string        `mapstructure:"admin"`     // Username that owns the leagues nobody owns, such as the default league
+	// Token that registering the admin account requires, at least 16 bytes
+	AdminToken string `mapstructure:"admin_token"`
+}
+
+// Metrics contains Prometheus metrics configuration
+type Metrics struct {
+	Enabled bool   `mapstructure:"enabled"` // Serve metrics and instrument requests and simulations
+	Path    string `mapstructure:"path"`    // Route the metrics are served on
+}
+
+// Log contains logging configuration
+type Log struct {
+	Level  string `mapstructure:"level"`  // debug, info, warn or error
+	Format string `mapstructure:"format"` // json or text
+}
+
+// Tracing contains OpenTelemetry trace export configuration
+type Tracing struct {
+	Enabled     bool    `mapstructure:"enabled"`      // Export a trace of every request and background job
+	Endpoint    string  `mapstructure:"endpoint"`     // OTLP/HTTP collector, as host:port
+	Insecure    bool    `mapstructure:"insecure"`     // Export over plain HTTP instead of HTTPS
+	SampleRatio float64 `mapstructure:"sample_ratio"` // Share of traces recorded, from 0 to 1
+}
+
+// Simulation contains match model and Monte Carlo settings
+type Simulation struct {
+	Simulations   int                    `mapstructure:"simulations"`    // Monte Carlo runs per forecast, and seasons per batch simulation unless given
+	DefaultPreset string                 `mapstructure:"default_preset"` // Model preset of leagues initialized without one
+	Presets       map[string]ModelPreset `mapstructure:"presets"`        // Match model presets by name
+}
+
+// ModelPresets returns the parameters of every preset by name
+func (s Simulation) ModelPresets() map[string]models.ModelParams {
+	presets := make(map[string]models.ModelParams, len(s.Presets))
+	for name, preset := range s.Presets {
+		presets[name] = preset.Params(name)
+	}
+	return presets
+}
+
+// ModelPreset contains the parameters of a match model preset
+type ModelPreset struct {
+	HomeAdvantage       float64 `mapstructure:"home_advantage"`        // Percentage added to the power of the home team
+	BaseGoals           float64 `mapstructure:"base_goals"`            // Expected goals of each side when powers are equal
+	PowerExponent       float64 `mapstructure:"power_exponent"`        // How strongly the power ratio scales expected goals
+	NoiseMin            float64 `mapstructure:"noise_min"`             // Lowest random factor on expected goals
+	NoiseMax            float64 `mapstructure:"noise_max"`             // Highest random factor on expected goals
+	GoalCap             float64 `mapstructure:"goal_cap"`              // Most goals a side may be expected to score
+	WinProbabilityScale float64 `mapstructure:"win_probability_scale"` // Slope of the logistic win probability per power point
+}
+
+// Params returns the model parameters of a preset
+func (p ModelPreset) Params(name string) models.ModelParams {
+	return models.ModelParams{
+		Preset:              name,
+		HomeAdvantage:       p.HomeAdvantage,
+		BaseGoals:           p.BaseGoals,
+		PowerExponent:       p.PowerExponent,
+		NoiseMin:            p.NoiseMin,
+		NoiseMax:            p.NoiseMax,
+		GoalCap:             p.GoalCap,
+		WinProbabilityScale: p.WinProbabilityScale,
+	}
+}
+
 // AppConfig is the global configuration instance
 var AppConfig Config
 
-// Load reads and parses the configuration file
+// loaded is the viper instance AppConfig was loaded with, which Watch reloads
+var loaded *viper.Viper
+
+// EnvPrefix starts the environment variable of every setting: the key in upper
+// case with dots replaced by underscores, e.g. STADIA_APP_PORT for app.port.
+// Lists are comma-separated.
+const EnvPrefix = "STADIA"
+
+// Load reads the configuration file, applies environment variables and
+// validates the result. Unknown keys in the file are errors.
 func Load(processCwdir string) error {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(filepath.Join(processCwdir, "config"))
-	viper.AddConfigPath(".") // Also check current directory
-
-	// Set defaults
-	viper.SetDefault("app.mode", "debug")
-	viper.SetDefault("app.port", "8000")
-	viper.SetDefault("app.version", "1.0.0")
-	viper.SetDefault("app.name", "stadia-backend")
-	viper.SetDefault("app.allowed_origins", []string{"http://localhost", "http://localhost:8080", "http://localhost:5173", "http://localhost:3000", "https://stadiaa.netlify.app", "https://stadia-xex6.onrender.com"})
+	v, err := read(processCwdir)
+	if err != nil {
+		return err
+	}
+
+	// Unmarshal config
+	var cfg Config
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		return fmt.Errorf("error unmarshaling config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	AppConfig = cfg
+	loaded = v
+	return nil
+}
+
+// read reads the configuration file, with the defaults and environment
+// variables underneath and on top of it
+func read(processCwdir string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	v.AddConfigPath(filepath.Join(processCwdir, "config"))
+	v.AddConfigPath(".") // Also check current directory
+
+	// Every key needs a default, or its environment variable is not read
+	setDefaults(v)
 
 	// Read environment variables
-	viper.AutomaticEnv()
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
 
 	// Read config file
-	if err := viper.ReadInConfig(); err != nil {
+	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Println("Config file not found, using defaults and environment variables")
+			slog.Info("Config file not found, using defaults and environment variables")
 		} else {
-			return fmt.Errorf("error reading config file: %w", err)
+			return nil, fmt.Errorf("error reading config file: %w", err)
 		}
 	} else {
-		log.Printf("Using config file: %s", viper.ConfigFileUsed())
+		slog.Info("Using config file", "path", v.ConfigFileUsed())
 	}
+	return v, nil
+}
 
-	// Unmarshal config
-	if err := viper.Unmarshal(&AppConfig); err != nil {
-		return fmt.Errorf("error unmarshaling config: %w", err)
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("app.mode", "debug")
+	v.SetDefault("app.port", "8000")
+	v.SetDefault("app.version", "1.0.0")
+	v.SetDefault("app.name", "stadia-backend")
+	v.SetDefault("app.data_dir", "data")
+	v.SetDefault("app.state_file", "")
+	v.SetDefault("app.read_timeout", "15s")
+	v.SetDefault("app.read_header_timeout", "5s")
+	v.SetDefault("app.write_timeout", "60s")
+	v.SetDefault("app.idle_timeout", "120s")
+	v.SetDefault("app.max_header_bytes", 1<<20)
+	v.SetDefault("app.shutdown_timeout", "30s")
+	v.SetDefault("app.drain_delay", "0s")
+	v.SetDefault("app.max_body_bytes", 2<<20)
+	v.SetDefault("app.max_teams", 32)
+	v.SetDefault("app.trusted_proxies", []string{})
+	v.SetDefault("app.rate_limit.enabled", true)
+	v.SetDefault("app.rate_limit.rate", 10)
+	v.SetDefault("app.rate_limit.burst", 20)
+	v.SetDefault("app.rate_limit.expensive_rate", 1)
+	v.SetDefault("app.rate_limit.expensive_burst", 5)
+	v.SetDefault("db.url", "")
+	v.SetDefault("jobs.workers", 2)
+	v.SetDefault("jobs.queue_size", 100)
+	v.SetDefault("jobs.timeout", "5m")
+	v.SetDefault("jobs.retention", "1h")
+	v.SetDefault("auth.enabled", false)
+	v.SetDefault("auth.secret", "")
+	v.SetDefault("auth.issuer", "stadia")
+	v.SetDefault("auth.token_ttl", "24h")
+	v.SetDefault("auth.admin", "")
+	v.SetDefault("auth.admin_token", "")
+	v.SetDefault("metrics.enabled", true)
+	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "json")
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.endpoint", "localhost:4318")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("simulation.simulations", 10000)
+	v.SetDefault("simulation.default_preset", models.PresetRealistic)
+	presets := make(map[string]any)
+	for name, params := range models.BuiltinModelPresets() {
+		presets[name] = map[string]any{
+			"home_advantage": params.HomeAdvantage, "base_goals": params.BaseGoals, "power_exponent": params.PowerExponent,
+			"noise_min": params.NoiseMin, "noise_max": params.NoiseMax, "goal_cap": params.GoalCap,
+			"win_probability_scale": params.WinProbabilityScale,
+		}
 	}
-
-	return nil
+	v.SetDefault("simulation.presets", presets)
+	v.SetDefault("app.allowed_origins", []string{"http://localhost", "http://localhost:8080", "http://localhost:5173", "http://localhost:3000", "https://stadiaa.netlify.app", "https://stadia-xex6.onrender.com"})
 }
 
-// MustLoad loads configuration and panics on error
+// MustLoad loads configuration and exits on error, logging each problem found
 func MustLoad(processCwdir string) {
 	if err := Load(processCwdir); err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		logProblems("Failed to load configuration", err)
+		os.Exit(1)
+	}
+}
+
+// logProblems logs each error joined in err, at any depth, on a line of its own
+func logProblems(msg string, err error) {
+	var problems interface{ Unwrap() []error }
+	if errors.As(err, &problems) {
+		for _, problem := range problems.Unwrap() {
+			logProblems(msg, problem)
+		}
+		return
+	}
+	slog.Error(msg, "error", err)
+}
+
+// Validate checks the configuration for values the server cannot run with,
+// reporting every problem found
+func (c *Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(slices.Contains([]string{"debug", "release", "test"}, c.App.Mode), "app.mode %q is not debug, release or test", c.App.Mode)
+	port, err := strconv.Atoi(c.App.Port)
+	check(c.App.Port == "" || (err == nil && port > 0 && port < 65536), "app.port %q is not a port number from 1 to 65535", c.App.Port)
+	check(c.App.Name != "", "app.name is required")
+	check(c.App.DataDir != "", "app.data_dir is required")
+	for _, origin := range c.App.AllowedOrigins {
+		check(validOrigin(origin), "app.allowed_origins: %q is not * or an http(s) origin such as https://example.com", origin)
+	}
+	for _, proxy := range c.App.TrustedProxies {
+		check(validProxy(proxy), "app.trusted_proxies: %q is not an IP address or CIDR range", proxy)
+	}
+	check(c.App.ReadTimeout >= 0 && c.App.ReadHeaderTimeout >= 0 && c.App.WriteTimeout >= 0 && c.App.IdleTimeout >= 0,
+		"app read, read header, write and idle timeouts must not be negative")
+	check(c.App.MaxHeaderBytes >= 0, "app.max_header_bytes must not be negative")
+	check(c.App.ShutdownTimeout >= 0, "app.shutdown_timeout must not be negative")
+	check(c.App.DrainDelay >= 0, "app.drain_delay must not be negative")
+	check(c.App.MaxBodyBytes >= 0, "app.max_body_bytes must not be negative")
+	check(c.App.MaxTeams >= 0, "app.max_teams must not be negative")
+	if limits := c.App.RateLimit; limits.Enabled {
+		check(limits.Rate > 0 && limits.Burst > 0, "app.rate_limit.rate and burst must be positive")
+		check(limits.ExpensiveRate > 0 && limits.ExpensiveBurst > 0, "app.rate_limit.expensive_rate and expensive_burst must be positive")
 	}
+	if c.DB.URL != "" {
+		check(validDBURL(c.DB.URL), "db.url is not a postgres:// or postgresql:// URL with a host")
+	}
+	check(c.Jobs.Workers > 0, "jobs.workers must be positive")
+	check(c.Jobs.QueueSize >= 0, "jobs.queue_size must not be negative")
+	check(c.Jobs.Timeout >= 0, "jobs.timeout must not be negative")
+	check(c.Jobs.Retention >= 0, "jobs.retention must not be negative")
+	if c.Auth.Enabled {
+		check(len(c.Auth.Secret) >= 32, "auth.secret must be at least 32 bytes when auth is enabled")
+		check(c.Auth.Issuer != "", "auth.issuer is required when auth is enabled")
+		check(c.Auth.TokenTTL > 0, "auth.token_ttl must be positive")
+		check(c.Auth.Admin == strings.ToLower(strings.TrimSpace(c.Auth.Admin)),
+			"auth.admin %q must be a lowercase username", c.Auth.Admin)
+		check(c.Auth.Admin == "" || len(c.Auth.AdminToken) >= 16,
+			"auth.admin_token must be at least 16 bytes when auth.admin is set")
+	}
+	if c.Metrics.Enabled {
+		check(strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path %q must start with /", c.Metrics.Path)
+	}
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.Log.Level)),
+		"log.level %q is not debug, info, warn or error", c.Log.Level)
+	check(slices.Contains([]string{"json", "text"}, strings.ToLower(c.Log.Format)),
+		"log.format %q is not json or text", c.Log.Format)
+	if c.Tracing.Enabled {
+		check(c.Tracing.Endpoint != "", "tracing.endpoint is required when tracing is enabled")
+		check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	}
+	check(c.Simulation.Simulations >= 1 && c.Simulation.Simulations <= 100000, "simulation.simulations must be between 1 and 100000")
+	_, ok := c.Simulation.Presets[c.Simulation.DefaultPreset]
+	check(ok, "simulation.default_preset %q is not one of simulation.presets", c.Simulation.DefaultPreset)
+	for _, name := range slices.Sorted(maps.Keys(c.Simulation.Presets)) {
+		if err := c.Simulation.Presets[name].Params(name).Validate(); err != nil {
+			problems = append(problems, fmt.Errorf("simulation.presets.%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// validOrigin reports whether a CORS origin is * or a scheme and host without a path
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+func validDBURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") && u.Host != ""
 }
 
 // GetPort returns the configured port or default
@@ -81,3 +389,12 @@ func GetPort() string {
 	}
 	return "8000"
 }
+
+// GetSeasonsDir returns the directory holding historical season files
+func GetSeasonsDir() string {
+	dataDir := AppConfig.App.DataDir
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	return filepath.Join(dataDir, "seasons")
+}
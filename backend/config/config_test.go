@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"stadia-backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadFile loads a config.yaml with the given content, restoring AppConfig
+// when the test ends
+func loadFile(t *testing.T, content string) error {
+	t.Helper()
+	saved := AppConfig
+	t.Cleanup(func() { AppConfig = saved })
+	return Load(writeConfigDir(t, content))
+}
+
+// writeConfigDir returns a directory with a config/config.yaml of the given content
+func writeConfigDir(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "config"), 0o755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", "config.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return dir
+}
+
+func TestLoadEnvironment(t *testing.T) {
+	t.Setenv("STADIA_APP_PORT", "9001")
+	t.Setenv("STADIA_APP_ALLOWED_ORIGINS", "https://a.example,http://b.example:3000")
+	t.Setenv("STADIA_APP_RATE_LIMIT_BURST", "40")
+	t.Setenv("STADIA_JOBS_TIMEOUT", "90s")
+	t.Setenv("APP_MODE", "release") // Not prefixed, so ignored
+
+	if err := loadFile(t, "app:\n  port: \"8001\"\n  max_teams: 8\n"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if AppConfig.App.Port != "9001" {
+		t.Errorf("Expected the environment to override the port, got %q", AppConfig.App.Port)
+	}
+	if AppConfig.App.MaxTeams != 8 {
+		t.Errorf("Expected max teams 8 from the file, got %d", AppConfig.App.MaxTeams)
+	}
+	if origins := AppConfig.App.AllowedOrigins; len(origins) != 2 || origins[1] != "http://b.example:3000" {
+		t.Errorf("Expected two origins from the environment, got %v", origins)
+	}
+	if AppConfig.App.RateLimit.Burst != 40 {
+		t.Errorf("Expected a nested key from the environment, got burst %d", AppConfig.App.RateLimit.Burst)
+	}
+	if AppConfig.Jobs.Timeout != 90*time.Second {
+		t.Errorf("Expected jobs timeout 90s, got %v", AppConfig.Jobs.Timeout)
+	}
+	if AppConfig.App.Mode != "debug" {
+		t.Errorf("Expected the default mode, got %q", AppConfig.App.Mode)
+	}
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	if err := loadFile(t, "app:\n  prot: \"8001\"\n"); err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Errorf("Expected an error naming the unknown key, got %v", err)
+	}
+
+	t.Setenv("STADIA_APP_MODE", "prod")
+	err := loadFile(t, "app:\n  port: \"70000\"\n")
+	if err == nil {
+		t.Fatal("Expected an error for an invalid mode and port")
+	}
+	for _, key := range []string{"app.mode", "app.port"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected the error to report %s, got %v", key, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Config {
+		return Config{
+			App:  App{Mode: "release", Port: "8000", Name: "stadia", DataDir: "data", AllowedOrigins: []string{"*", "https://stadia.example"}},
+			Jobs: Jobs{Workers: 1},
+			Log:  Log{Level: "info", Format: "json"},
+			Simulation: Simulation{Simulations: 10000, DefaultPreset: "custom", Presets: map[string]ModelPreset{
+				"custom": {HomeAdvantage: 10, BaseGoals: 1.5, PowerExponent: 0.4, NoiseMin: 0.8, NoiseMax: 1.2, GoalCap: 4.5, WinProbabilityScale: 0.05},
+			}},
+		}
+	}
+	c := valid()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		key    string
+	}{
+		{"mode", func(c *Config) { c.App.Mode = "Release" }, "app.mode"},
+		{"port", func(c *Config) { c.App.Port = "0" }, "app.port"},
+		{"origin with path", func(c *Config) { c.App.AllowedOrigins = []string{"https://stadia.example/app"} }, "app.allowed_origins"},
+		{"origin without scheme", func(c *Config) { c.App.AllowedOrigins = []string{"stadia.example"} }, "app.allowed_origins"},
+		{"proxy", func(c *Config) { c.App.TrustedProxies = []string{"10.0.0.0/33"} }, "app.trusted_proxies"},
+		{"db scheme", func(c *Config) { c.DB.URL = "mysql://db:3306/stadia" }, "db.url"},
+		{"db host", func(c *Config) { c.DB.URL = "postgres:///stadia" }, "db.url"},
+		{"auth secret", func(c *Config) { c.Auth = Auth{Enabled: true, Issuer: "stadia", TokenTTL: time.Hour} }, "auth.secret"},
+		{"auth admin", func(c *Config) {
+			c.Auth = Auth{Enabled: true, Secret: strings.Repeat("s", 32), Issuer: "stadia", TokenTTL: time.Hour, Admin: "Root"}
+		}, "auth.admin"},
+		{"auth admin token", func(c *Config) {
+			c.Auth = Auth{Enabled: true, Secret: strings.Repeat("s", 32), Issuer: "stadia", TokenTTL: time.Hour, Admin: "root", AdminToken: "short"}
+		}, "auth.admin_token"},
+		{"jobs timeout", func(c *Config) { c.Jobs.Timeout = -time.Second }, "jobs.timeout"},
+		{"default preset", func(c *Config) { c.Simulation.DefaultPreset = "realistic" }, "simulation.default_preset"},
+		{"preset noise", func(c *Config) {
+			c.Simulation.Presets["wild"] = ModelPreset{BaseGoals: 1, NoiseMin: 1.5, NoiseMax: 1, GoalCap: 4, WinProbabilityScale: 1}
+		}, "simulation.presets.wild"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.change(&c)
+			if err := c.Validate(); err == nil || !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("Expected an error about %s, got %v", tt.key, err)
+			}
+		})
+	}
+
+	c = valid()
+	c.DB.URL = "postgresql://stadia:secret@db:5432/stadia"
+	c.App.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12"}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLoadPresets(t *testing.T) {
+	err := loadFile(t, `simulation:
+  default_preset: defensive
+  presets:
+    realistic:
+      home_advantage: 12
+    defensive:
+      home_advantage: 10
+      base_goals: 1.1
+      power_exponent: 0.3
+      noise_min: 0.9
+      noise_max: 1.1
+      goal_cap: 3
+      win_probability_scale: 0.04
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	presets := AppConfig.Simulation.Presets
+	for name, builtin := range models.BuiltinModelPresets() {
+		if name == models.PresetRealistic {
+			builtin.HomeAdvantage = 12
+		}
+		if got := presets[name].Params(name); got != builtin {
+			t.Errorf("Expected preset %s to be %+v, got %+v", name, builtin, got)
+		}
+	}
+	if presets["defensive"].GoalCap != 3 || AppConfig.Simulation.DefaultPreset != "defensive" {
+		t.Errorf("Expected the defensive preset from the file as the default, got %+v", AppConfig.Simulation)
+	}
+}
+
+func TestRedactedYAML(t *testing.T) {
+	c := Config{
+		App:  App{Port: "8000", ShutdownTimeout: 30 * time.Second},
+		DB:   DB{URL: "postgres://stadia:hunter2@db:5432/stadia?sslmode=require&password=hunter3"},
+		Auth: Auth{Secret: "0123456789abcdef0123456789abcdef", AdminToken: "bootstrap-token-1"},
+	}
+
+	var out bytes.Buffer
+	if err := c.Redacted().WriteYAML(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	text := out.String()
+	for _, secret := range []string{"hunter2", "hunter3", "0123456789abcdef", "bootstrap-token-1"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("Expected %q to be redacted:\n%s", secret, text)
+		}
+	}
+	for _, want := range []string{"secret: REDACTED", "url: postgres://stadia:REDACTED@db:5432/stadia?password=REDACTED&sslmode=require", "shutdown_timeout: 30s", "port: \"8000\""} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+	if c.Auth.Secret == redacted {
+		t.Error("Expected Redacted to leave the original unchanged")
+	}
+}
+
+func TestPrintInvalidConfig(t *testing.T) {
+	dir := writeConfigDir(t, "app:\n  port: \"70000\"\n  prot: 1\ndb:\n  url: \"postgres://db/stadia?password=hunter2\"\n")
+
+	var out bytes.Buffer
+	err := Print(dir, &out)
+	if err == nil {
+		t.Fatal("Expected the problems of the configuration to be reported")
+	}
+	for _, problem := range []string{"prot", "app.port"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected the error to report %s, got %v", problem, err)
+		}
+	}
+	text := out.String()
+	if !strings.Contains(text, `port: "70000"`) || strings.Contains(text, "hunter2") {
+		t.Errorf("Expected the invalid configuration to be printed with secrets redacted:\n%s", text)
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in printed configuration
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the configuration with the auth secret, the
+// admin token and the database passwords replaced, safe to print or log. A
+// database URL that cannot be parsed is replaced as a whole.
+func (c Config) Redacted() Config {
+	if c.Auth.Secret != "" {
+		c.Auth.Secret = redacted
+	}
+	if c.Auth.AdminToken != "" {
+		c.Auth.AdminToken = redacted
+	}
+	if c.DB.URL != "" {
+		c.DB.URL = redactURL(c.DB.URL)
+	}
+	return c
+}
+
+// redactURL replaces the password of a URL's user information and the
+// values of query parameters such as password or sslpassword
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+
+	changed := false
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			changed = true
+		}
+	}
+	query := u.Query()
+	for key := range query {
+		if strings.Contains(strings.ToLower(key), "password") {
+			query.Set(key, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Print writes the configuration Load would read, with secrets redacted, and
+// then validates it. Unlike Load it prints an invalid configuration too, as
+// far as it can be read, so the problems can be looked into.
+func Print(processCwdir string, w io.Writer) error {
+	v, err := read(processCwdir)
+	if err != nil {
+		return err
+	}
+
+	var cfg Config
+	var problems []error
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		problems = append(problems, fmt.Errorf("error unmarshaling config: %w", err))
+		// Print the keys that can be read
+		cfg = Config{}
+		v.Unmarshal(&cfg)
+	}
+	if err := cfg.Redacted().WriteYAML(w); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, fmt.Errorf("invalid configuration: %w", err))
+	}
+	return errors.Join(problems...)
+}
+
+// MustPrint runs Print and exits on error, logging each problem found
+func MustPrint(processCwdir string, w io.Writer) {
+	if err := Print(processCwdir, w); err != nil {
+		logProblems("Invalid configuration", err)
+		os.Exit(1)
+	}
+}
+
+// WriteYAML writes the configuration as YAML with the keys of the config
+// file, in the order of the fields. It does not redact secrets.
+func (c Config) WriteYAML(w io.Writer) error {
+	node, err := yamlNode(reflect.ValueOf(c))
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// yamlNode encodes structs by their mapstructure tags, which yaml ignores,
+// maps in key order, and durations as strings such as "30s" rather than
+// nanoseconds
+func yamlNode(v reflect.Value) (*yaml.Node, error) {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: d.String()}, nil
+	}
+	if v.Kind() == reflect.Map {
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, key := range keys {
+			value, err := yamlNode(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key.String()}, value)
+		}
+		return node, nil
+	}
+	if v.Kind() != reflect.Struct {
+		node := &yaml.Node{}
+		if err := node.Encode(v.Interface()); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		value, err := yamlNode(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+	return node, nil
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Reloadable lists the keys whose changes Watch applies while the server
+// runs. A key also covers the keys nested in it.
+var Reloadable = []string{
+	"app.allowed_origins",
+	"app.rate_limit.rate",
+	"app.rate_limit.burst",
+	"app.rate_limit.expensive_rate",
+	"app.rate_limit.expensive_burst",
+	"log.level",
+	"simulation.simulations",
+	"simulation.default_preset",
+	"simulation.presets", // Also applied to the leagues using them
+}
+
+// Change is a setting whose value differs between two configurations
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// Diff returns the settings that differ between two configurations, in the
+// order of the fields. Settings only one of them has, such as a preset that
+// was added, have an empty old or new value.
+func Diff(old, new Config) []Change {
+	oldValues, newValues := flatten(reflect.ValueOf(old), ""), flatten(reflect.ValueOf(new), "")
+	newByKey := make(map[string]string, len(newValues))
+	for _, value := range newValues {
+		newByKey[value.key] = value.value
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(oldValues))
+	for _, value := range oldValues {
+		seen[value.key] = true
+		if newValue := newByKey[value.key]; newValue != value.value {
+			changes = append(changes, Change{Key: value.key, Old: value.value, New: newValue})
+		}
+	}
+	for _, value := range newValues {
+		if !seen[value.key] {
+			changes = append(changes, Change{Key: value.key, New: value.value})
+		}
+	}
+	return changes
+}
+
+// CheckReload returns an error naming the changed settings that only take
+// effect after a restart
+func CheckReload(changes []Change) error {
+	var restart []string
+	for _, change := range changes {
+		reloadable := slices.ContainsFunc(Reloadable, func(key string) bool {
+			return change.Key == key || strings.HasPrefix(change.Key, key+".")
+		})
+		if !reloadable {
+			restart = append(restart, change.Key)
+		}
+	}
+	if len(restart) > 0 {
+		return fmt.Errorf("changing %s needs a restart", strings.Join(restart, ", "))
+	}
+	return nil
+}
+
+var (
+	rejectedMu sync.Mutex
+	rejected   error // Why the file on disk is not in effect, nil while it is
+)
+
+// setRejected records why the last change to the configuration file was not
+// applied, or nil once the file is in effect again
+func setRejected(err error) {
+	rejectedMu.Lock()
+	defer rejectedMu.Unlock()
+	rejected = err
+}
+
+// CheckFile is a readiness check that fails while the configuration file
+// differs from the configuration in use: it no longer loads or validates, or
+// it changes settings that need a restart. It passes until Watch has seen a
+// change.
+func CheckFile(ctx context.Context) error {
+	rejectedMu.Lock()
+	defer rejectedMu.Unlock()
+	if rejected != nil {
+		return fmt.Errorf("config file change not applied: %w", rejected)
+	}
+	return nil
+}
+
+// Watch reloads the configuration file read by Load whenever it changes. A
+// valid configuration that only changes Reloadable settings is passed to
+// apply, which applies it to the running server; any other change is logged
+// and ignored, so the settings in use always come from one version of the
+// file. apply runs on the watcher goroutine, one call at a time. AppConfig
+// keeps the configuration loaded at startup. Watch does nothing when no
+// configuration file was found.
+func Watch(apply func(Config)) {
+	v, current := loaded, AppConfig
+	setRejected(nil)
+	if v == nil || v.ConfigFileUsed() == "" {
+		return
+	}
+
+	v.OnConfigChange(func(fsnotify.Event) {
+		// viper has reread the file, but only logs errors doing so
+		if err := v.ReadInConfig(); err != nil {
+			slog.Error("Configuration change not applied", "error", err)
+			setRejected(err)
+			return
+		}
+		var next Config
+		if err := v.UnmarshalExact(&next); err != nil {
+			logProblems("Configuration change not applied", err)
+			setRejected(err)
+			return
+		}
+		if err := next.Validate(); err != nil {
+			logProblems("Configuration change not applied", err)
+			setRejected(err)
+			return
+		}
+
+		changes := Diff(current, next)
+		if err := CheckReload(changes); err != nil {
+			slog.Warn("Configuration change not applied", "error", err)
+			setRejected(err)
+			return
+		}
+		setRejected(nil)
+		if len(changes) == 0 {
+			return
+		}
+		for _, change := range changes {
+			slog.Info("Configuration changed", "key", change.Key, "old", change.Old, "new", change.New)
+		}
+		current = next
+		apply(next)
+	})
+	v.WatchConfig()
+	slog.Info("Watching config file for changes", "path", v.ConfigFileUsed())
+}
+
+// setting is a key and its value formatted for comparing and logging
+type setting struct {
+	key   string
+	value string
+}
+
+// flatten lists the settings of a configuration by their dotted keys. Map
+// entries are listed by key, in key order.
+func flatten(v reflect.Value, prefix string) []setting {
+	if v.Kind() == reflect.Map {
+		var settings []setting
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, key := range keys {
+			settings = append(settings, flatten(v.MapIndex(key), prefix+"."+key.String())...)
+		}
+		return settings
+	}
+	if v.Kind() != reflect.Struct {
+		return []setting{{key: prefix, value: fmt.Sprint(v.Interface())}}
+	}
+
+	var settings []setting
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		settings = append(settings, flatten(v.Field(i), key)...)
+	}
+	return settings
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffAndCheckReload(t *testing.T) {
+	old := Config{App: App{Port: "8000", AllowedOrigins: []string{"http://a.example"}}, Log: Log{Level: "info"}}
+	next := old
+	next.App.AllowedOrigins = []string{"http://a.example", "http://b.example"}
+	next.Log.Level = "debug"
+
+	changes := Diff(old, next)
+	if len(changes) != 2 || changes[0].Key != "app.allowed_origins" || changes[1] != (Change{Key: "log.level", Old: "info", New: "debug"}) {
+		t.Fatalf("Expected the origins and log level to change, got %+v", changes)
+	}
+	if err := CheckReload(changes); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	next.Simulation.Presets = map[string]ModelPreset{"defensive": {BaseGoals: 1.1}}
+	changes = Diff(old, next)
+	if last := changes[len(changes)-1]; last.Key != "simulation.presets.defensive.win_probability_scale" || last.Old != "" {
+		t.Errorf("Expected the added preset to be listed by key, got %+v", last)
+	}
+	if err := CheckReload(changes); err != nil {
+		t.Errorf("Expected presets to be reloadable, got %v", err)
+	}
+
+	next.App.Port = "9000"
+	next.Auth.Secret = "changed"
+	err := CheckReload(Diff(old, next))
+	if err == nil || !strings.Contains(err.Error(), "app.port, auth.secret") {
+		t.Errorf("Expected the port and secret to need a restart, got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	if err := loadFile(t, "log:\n  level: info\n"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	applied := make(chan Config, 10)
+	Watch(func(cfg Config) { applied <- cfg })
+
+	write := func(content string) {
+		if err := os.WriteFile(loaded.ConfigFileUsed(), []byte(content), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Needs a restart, so ignored
+	write("app:\n  port: \"9000\"\nlog:\n  level: info\n")
+	// Invalid, so ignored
+	write("log:\n  level: loud\n")
+	write("log:\n  level: debug\nsimulation:\n  presets:\n    chaotic:\n      home_advantage: 15\n")
+
+	select {
+	case cfg := <-applied:
+		if cfg.Log.Level != "debug" || cfg.Simulation.Presets["chaotic"].HomeAdvantage != 15 || cfg.App.Port != "8000" {
+			t.Errorf("Expected only the last change to be applied, got %+v", cfg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the change to be applied")
+	}
+	if AppConfig.Log.Level != "info" {
+		t.Errorf("Expected AppConfig to keep the startup configuration, got level %q", AppConfig.Log.Level)
+	}
+	if err := CheckFile(context.Background()); err != nil {
+		t.Errorf("Expected the check to pass once the file applies, got %v", err)
+	}
+
+	// The check follows the file, whether or not a change is applied
+	waitForCheck := func(failing bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for (CheckFile(context.Background()) != nil) != failing {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the check to fail: %v", failing)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	write("log:\n  level: loud\n")
+	waitForCheck(true)
+	write("log:\n  level: debug\nsimulation:\n  presets:\n    chaotic:\n      home_advantage: 15\n")
+	waitForCheck(false)
+	write("app:\n  port: \"9000\"\nlog:\n  level: debug\n")
+	waitForCheck(true)
+}
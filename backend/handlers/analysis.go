@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stadia-backend/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyzeTeam explains what a team needs to reach a target finish
+// @Summary Analyze team
+// @Description For a team and a target finish (top N), list the minimal sets of remaining results that guarantee or allow it, the most likely path there, and rank the remaining matches by how much their outcome moves the team's probability
+// @Tags analysis
+// @Produce json
+// @Param teamId path string true "Team ID"
+// @Param target query int false "Finish in the top N (default 1, winning the group)"
+// @Success 200 {object} models.TeamAnalysis "Team analysis"
+// @Failure 400 {object} map[string]string "Invalid target"
+// @Failure 404 {object} map[string]string "Team not found"
+// @Router /league/analysis/{teamId} [get]
+func (h *LeagueHandler) AnalyzeTeam(c *gin.Context) {
+	target, err := strconv.Atoi(c.DefaultQuery("target", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target must be a number"})
+		return
+	}
+
+	analysis, err := currentLeague(c).AnalyzeTeam(c.Request.Context(), c.Param("teamId"), target)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrTeamNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stadia-backend/models"
+	"stadia-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKeyRequest represents the request to create an API key
+type CreateAPIKeyRequest struct {
+	Name   string         `json:"name" binding:"required"`
+	Scopes []models.Scope `json:"scopes" binding:"required,min=1"`
+}
+
+// CreateAPIKey creates an API key for the signed-in user
+// @Summary Create API key
+// @Description Create an API key that acts for the signed-in user, limited to its scopes: league:read, league:simulate (includes league:read) or league:admin (includes every scope). Send it in the X-API-Key header. The key is shown only in this response; only its hash is stored.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Key name and scopes"
+// @Success 201 {object} models.CreatedAPIKey "API key created"
+// @Failure 400 {object} map[string]string "Missing name or unknown scope"
+// @Failure 401 {object} map[string]string "Authentication required"
+// @Failure 403 {object} map[string]string "Called with an API key"
+// @Router /admin/keys [post]
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.authService.CreateAPIKey(currentUser(c).ID, req.Name, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// GetAPIKeys lists the API keys of the signed-in user
+// @Summary List API keys
+// @Description List the signed-in user's API keys, oldest first, with their scopes and when they were last used. The keys themselves are not returned.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "API keys"
+// @Failure 401 {object} map[string]string "Authentication required"
+// @Failure 403 {object} map[string]string "Called with an API key"
+// @Router /admin/keys [get]
+func (h *AuthHandler) GetAPIKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"keys": h.authService.APIKeys(currentUser(c).ID)})
+}
+
+// RevokeAPIKey deletes an API key
+// @Summary Revoke API key
+// @Description Delete one of the signed-in user's API keys, so that it no longer authenticates
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} map[string]string "API key revoked"
+// @Failure 401 {object} map[string]string "Authentication required"
+// @Failure 403 {object} map[string]string "Called with an API key"
+// @Failure 404 {object} map[string]string "API key not found"
+// @Router /admin/keys/{id} [delete]
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	err := h.authService.RevokeAPIKey(currentUser(c).ID, c.Param("id"))
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
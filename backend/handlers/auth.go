@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"stadia-backend/logging"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Keys of the values the auth middleware stores in the request context
+const (
+	userKey       = "user"
+	apiKeyKey     = "apiKey"
+	leagueKey     = "league"
+	leagueIDKey   = "leagueId"
+	leagueLockKey = "leagueLock"
+)
+
+const (
+	// ShareTokenHeader carries a sharing token for clients that cannot add it to the URL
+	ShareTokenHeader = "X-Share-Token"
+
+	// APIKeyHeader carries the API key of an automation client
+	APIKeyHeader = "X-API-Key"
+)
+
+// AuthHandler handles user accounts and enforces league roles. Without an
+// auth service, authentication is disabled and every caller owns every league.
+type AuthHandler struct {
+	authService *services.AuthService
+	registry    *services.LeagueRegistry
+}
+
+// NewAuthHandler creates a new auth handler. Pass a nil auth service to
+// disable authentication.
+func NewAuthHandler(authService *services.AuthService, registry *services.LeagueRegistry) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		registry:    registry,
+	}
+}
+
+// CredentialsRequest represents a username and password
+type CredentialsRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// currentUser returns the authenticated user, or nil for an anonymous request
+func currentUser(c *gin.Context) *models.User {
+	user, _ := c.Get(userKey)
+	u, _ := user.(*models.User)
+	return u
+}
+
+// currentAPIKey returns the API key of the request, or nil when the caller
+// signed in with a token or is anonymous
+func currentAPIKey(c *gin.Context) *models.APIKey {
+	key, _ := c.Get(apiKeyKey)
+	k, _ := key.(*models.APIKey)
+	return k
+}
+
+// currentLeague returns the league resolved by RequireLeagueRole
+func currentLeague(c *gin.Context) *services.LeagueService {
+	return c.MustGet(leagueKey).(*services.LeagueService)
+}
+
+// currentLeagueID returns the ID of the league resolved by RequireLeagueRole
+func currentLeagueID(c *gin.Context) string {
+	return c.GetString(leagueIDKey)
+}
+
+// shareToken returns the sharing token of a request, from the share query
+// parameter or the X-Share-Token header
+func shareToken(c *gin.Context) string {
+	if token := c.Query("share"); token != "" {
+		return token
+	}
+	return c.GetHeader(ShareTokenHeader)
+}
+
+// RegisterRequest represents the request to create a user account
+type RegisterRequest struct {
+	CredentialsRequest
+	AdminToken string `json:"adminToken"` // auth.admin_token; only for the account named by auth.admin
+}
+
+// Register creates a user account and signs the user in
+// @Summary Register
+// @Description Create a user account and return a bearer token for it. Registering the account named by auth.admin takes the token set as auth.admin_token, and makes it the owner of the default league and every other league nobody owns.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RegisterRequest true "Username (3 to 32 letters, digits, dots, dashes or underscores), password (at least 8 characters) and, for the admin account, the admin token"
+// @Success 201 {object} models.AuthToken "Account created"
+// @Failure 400 {object} map[string]string "Invalid username or password"
+// @Failure 403 {object} map[string]string "Admin account without the admin token"
+// @Failure 409 {object} map[string]string "Username already taken"
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.Register(req.Username, req.Password, req.AdminToken)
+	if errors.Is(err, services.ErrUsernameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrAdminToken) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Usernames are unique and the admin's takes its token, so only the
+	// configured admin can claim leagues
+	if admin := h.authService.Admin(); admin != nil && admin.ID == user.ID {
+		h.registry.ClaimUnowned(user.ID)
+	}
+
+	token, err := h.authService.IssueToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, token)
+}
+
+// Login signs a user in
+// @Summary Log in
+// @Description Check a username and password and return a signed bearer token to send in the Authorization header
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body CredentialsRequest true "Username and password"
+// @Success 200 {object} models.AuthToken "Signed in"
+// @Failure 401 {object} map[string]string "Invalid username or password"
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req CredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.Login(req.Username, req.Password)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.authService.IssueToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// Me returns the signed-in user
+// @Summary Current user
+// @Description Get the user the bearer token was issued to
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.User "Signed-in user"
+// @Failure 401 {object} map[string]string "Missing, invalid or expired token"
+// @Router /auth/me [get]
+func (h *AuthHandler) Me(c *gin.Context) {
+	c.JSON(http.StatusOK, currentUser(c))
+}
+
+// Authenticate reads the API key or bearer token of a request, if any, and
+// stores its user in the context. Requests with an invalid key or token are
+// rejected; requests without one continue anonymously.
+func (h *AuthHandler) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.authService == nil {
+			c.Next()
+			return
+		}
+
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			user, apiKey, err := h.authService.AuthenticateAPIKey(key)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set(userKey, user)
+			c.Set(apiKeyKey, apiKey)
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header must be a bearer token"})
+			return
+		}
+		user, err := h.authService.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(userKey, user)
+		c.Next()
+	}
+}
+
+// RequireUser rejects anonymous requests when authentication is enabled
+func (h *AuthHandler) RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.authService != nil && currentUser(c) == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests that are anonymous or made with an API key,
+// for endpoints only a signed-in user may use
+func (h *AuthHandler) RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if currentAPIKey(c) != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API keys cannot be used here; sign in instead"})
+			return
+		}
+		h.RequireUser()(c)
+	}
+}
+
+// RequireScope rejects requests made with an API key that lacks a scope.
+// Signed-in users and anonymous callers are not limited by scopes.
+func (h *AuthHandler) RequireScope(scope models.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := currentAPIKey(c); key != nil && !key.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key lacks the %s scope", scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireLeagueRole resolves the league of a request, from the leagueId path
+// parameter or the default league, and rejects callers without the role.
+// Owners may do everything viewers may.
+func (h *AuthHandler) RequireLeagueRole(role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("leagueId")
+		if id == "" {
+			id = services.DefaultLeagueID
+		}
+		service, _, err := h.registry.Get(id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		lock, err := h.registry.LeagueLock(id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if !h.authorize(c, id, role) {
+			return
+		}
+
+		c.Set(leagueKey, service)
+		c.Set(leagueIDKey, id)
+		c.Set(leagueLockKey, lock)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "league_id", id))
+		c.Next()
+	}
+}
+
+// LockLeague holds the lock of the league resolved by RequireLeagueRole
+// while the rest of the request runs: exclusively for requests that change
+// the league, shared for requests that read or copy it
+func LockLeague(exclusive bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lock := c.MustGet(leagueLockKey).(*sync.RWMutex)
+		if exclusive {
+			lock.Lock()
+			defer lock.Unlock()
+		} else {
+			lock.RLock()
+			defer lock.RUnlock()
+		}
+		c.Next()
+	}
+}
+
+// role returns what the caller may do with a league, or an empty role when
+// the caller has no access or the league does not exist
+func (h *AuthHandler) role(c *gin.Context, leagueID string) models.Role {
+	if h.authService == nil {
+		if _, _, err := h.registry.Get(leagueID); err != nil {
+			return ""
+		}
+		return models.RoleOwner
+	}
+
+	userID := ""
+	if user := currentUser(c); user != nil {
+		userID = user.ID
+	}
+	role, _ := h.registry.Role(leagueID, userID, shareToken(c))
+	return role
+}
+
+// authorize checks that the caller has a role on a league, answering the
+// request with 401 or 403 when not
+func (h *AuthHandler) authorize(c *gin.Context, leagueID string, required models.Role) bool {
+	switch role := h.role(c, leagueID); {
+	case role == models.RoleOwner, role == required:
+		return true
+	case role == "" && currentUser(c) == nil:
+		c.Header("WWW-Authenticate", "Bearer")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+	case role == "":
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "you do not have access to this league"})
+	default:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only the league owner can do this"})
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireLeagueRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authService, err := services.NewAuthService(services.AuthSettings{
+		Secret:   strings.Repeat("s", 32),
+		Issuer:   "stadia-test",
+		TokenTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	registry := services.NewLeagueRegistry(0)
+	alice, _ := authService.Register("alice", "password123", "")
+	token, _ := authService.IssueToken(alice)
+	info, _ := registry.Create(alice.ID, "Friday League")
+	share, _ := registry.CreateShare(info.ID)
+
+	newRouter := func(h *AuthHandler) *gin.Engine {
+		router := gin.New()
+		league := router.Group("/leagues/:leagueId", h.Authenticate())
+		league.GET("/view", h.RequireLeagueRole(models.RoleViewer), func(c *gin.Context) { c.String(http.StatusOK, currentLeagueID(c)) })
+		league.POST("/own", h.RequireLeagueRole(models.RoleOwner), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	serve := func(router *gin.Engine, method, path string, headers map[string]string) int {
+		req := httptest.NewRequest(method, path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	enabled := newRouter(NewAuthHandler(authService, registry))
+	base := "/leagues/" + info.ID
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{"owner views", http.MethodGet, base + "/view", map[string]string{"Authorization": "Bearer " + token.Token}, http.StatusOK},
+		{"owner changes", http.MethodPost, base + "/own", map[string]string{"Authorization": "Bearer " + token.Token}, http.StatusOK},
+		{"share in query views", http.MethodGet, base + "/view?share=" + share.Token, nil, http.StatusOK},
+		{"share in header changes", http.MethodPost, base + "/own", map[string]string{ShareTokenHeader: share.Token}, http.StatusForbidden},
+		{"anonymous views", http.MethodGet, base + "/view", nil, http.StatusUnauthorized},
+		{"malformed token", http.MethodGet, base + "/view", map[string]string{"Authorization": "Basic abc"}, http.StatusUnauthorized},
+		{"unknown league", http.MethodGet, "/leagues/missing/view", nil, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if code := serve(enabled, test.method, test.path, test.headers); code != test.want {
+			t.Errorf("%s: expected %d, got %d", test.name, test.want, code)
+		}
+	}
+
+	// Without authentication every caller owns every league
+	disabled := newRouter(NewAuthHandler(nil, registry))
+	if code := serve(disabled, http.MethodPost, base+"/own", nil); code != http.StatusOK {
+		t.Errorf("Expected anonymous callers to own leagues without authentication, got %d", code)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewAuthHandler(nil, services.NewLeagueRegistry(0))
+	router := gin.New()
+	// Stands in for Authenticate, which stores the API key of a request
+	router.Use(func(c *gin.Context) {
+		if scope := c.GetHeader(APIKeyHeader); scope != "" {
+			c.Set(apiKeyKey, &models.APIKey{ID: "key", Scopes: []models.Scope{models.Scope(scope)}})
+		}
+	})
+	router.POST("/play", h.RequireScope(models.ScopeLeagueSimulate), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		scope models.Scope
+		want  int
+	}{
+		{"", http.StatusOK}, // Signed-in users and anonymous callers have no scopes
+		{models.ScopeLeagueRead, http.StatusForbidden},
+		{models.ScopeLeagueSimulate, http.StatusOK},
+		{models.ScopeLeagueAdmin, http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/play", nil)
+		req.Header.Set(APIKeyHeader, string(test.scope))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != test.want {
+			t.Errorf("scope %q: expected %d, got %d", test.scope, test.want, recorder.Code)
+		}
+	}
+}
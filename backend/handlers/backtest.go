@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stadia-backend/config"
+	"stadia-backend/models"
+	"stadia-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BacktestHandler handles backtesting HTTP requests. Each backtest has a
+// service of its own, as backtests of different presets may run at once.
+type BacktestHandler struct{}
+
+// NewBacktestHandler creates a new backtest handler
+func NewBacktestHandler() *BacktestHandler {
+	return &BacktestHandler{}
+}
+
+// BacktestRequest represents the request to run a backtest
+type BacktestRequest struct {
+	Seasons []string                `json:"seasons"` // Season names; all local seasons when empty
+	Method  models.PredictionMethod `json:"method"`  // Prediction method; auto when empty
+	Preset  string                  `json:"preset"`  // Match model preset; the default preset when empty
+}
+
+// RunBacktest replays historical seasons and scores the forecasts
+// @Summary Run backtest
+// @Description Replay historical seasons from the local data directory week by week, forecasting with a match model preset and the results known so far, and score the championship and match forecasts with Brier score, log loss and ranked probability score, with calibration buckets
+// @Tags backtest
+// @Accept json
+// @Produce json
+// @Param request body BacktestRequest false "Seasons, prediction method and model preset"
+// @Success 200 {object} models.BacktestReport "Backtest report"
+// @Failure 400 {object} map[string]string "Invalid request or season file"
+// @Failure 404 {object} map[string]string "Season not found"
+// @Router /backtest [post]
+func (h *BacktestHandler) RunBacktest(c *gin.Context) {
+	var req BacktestRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Method == "" {
+		req.Method = models.MethodAuto
+	}
+	model, err := services.CurrentSimulationSettings().Preset(req.Preset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seasons, err := services.LoadSeasons(config.GetSeasonsDir(), req.Seasons)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrSeasonNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	backtestService := services.NewBacktestService()
+	backtestService.SetParams(model)
+	report, err := backtestService.Run(c.Request.Context(), seasons, req.Method)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetSeasons lists the historical seasons available for backtesting
+// @Summary List backtest seasons
+// @Description List the historical seasons in the local data directory
+// @Tags backtest
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Season names"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /backtest/seasons [get]
+func (h *BacktestHandler) GetSeasons(c *gin.Context) {
+	seasons, err := services.ListSeasons(config.GetSeasonsDir())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": seasons})
+}
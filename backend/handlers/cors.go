@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// AllowedOrigins is the list of origins cross-origin requests may come from.
+// It can be replaced while requests are served; use Allow as the
+// AllowOriginFunc of the CORS middleware.
+type AllowedOrigins struct {
+	origins atomic.Pointer[[]string]
+}
+
+// NewAllowedOrigins creates an origin list. "*" allows every origin.
+func NewAllowedOrigins(origins []string) *AllowedOrigins {
+	allowed := &AllowedOrigins{}
+	allowed.Set(origins)
+	return allowed
+}
+
+// Set replaces the allowed origins
+func (a *AllowedOrigins) Set(origins []string) {
+	normalized := make([]string, len(origins))
+	for i, origin := range origins {
+		normalized[i] = strings.ToLower(origin)
+	}
+	a.origins.Store(&normalized)
+}
+
+// Allow reports whether requests from an origin are allowed
+func (a *AllowedOrigins) Allow(origin string) bool {
+	origins := *a.origins.Load()
+	return slices.Contains(origins, "*") || slices.Contains(origins, strings.ToLower(origin))
+}
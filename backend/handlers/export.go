@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportContentTypes maps export formats to their content types
+var exportContentTypes = map[models.ExportFormat]string{
+	models.FormatCSV:       "text/csv; charset=utf-8",
+	models.FormatJSONLines: "application/x-ndjson",
+}
+
+// Export streams standings, matches or predictions as CSV or JSON Lines
+// @Summary Export data
+// @Description Export the standings, all matches or the prediction history as CSV or JSON Lines with stable column names. With a week, the standings are recalculated from the results up to that week, matches are limited to that week and predictions to those stored after it.
+// @Tags export
+// @Produce text/csv,application/x-ndjson
+// @Param dataset path string true "Dataset" Enums(standings, matches, predictions)
+// @Param format query string false "File format (default csv)" Enums(csv, jsonl)
+// @Param week query int false "Week to export"
+// @Success 200 {string} string "Exported rows"
+// @Failure 400 {object} map[string]string "Unknown dataset or format, or invalid week"
+// @Router /league/export/{dataset} [get]
+func (h *LeagueHandler) Export(c *gin.Context) {
+	format := models.ExportFormat(c.DefaultQuery("format", string(models.FormatCSV)))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown format %q", format)})
+		return
+	}
+
+	var week *int
+	if value := c.Query("week"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "week must be a number"})
+			return
+		}
+		week = &parsed
+	}
+
+	dataset := models.ExportDataset(c.Param("dataset"))
+	table, err := currentLeague(c).Export(dataset, week)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("%s.%s", dataset, format)
+	if week != nil {
+		filename = fmt.Sprintf("%s-week-%d.%s", dataset, *week, format)
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// Headers are sent; a write error can only mean the client went away
+	_ = services.WriteExport(c.Writer, table, format)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"stadia-backend/models"
+	"stadia-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler answers liveness and readiness probes
+type HealthHandler struct {
+	healthService *services.HealthService
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(healthService *services.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+// Livez answers liveness probes: the process is up and serving HTTP. It does
+// not run the readiness checks, so a failing dependency does not get the
+// server restarted.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive", "phase": h.healthService.Phase()})
+}
+
+// Readyz answers readiness probes with the outcome of every readiness check.
+// It answers 503 unless the server is ready and every check passed, including
+// while the saved state is being recovered and while shutting down.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.healthService.Readiness(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// RejectWhileStarting answers requests with 503 until the saved state has
+// been recovered, so that nothing changes a league about to be replaced
+func (h *HealthHandler) RejectWhileStarting() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.healthService.Phase() == models.PhaseStarting {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is starting, retry shortly"})
+			return
+		}
+		c.Next()
+	}
+}
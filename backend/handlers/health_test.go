@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRejectWhileStarting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	healthService := services.NewHealthService(time.Second)
+	router := gin.New()
+	router.Use(NewHealthHandler(healthService).RejectWhileStarting())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		phase      models.ServerPhase
+		want       int
+		retryAfter string
+	}{
+		{models.PhaseStarting, http.StatusServiceUnavailable, "1"},
+		{models.PhaseReady, http.StatusOK, ""},
+		{models.PhaseDraining, http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		healthService.SetPhase(test.phase)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		if recorder.Code != test.want {
+			t.Errorf("%s: expected %d, got %d", test.phase, test.want, recorder.Code)
+		}
+		if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != test.retryAfter {
+			t.Errorf("%s: expected Retry-After %q, got %q", test.phase, test.retryAfter, retryAfter)
+		}
+	}
+}
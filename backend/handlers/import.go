@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadBody returns the uploaded file: the "file" field of a multipart form,
+// or the raw request body otherwise
+func uploadBody(c *gin.Context) (io.ReadCloser, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("multipart upload must have a file field")
+		}
+		return header.Open()
+	}
+	return c.Request.Body, nil
+}
+
+// importUpload runs an import and writes the result or the per-row errors
+func importUpload(c *gin.Context, run func(io.Reader) (*models.ImportResult, error)) {
+	body, err := uploadBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	result, err := run(body)
+	if err != nil {
+		var importErr *services.ImportError
+		if errors.As(err, &importErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "rows": importErr.Rows})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ImportTeams initializes the league from a CSV file of teams
+// @Summary Import teams from CSV
+// @Description Initialize a new league from a CSV file with the columns name, power and optionally logo and country. The file is sent as the request body or as the "file" field of a multipart form. Every row is validated first; if any row is invalid, the per-row errors are returned and the current league is left unchanged.
+// @Tags import
+// @Accept text/csv,mpfd
+// @Produce json
+// @Param file formData file false "CSV file (multipart uploads)"
+// @Success 200 {object} models.ImportResult "League initialized from the file"
+// @Failure 400 {object} map[string]interface{} "Invalid file, with per-row errors"
+// @Router /league/import/teams [post]
+func (h *LeagueHandler) ImportTeams(c *gin.Context) {
+	importUpload(c, currentLeague(c).ImportTeamsCSV)
+}
+
+// ImportResults fills in match results from a CSV file
+// @Summary Import results from CSV
+// @Description Fill in scores from a CSV file with the columns week, home_team, away_team, home_score and away_score, matched to fixtures by week and team names. Existing results of the same fixtures are replaced. Every row is validated first; if any row is invalid, the per-row errors are returned and no result is applied.
+// @Tags import
+// @Accept text/csv,mpfd
+// @Produce json
+// @Param file formData file false "CSV file (multipart uploads)"
+// @Success 200 {object} models.ImportResult "Results applied"
+// @Failure 400 {object} map[string]interface{} "Invalid file, with per-row errors"
+// @Router /league/import/results [post]
+func (h *LeagueHandler) ImportResults(c *gin.Context) {
+	importUpload(c, func(r io.Reader) (*models.ImportResult, error) {
+		return currentLeague(c).ImportResultsCSV(c.Request.Context(), r)
+	})
+}
+
+// ImportCompetition creates a league from a real competition file
+// @Summary Import a real competition
+// @Description Create a league from an openfootball JSON or Football.TXT file or a football-data.co.uk CSV file, keeping the real fixture order and marking matches with a score as played so the rest of the season can be forecast. Team powers are derived from points and goal difference per game. The format can be left out for multipart uploads with a .json, .txt or .csv file name.
+// @Tags import
+// @Accept json,plain,text/csv,mpfd
+// @Produce json
+// @Param format query string false "File format" Enums(openfootball-json, openfootball-txt, football-data)
+// @Param name query string false "League name (default from the file)"
+// @Param file formData file false "Competition file (multipart uploads)"
+// @Success 200 {object} models.ImportResult "League created from the competition"
+// @Failure 400 {object} map[string]interface{} "Invalid file or unknown format, with per-row errors"
+// @Router /league/import/competition [post]
+func (h *LeagueHandler) ImportCompetition(c *gin.Context) {
+	format := services.CompetitionFormat(c.Query("format"))
+	if format == "" && strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if header, err := c.FormFile("file"); err == nil {
+			format = services.CompetitionFormatForFile(header.Filename)
+		}
+	}
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format is required"})
+		return
+	}
+
+	importUpload(c, func(r io.Reader) (*models.ImportResult, error) {
+		return currentLeague(c).ImportCompetition(c.Request.Context(), r, format, c.Query("name"))
+	})
+}
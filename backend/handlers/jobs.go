@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler handles background job HTTP requests. Jobs are visible to the
+// viewers of the league they were submitted for and cancelled by its owner.
+type JobHandler struct {
+	jobService  *services.JobService
+	authHandler *AuthHandler
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobService *services.JobService, authHandler *AuthHandler) *JobHandler {
+	return &JobHandler{
+		jobService:  jobService,
+		authHandler: authHandler,
+	}
+}
+
+// jobErrorStatus maps job errors to HTTP status codes
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrJobQueueFull), errors.Is(err, services.ErrJobServiceClosed):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// jobTimeout reads the optional timeout query parameter in seconds
+func jobTimeout(c *gin.Context) (time.Duration, error) {
+	value := c.Query("timeout")
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 1 {
+		return 0, errors.New("timeout must be a positive number of seconds")
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// accepted logs a submitted job and answers with 202 and its location
+func accepted(c *gin.Context, job *models.Job) {
+	slog.InfoContext(c.Request.Context(), "Job submitted", "job_id", job.ID, "job_kind", job.Kind)
+	c.Header("Location", "/api/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// StartSeasonSimulation starts simulating many seasons as a background job
+// @Summary Start batch simulation
+// @Description Simulate many complete seasons as a background job, from the current standings or from week 0, and aggregate the final tables: title and qualification rates, position histogram and the distribution of points and goal difference per team. The league state is copied when the job is submitted. Follow the returned job for progress and the result.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param options body models.SeasonSimulationOptions false "Number of seasons (default simulation.simulations, 10000 out of the box; at most 100000), starting point, seed and qualifying places (default 2)"
+// @Param timeout query int false "Timeout in seconds, at most the configured job timeout"
+// @Success 202 {object} models.Job "Job queued"
+// @Failure 400 {object} map[string]string "Invalid options or league not initialized"
+// @Failure 503 {object} map[string]string "Job queue full or shutting down"
+// @Router /league/simulations [post]
+func (h *JobHandler) StartSeasonSimulation(c *gin.Context) {
+	var options models.SeasonSimulationOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&options); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if options.Seasons == 0 {
+		options.Seasons = services.CurrentSimulationSettings().Simulations
+	}
+	timeout, err := jobTimeout(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := currentLeague(c).SubmitSeasonSimulation(h.jobService, currentLeagueID(c), options, timeout)
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	accepted(c, job)
+}
+
+// GetSeasonSimulations lists the league's batch simulations
+// @Summary List batch simulations
+// @Description List the league's queued, running and recently finished batch simulations, newest first, without their results. They are the season_simulation jobs of the league.
+// @Tags jobs
+// @Produce json
+// @Success 200 {array} models.Job "Batch simulation jobs"
+// @Router /league/simulations [get]
+func (h *JobHandler) GetSeasonSimulations(c *gin.Context) {
+	leagueID := currentLeagueID(c)
+	jobs := make([]*models.Job, 0)
+	for _, job := range h.jobService.List(models.JobSeasonSimulation) {
+		if job.LeagueID == leagueID {
+			jobs = append(jobs, job)
+		}
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// GetSeasonSimulation returns a batch simulation of the league with its
+// progress and, once completed, its aggregate statistics
+// @Summary Get batch simulation
+// @Description Get the status and progress of one of the league's batch simulations, with the aggregate statistics once it has completed. It is the same job as under /jobs/{id}.
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job "Batch simulation job"
+// @Failure 404 {object} map[string]string "Batch simulation not found or expired"
+// @Router /league/simulations/{id} [get]
+func (h *JobHandler) GetSeasonSimulation(c *gin.Context) {
+	job, err := h.jobService.Get(c.Param("id"))
+	if err == nil && (job.Kind != models.JobSeasonSimulation || job.LeagueID != currentLeagueID(c)) {
+		err = services.ErrJobNotFound
+	}
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// StartPrediction calculates championship predictions as a background job
+// @Summary Start prediction job
+// @Description Calculate championship predictions for the current league state as a background job, for methods that can take long such as exact enumeration
+// @Tags jobs
+// @Produce json
+// @Param method query string false "Prediction method (default auto)" Enums(auto, monte_carlo, heuristic, exact)
+// @Param timeout query int false "Timeout in seconds, at most the configured job timeout"
+// @Success 202 {object} models.Job "Job queued"
+// @Failure 400 {object} map[string]string "Unknown method or league not initialized"
+// @Failure 503 {object} map[string]string "Job queue full or shutting down"
+// @Router /league/predictions/jobs [post]
+func (h *JobHandler) StartPrediction(c *gin.Context) {
+	method := models.PredictionMethod(c.DefaultQuery("method", string(models.MethodAuto)))
+	timeout, err := jobTimeout(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := currentLeague(c).SubmitPrediction(h.jobService, currentLeagueID(c), method, timeout)
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	accepted(c, job)
+}
+
+// GetJobs lists the stored jobs
+// @Summary List jobs
+// @Description List queued, running and recently finished jobs, newest first, without their results
+// @Tags jobs
+// @Produce json
+// @Param kind query string false "Job kind" Enums(season_simulation, prediction)
+// @Success 200 {array} models.Job "Jobs"
+// @Router /jobs [get]
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	jobs := h.jobService.List(models.JobKind(c.Query("kind")))
+	visible := make([]*models.Job, 0, len(jobs))
+	for _, job := range jobs {
+		if h.authHandler.role(c, job.LeagueID) != "" {
+			visible = append(visible, job)
+		}
+	}
+	c.JSON(http.StatusOK, visible)
+}
+
+// job returns a job the caller may access with the given role, or answers
+// the request with an error
+func (h *JobHandler) job(c *gin.Context, role models.Role) (*models.Job, bool) {
+	job, err := h.jobService.Get(c.Param("id"))
+	if err == nil && h.authHandler.role(c, job.LeagueID) == "" {
+		// Jobs of leagues the caller cannot see are not revealed
+		err = services.ErrJobNotFound
+	}
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !h.authHandler.authorize(c, job.LeagueID, role) {
+		return nil, false
+	}
+	return job, true
+}
+
+// GetJob returns a job with its progress and, once completed, its result
+// @Summary Get job
+// @Description Get the status and progress of a job, with its result once it has completed
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job "Job"
+// @Failure 404 {object} map[string]string "Job not found or expired"
+// @Router /jobs/{id} [get]
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.job(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob cancels a queued or running job
+// @Summary Cancel job
+// @Description Cancel a queued or running job. Cancelling a finished job has no effect.
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job "Job after cancelling"
+// @Failure 404 {object} map[string]string "Job not found or expired"
+// @Router /jobs/{id}/cancel [post]
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	if _, ok := h.job(c, models.RoleOwner); !ok {
+		return
+	}
+
+	job, err := h.jobService.Cancel(c.Param("id"))
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// StreamJob sends a job's state as server-sent events until it finishes
+// @Summary Follow job
+// @Description Stream the state of a job as server-sent "job" events, starting with the current state, whenever its status or progress changes. The stream ends once the job has finished; the last event carries the result.
+// @Tags jobs
+// @Produce text/event-stream
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job "Job events"
+// @Failure 404 {object} map[string]string "Job not found or expired"
+// @Router /jobs/{id}/events [get]
+func (h *JobHandler) StreamJob(c *gin.Context) {
+	if _, ok := h.job(c, models.RoleViewer); !ok {
+		return
+	}
+
+	updates, unsubscribe, err := h.jobService.Subscribe(c.Param("id"))
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	// The stream lasts as long as the job, beyond the server's write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case job, ok := <-updates:
+			if !ok {
+				return false
+			}
+			c.SSEvent("job", job)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
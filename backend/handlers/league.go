@@ -8,25 +8,28 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// LeagueHandler handles league-related HTTP requests
+// LeagueHandler handles league-related HTTP requests. The league a request
+// is for is resolved by AuthHandler.RequireLeagueRole.
 type LeagueHandler struct {
-	leagueService *services.LeagueService
+	registry *services.LeagueRegistry
 }
 
 // NewLeagueHandler creates a new league handler
-func NewLeagueHandler(leagueService *services.LeagueService) *LeagueHandler {
+func NewLeagueHandler(registry *services.LeagueRegistry) *LeagueHandler {
 	return &LeagueHandler{
-		leagueService: leagueService,
+		registry: registry,
 	}
 }
 
 // InitializeRequest represents the request to initialize a league
 type InitializeRequest struct {
 	Teams []struct {
-		Name  string `json:"name" binding:"required"`
-		Power int    `json:"power" binding:"required,min=1,max=100"`
-		Logo  string `json:"logo"`
+		Name    string `json:"name" binding:"required"`
+		Power   int    `json:"power" binding:"required,min=1,max=100"`
+		Logo    string `json:"logo"`
+		Country string `json:"country"`
 	} `json:"teams" binding:"required,min=2"`
+	Preset string `json:"preset"` // Match model preset; the configured default when empty
 }
 
 // UpdateMatchRequest represents the request to update a match result
@@ -37,14 +40,13 @@ type UpdateMatchRequest struct {
 
 // Initialize initializes the league with teams
 // @Summary Initialize league
-// @Description Initialize a new league with the provided teams
+// @Description Initialize a new league with the provided teams, simulated with a match model preset (see /models)
 // @Tags league
 // @Accept json
 // @Produce json
-// @Param request body InitializeRequest true "Teams to initialize"
+// @Param request body InitializeRequest true "Teams to initialize and model preset"
 // @Success 200 {object} map[string]interface{} "League initialized successfully"
-// @Failure 400 {object} map[string]string "Invalid request"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} map[string]string "Invalid request, unknown preset or more teams than app.max_teams"
 // @Router /league/initialize [post]
 func (h *LeagueHandler) Initialize(c *gin.Context) {
 	var req InitializeRequest
@@ -52,21 +54,46 @@ func (h *LeagueHandler) Initialize(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	model, err := services.CurrentSimulationSettings().Preset(req.Preset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	teams := make([]*models.Team, len(req.Teams))
 	for i, teamReq := range req.Teams {
 		teams[i] = models.NewTeam(teamReq.Name, teamReq.Power, teamReq.Logo)
+		teams[i].Country = teamReq.Country
 	}
 
-	err := h.leagueService.InitializeLeague(teams)
-	if err != nil {
+	league := currentLeague(c)
+	if err := league.InitializeLeague(teams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := league.SetModel(c.Request.Context(), model); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "League initialized successfully",
-		"league":  h.leagueService.GetLeague(),
+		"league":  league.GetLeague(),
+	})
+}
+
+// GetModelPresets returns the match model presets leagues can be initialized with
+// @Summary Get model presets
+// @Description Get the match model presets a league can be initialized with, and the default one. A league returns the parameters it uses under settings.model, which follow its preset when the preset changes.
+// @Tags league
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Default preset and presets by name"
+// @Router /models [get]
+func (h *LeagueHandler) GetModelPresets(c *gin.Context) {
+	settings := services.CurrentSimulationSettings()
+	c.JSON(http.StatusOK, gin.H{
+		"defaultPreset": settings.DefaultPreset,
+		"presets":       settings.Presets,
 	})
 }
 
@@ -78,19 +105,19 @@ func (h *LeagueHandler) Initialize(c *gin.Context) {
 // @Success 200 {object} models.League "Current league state"
 // @Router /league [get]
 func (h *LeagueHandler) GetLeague(c *gin.Context) {
-	league := h.leagueService.GetLeague()
+	league := currentLeague(c).GetLeague()
 	c.JSON(http.StatusOK, league)
 }
 
 // GetStandings returns the current league standings
 // @Summary Get standings
-// @Description Get the current league standings sorted by points
+// @Description Get the current league standings sorted by points, with each team's clinched, eliminated or alive status for winning the group and finishing in the top two and top three
 // @Tags league
 // @Produce json
 // @Success 200 {object} map[string]interface{} "League standings"
 // @Router /league/standings [get]
 func (h *LeagueHandler) GetStandings(c *gin.Context) {
-	standings := h.leagueService.GetStandings()
+	standings := currentLeague(c).GetStandingsTable()
 	c.JSON(http.StatusOK, gin.H{"standings": standings})
 }
 
@@ -103,7 +130,7 @@ func (h *LeagueHandler) GetStandings(c *gin.Context) {
 // @Failure 400 {object} map[string]string "All weeks already played or league not initialized"
 // @Router /league/play-next-week [post]
 func (h *LeagueHandler) PlayNextWeek(c *gin.Context) {
-	err := h.leagueService.PlayNextWeek()
+	err := currentLeague(c).PlayNextWeek(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -111,7 +138,7 @@ func (h *LeagueHandler) PlayNextWeek(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Week played successfully",
-		"league":  h.leagueService.GetLeague(),
+		"league":  currentLeague(c).GetLeague(),
 	})
 }
 
@@ -124,7 +151,7 @@ func (h *LeagueHandler) PlayNextWeek(c *gin.Context) {
 // @Failure 400 {object} map[string]string "League not initialized"
 // @Router /league/play-all-weeks [post]
 func (h *LeagueHandler) PlayAllWeeks(c *gin.Context) {
-	err := h.leagueService.PlayAllWeeks()
+	err := currentLeague(c).PlayAllWeeks(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -132,7 +159,7 @@ func (h *LeagueHandler) PlayAllWeeks(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "All weeks played successfully",
-		"league":  h.leagueService.GetLeague(),
+		"league":  currentLeague(c).GetLeague(),
 	})
 }
 
@@ -156,7 +183,7 @@ func (h *LeagueHandler) UpdateMatch(c *gin.Context) {
 		return
 	}
 
-	err := h.leagueService.UpdateMatchResult(matchID, req.HomeScore, req.AwayScore)
+	err := currentLeague(c).UpdateMatchResult(c.Request.Context(), matchID, req.HomeScore, req.AwayScore)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -164,7 +191,7 @@ func (h *LeagueHandler) UpdateMatch(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Match updated successfully",
-		"league":  h.leagueService.GetLeague(),
+		"league":  currentLeague(c).GetLeague(),
 	})
 }
 
@@ -177,7 +204,7 @@ func (h *LeagueHandler) UpdateMatch(c *gin.Context) {
 // @Failure 400 {object} map[string]string "League not initialized"
 // @Router /league/reset [post]
 func (h *LeagueHandler) ResetLeague(c *gin.Context) {
-	err := h.leagueService.ResetLeague()
+	err := currentLeague(c).ResetLeague()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -185,18 +212,31 @@ func (h *LeagueHandler) ResetLeague(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "League reset successfully",
-		"league":  h.leagueService.GetLeague(),
+		"league":  currentLeague(c).GetLeague(),
 	})
 }
 
 // GetPredictions returns championship predictions
 // @Summary Get predictions
-// @Description Get championship predictions using Monte Carlo simulation
+// @Description Get championship predictions. Without a method the stored league predictions are returned; with a method they are calculated on demand for the current state.
 // @Tags league
 // @Produce json
-// @Success 200 {object} []models.Prediction "Championship predictions"
+// @Param method query string false "Prediction method" Enums(auto, monte_carlo, heuristic, exact)
+// @Success 200 {object} models.PredictionResponse "Championship predictions"
+// @Failure 400 {object} map[string]string "Unknown method or too many remaining matches for exact enumeration"
 // @Router /league/predictions [get]
 func (h *LeagueHandler) GetPredictions(c *gin.Context) {
-	predictions := h.leagueService.GetPredictions()
+	method := c.Query("method")
+	if method == "" {
+		c.JSON(http.StatusOK, currentLeague(c).GetPredictions())
+		return
+	}
+
+	predictions, err := currentLeague(c).CalculatePredictions(c.Request.Context(), models.PredictionMethod(method))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, predictions)
 }
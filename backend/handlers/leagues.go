@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stadia-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateLeagueRequest represents the request to create a league
+type CreateLeagueRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// leagueErrorStatus maps league registry errors to HTTP status codes
+func leagueErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrLeagueNotFound), errors.Is(err, services.ErrShareNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// CreateLeague creates an empty league owned by the signed-in user
+// @Summary Create league
+// @Description Create an empty league owned by the signed-in user. Initialize it with POST /leagues/{leagueId}/initialize; every /league endpoint is available under /leagues/{leagueId}.
+// @Tags leagues
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateLeagueRequest true "League name"
+// @Success 201 {object} models.LeagueInfo "League created"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Authentication required"
+// @Router /leagues [post]
+func (h *LeagueHandler) CreateLeague(c *gin.Context) {
+	var req CreateLeagueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ownerID := ""
+	if user := currentUser(c); user != nil {
+		ownerID = user.ID
+	}
+	info, err := h.registry.Create(ownerID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Location", "/api/leagues/"+info.ID)
+	c.JSON(http.StatusCreated, info)
+}
+
+// ImportLeague creates a league from a snapshot
+// @Summary Import league
+// @Description Create a league owned by the signed-in user from a snapshot exported with GET /league/snapshot, keeping the league under /league untouched. Older schema versions are migrated as for POST /league/snapshot.
+// @Tags leagues
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name query string false "League name (default the name in the snapshot)"
+// @Param snapshot body models.LeagueSnapshot true "League snapshot"
+// @Success 201 {object} models.LeagueInfo "League created"
+// @Failure 400 {object} map[string]string "Invalid snapshot or unsupported schema version"
+// @Failure 401 {object} map[string]string "Authentication required"
+// @Router /leagues/import [post]
+func (h *LeagueHandler) ImportLeague(c *gin.Context) {
+	ownerID := ""
+	if user := currentUser(c); user != nil {
+		ownerID = user.ID
+	}
+	info, err := h.registry.Import(ownerID, c.Query("name"), c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Location", "/api/leagues/"+info.ID)
+	c.JSON(http.StatusCreated, info)
+}
+
+// GetLeagues lists the leagues of the signed-in user
+// @Summary List leagues
+// @Description List the leagues owned by the signed-in user, oldest first. With authentication disabled every league is listed.
+// @Tags leagues
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Leagues"
+// @Failure 401 {object} map[string]string "Authentication required"
+// @Router /leagues [get]
+func (h *LeagueHandler) GetLeagues(c *gin.Context) {
+	ownerID := ""
+	if user := currentUser(c); user != nil {
+		ownerID = user.ID
+	}
+	c.JSON(http.StatusOK, gin.H{"leagues": h.registry.List(ownerID)})
+}
+
+// DeleteLeague deletes a league
+// @Summary Delete league
+// @Description Delete a league with its sharing links. The default league cannot be deleted. Owner only.
+// @Tags leagues
+// @Produce json
+// @Security BearerAuth
+// @Param leagueId path string true "League ID"
+// @Success 200 {object} map[string]string "League deleted"
+// @Failure 400 {object} map[string]string "Default league"
+// @Failure 403 {object} map[string]string "Not the league owner"
+// @Failure 404 {object} map[string]string "League not found"
+// @Router /leagues/{leagueId} [delete]
+func (h *LeagueHandler) DeleteLeague(c *gin.Context) {
+	if err := h.registry.Delete(currentLeagueID(c)); err != nil {
+		c.JSON(leagueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "League deleted successfully"})
+}
+
+// CreateShare creates a read-only sharing link for the league
+// @Summary Create sharing link
+// @Description Create a token that gives read-only access to the league. Pass it as the share query parameter or the X-Share-Token header; no account is needed. Owner only.
+// @Tags leagues
+// @Produce json
+// @Security BearerAuth
+// @Param leagueId path string true "League ID"
+// @Success 201 {object} models.ShareLink "Sharing link"
+// @Failure 403 {object} map[string]string "Not the league owner"
+// @Failure 404 {object} map[string]string "League not found"
+// @Router /leagues/{leagueId}/shares [post]
+func (h *LeagueHandler) CreateShare(c *gin.Context) {
+	share, err := h.registry.CreateShare(currentLeagueID(c))
+	if err != nil {
+		c.JSON(leagueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, share)
+}
+
+// GetShares lists the sharing links of the league
+// @Summary List sharing links
+// @Description List the read-only sharing links of the league, oldest first. Owner only.
+// @Tags leagues
+// @Produce json
+// @Security BearerAuth
+// @Param leagueId path string true "League ID"
+// @Success 200 {object} map[string]interface{} "Sharing links"
+// @Failure 403 {object} map[string]string "Not the league owner"
+// @Failure 404 {object} map[string]string "League not found"
+// @Router /leagues/{leagueId}/shares [get]
+func (h *LeagueHandler) GetShares(c *gin.Context) {
+	shares, err := h.registry.Shares(currentLeagueID(c))
+	if err != nil {
+		c.JSON(leagueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// RevokeShare deletes a sharing link
+// @Summary Revoke sharing link
+// @Description Delete a sharing link so that its token no longer gives access. Owner only.
+// @Tags leagues
+// @Produce json
+// @Security BearerAuth
+// @Param leagueId path string true "League ID"
+// @Param token path string true "Sharing token"
+// @Success 200 {object} map[string]string "Sharing link revoked"
+// @Failure 403 {object} map[string]string "Not the league owner"
+// @Failure 404 {object} map[string]string "League or sharing link not found"
+// @Router /leagues/{leagueId}/shares/{token} [delete]
+func (h *LeagueHandler) RevokeShare(c *gin.Context) {
+	if err := h.registry.RevokeShare(currentLeagueID(c), c.Param("token")); err != nil {
+		c.JSON(leagueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sharing link revoked successfully"})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"stadia-backend/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit rejects requests with 429 once the client's bucket is empty.
+// Requests made with an API key are limited per key, others per client IP.
+// A nil limiter lets every request through.
+func RateLimit(limiter *services.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		client := "ip:" + c.ClientIP()
+		if key := currentAPIKey(c); key != nil {
+			client = "key:" + key.ID
+		}
+		if ok, retryAfter := limiter.Allow(client); !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": fmt.Sprintf("rate limit exceeded, retry in %d seconds", seconds),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// LimitBody rejects request bodies larger than maxBytes with 413. Bodies of
+// unknown length are cut off at the limit, failing to parse. 0 disables the limit.
+func LimitBody(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("request body is larger than %d bytes", maxBytes),
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Stands in for Authenticate, which stores the API key of a request
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader(APIKeyHeader); id != "" {
+			c.Set(apiKeyKey, &models.APIKey{ID: id})
+		}
+	})
+	router.Use(RateLimit(services.NewRateLimiter(services.RateLimitSettings{Rate: 0.5, Burst: 1})))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	tests := []struct {
+		name    string
+		ip, key string
+		want    int
+	}{
+		{"first request", "10.0.0.1", "", http.StatusOK},
+		{"same IP", "10.0.0.1", "", http.StatusTooManyRequests},
+		{"other IP", "10.0.0.2", "", http.StatusOK},
+		{"API key from a limited IP", "10.0.0.1", "key-1", http.StatusOK},
+		{"same API key from another IP", "10.0.0.3", "key-1", http.StatusTooManyRequests},
+		{"other API key", "10.0.0.1", "key-2", http.StatusOK},
+	}
+	for _, test := range tests {
+		recorder := request(test.ip, test.key)
+		if recorder.Code != test.want {
+			t.Errorf("%s: expected %d, got %d", test.name, test.want, recorder.Code)
+			continue
+		}
+		// An empty bucket refills one token in 2 seconds
+		if retryAfter := recorder.Header().Get("Retry-After"); test.want == http.StatusTooManyRequests && retryAfter != "2" {
+			t.Errorf("%s: expected Retry-After 2, got %q", test.name, retryAfter)
+		}
+	}
+
+	unlimited := gin.New()
+	unlimited.Use(RateLimit(nil))
+	unlimited.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		unlimited.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected a nil limiter to let requests through, got %d", recorder.Code)
+		}
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LimitBody(10))
+	router.POST("/", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	tests := []struct {
+		name          string
+		body          string
+		unknownLength bool
+		want          int
+	}{
+		{"at the limit", "0123456789", false, http.StatusOK},
+		{"oversized Content-Length", "0123456789a", false, http.StatusRequestEntityTooLarge},
+		{"oversized body of unknown length", strings.Repeat("a", 20), true, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+		if test.unknownLength {
+			req.ContentLength = -1
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != test.want {
+			t.Errorf("%s: expected %d, got %d", test.name, test.want, recorder.Code)
+		}
+		if test.want == http.StatusOK && recorder.Body.String() != test.body {
+			t.Errorf("%s: expected the handler to read the whole body, got %q", test.name, recorder.Body.String())
+		}
+	}
+}
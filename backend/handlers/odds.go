@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"stadia-backend/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultValueThreshold is the minimum edge, in percent, for a value spot
+const defaultValueThreshold = 5.0
+
+// CompareOddsRequest represents bookmaker odds to compare with the model
+type CompareOddsRequest struct {
+	Matches   []models.BookmakerMatchOdds    `json:"matches" binding:"dive"`
+	Outright  []models.BookmakerOutrightOdds `json:"outright" binding:"dive"`
+	Threshold *float64                       `json:"threshold"` // Minimum edge for a value spot in percent, default 5
+	Method    models.PredictionMethod        `json:"method"`    // Method for the outright probabilities, default auto
+}
+
+// GetOdds returns the model's odds for unplayed matches and the outright winner
+// @Summary Get odds
+// @Description Convert the model's match outcome and outright-winner probabilities into decimal, fractional and American odds. A margin spreads a bookmaker overround proportionally over every outcome; without one the odds are fair.
+// @Tags odds
+// @Produce json
+// @Param margin query number false "Margin in percent (0-50, default 0)"
+// @Param method query string false "Prediction method for the outright odds" Enums(auto, monte_carlo, heuristic, exact)
+// @Success 200 {object} models.OddsResponse "Odds"
+// @Failure 400 {object} map[string]string "Invalid margin or method"
+// @Router /league/odds [get]
+func (h *LeagueHandler) GetOdds(c *gin.Context) {
+	margin, err := strconv.ParseFloat(c.DefaultQuery("margin", "0"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "margin must be a number"})
+		return
+	}
+	method := models.PredictionMethod(c.DefaultQuery("method", string(models.MethodAuto)))
+
+	odds, err := currentLeague(c).GetOdds(c.Request.Context(), margin, method)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, odds)
+}
+
+// CompareOdds compares bookmaker odds with the model
+// @Summary Compare bookmaker odds
+// @Description Strip the overround from bookmaker decimal odds for unplayed matches and the outright winner, compare the fair probabilities with the model and list value spots where betting at the bookmaker's odds has a positive expected return under the model
+// @Tags odds
+// @Accept json
+// @Produce json
+// @Param request body CompareOddsRequest true "Bookmaker odds"
+// @Success 200 {object} models.OddsComparison "Comparison with value spots"
+// @Failure 400 {object} map[string]string "Invalid odds, unknown or played match, or incomplete outright book"
+// @Router /league/odds/compare [post]
+func (h *LeagueHandler) CompareOdds(c *gin.Context) {
+	var req CompareOddsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	threshold := defaultValueThreshold
+	if req.Threshold != nil {
+		threshold = *req.Threshold
+	}
+	if req.Method == "" {
+		req.Method = models.MethodAuto
+	}
+
+	comparison, err := currentLeague(c).CompareOdds(c.Request.Context(), req.Matches, req.Outright, threshold, req.Method)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
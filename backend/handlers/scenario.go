@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EvaluateScenarioRequest represents hypothetical results to evaluate
+type EvaluateScenarioRequest struct {
+	Results []models.ScenarioResult `json:"results" binding:"dive"`
+}
+
+// SaveScenarioRequest represents a named scenario to save
+type SaveScenarioRequest struct {
+	Name    string                  `json:"name" binding:"required"`
+	Results []models.ScenarioResult `json:"results" binding:"required,min=1,dive"`
+}
+
+// EvaluateScenario evaluates hypothetical results without saving them
+// @Summary Evaluate what-if scenario
+// @Description Apply hypothetical results for unplayed matches to a copy of the league and return the resulting standings and predictions. The league itself is not changed.
+// @Tags scenarios
+// @Accept json
+// @Produce json
+// @Param request body EvaluateScenarioRequest true "Hypothetical results"
+// @Success 200 {object} models.ScenarioOutcome "Standings and predictions after the scenario"
+// @Failure 400 {object} map[string]string "Invalid request, unknown or already played match"
+// @Router /league/scenarios/evaluate [post]
+func (h *LeagueHandler) EvaluateScenario(c *gin.Context) {
+	var req EvaluateScenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	outcome, err := currentLeague(c).EvaluateScenario(c.Request.Context(), req.Results)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, outcome)
+}
+
+// SaveScenario saves a named what-if scenario
+// @Summary Save scenario
+// @Description Save a named set of hypothetical results for later evaluation and comparison
+// @Tags scenarios
+// @Accept json
+// @Produce json
+// @Param request body SaveScenarioRequest true "Scenario to save"
+// @Success 201 {object} models.Scenario "Saved scenario"
+// @Failure 400 {object} map[string]string "Invalid request, unknown or already played match"
+// @Router /league/scenarios [post]
+func (h *LeagueHandler) SaveScenario(c *gin.Context) {
+	var req SaveScenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scenario, err := currentLeague(c).SaveScenario(req.Name, req.Results)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, scenario)
+}
+
+// GetScenarios lists saved scenarios
+// @Summary List scenarios
+// @Description List all saved what-if scenarios
+// @Tags scenarios
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Saved scenarios"
+// @Router /league/scenarios [get]
+func (h *LeagueHandler) GetScenarios(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"scenarios": currentLeague(c).GetScenarios()})
+}
+
+// GetScenario evaluates a saved scenario
+// @Summary Get scenario
+// @Description Evaluate a saved scenario against the current league
+// @Tags scenarios
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Success 200 {object} models.ScenarioOutcome "Standings and predictions after the scenario"
+// @Failure 400 {object} map[string]string "Scenario refers to a match that has since been played"
+// @Failure 404 {object} map[string]string "Scenario not found"
+// @Router /league/scenarios/{id} [get]
+func (h *LeagueHandler) GetScenario(c *gin.Context) {
+	outcome, err := currentLeague(c).EvaluateSavedScenario(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(scenarioErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, outcome)
+}
+
+// DeleteScenario deletes a saved scenario
+// @Summary Delete scenario
+// @Description Delete a saved what-if scenario
+// @Tags scenarios
+// @Produce json
+// @Param id path string true "Scenario ID"
+// @Success 200 {object} map[string]string "Scenario deleted successfully"
+// @Failure 404 {object} map[string]string "Scenario not found"
+// @Router /league/scenarios/{id} [delete]
+func (h *LeagueHandler) DeleteScenario(c *gin.Context) {
+	if err := currentLeague(c).DeleteScenario(c.Param("id")); err != nil {
+		c.JSON(scenarioErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scenario deleted successfully"})
+}
+
+// CompareScenarios compares saved scenarios side by side
+// @Summary Compare scenarios
+// @Description Evaluate saved scenarios side by side with the current league
+// @Tags scenarios
+// @Produce json
+// @Param ids query string true "Comma-separated scenario IDs, at most 5"
+// @Success 200 {object} models.ScenarioComparison "Current league and scenario outcomes"
+// @Failure 400 {object} map[string]string "Missing or too many IDs, or a scenario refers to a played match"
+// @Failure 404 {object} map[string]string "Scenario not found"
+// @Router /league/scenarios/compare [get]
+func (h *LeagueHandler) CompareScenarios(c *gin.Context) {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one scenario ID is required"})
+		return
+	}
+
+	comparison, err := currentLeague(c).CompareScenarios(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(scenarioErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// scenarioErrorStatus maps scenario errors to HTTP status codes
+func scenarioErrorStatus(err error) int {
+	if errors.Is(err, services.ErrScenarioNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"stadia-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportSnapshot downloads a complete snapshot of the league
+// @Summary Export league snapshot
+// @Description Download a versioned, self-describing snapshot of the league: teams, fixtures with match IDs, results, current week, prediction history, settings, seed and saved scenarios
+// @Tags snapshot
+// @Produce json
+// @Success 200 {object} models.LeagueSnapshot "League snapshot"
+// @Router /league/snapshot [get]
+func (h *LeagueHandler) ExportSnapshot(c *gin.Context) {
+	snapshot := currentLeague(c).ExportSnapshot()
+	snapshot.AppVersion = config.AppConfig.App.Version
+
+	filename := fmt.Sprintf("league-%s.json", snapshot.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ImportSnapshot replaces the league with a snapshot
+// @Summary Replace league from snapshot
+// @Description Replace the state of this league with a snapshot, keeping its ID, owner and sharing links. Use POST /leagues/import to restore a snapshot as a new league instead. Older schema versions, including the plain league JSON of GET /league, are migrated; newer ones are rejected. Team statistics are recalculated from the results.
+// @Tags snapshot
+// @Accept json
+// @Produce json
+// @Param snapshot body models.LeagueSnapshot true "League snapshot"
+// @Success 200 {object} models.LeagueSnapshot "Restored league"
+// @Failure 400 {object} map[string]string "Invalid snapshot or unsupported schema version"
+// @Router /league/snapshot [post]
+func (h *LeagueHandler) ImportSnapshot(c *gin.Context) {
+	snapshot, err := currentLeague(c).ImportSnapshot(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	snapshot.AppVersion = config.AppConfig.App.Version
+
+	c.JSON(http.StatusOK, snapshot)
+}
@@ -0,0 +1,120 @@
+// Package logging sets up structured logging and carries request-scoped log
+// attributes, such as the request ID, in a context
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDKey is the attribute that holds the ID of the request a log line
+// belongs to
+const RequestIDKey = "request_id"
+
+// Attributes that hold the trace and span a log line was written in
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// attrsKey is the context key of the attributes added by With
+type attrsKey struct{}
+
+// ParseLevel parses a log level: debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, fmt.Errorf("unknown log level %q", level)
+	}
+	return l, nil
+}
+
+// New creates a logger writing to w. Pass a *slog.LevelVar as the level to
+// change it later; the format is json or text. Log calls given a context made
+// by With also log the attributes of that context, and those given a context
+// with a recording trace span log its trace and span IDs.
+func New(w io.Writer, level slog.Leveler, format string) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// With returns a context whose log lines also carry the given attributes, as
+// key-value pairs or slog.Attr values like the arguments of slog.Info
+func With(ctx context.Context, args ...any) context.Context {
+	record := slog.NewRecord(time.Time{}, 0, "", 0)
+	record.Add(args...)
+
+	attrs := append([]slog.Attr(nil), contextAttrs(ctx)...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// WithRequestID returns a context whose log lines carry a request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return With(ctx, RequestIDKey, id)
+}
+
+// RequestID returns the request ID of a context, or an empty string
+func RequestID(ctx context.Context) string {
+	for _, attr := range contextAttrs(ctx) {
+		if attr.Key == RequestIDKey {
+			return attr.Value.String()
+		}
+	}
+	return ""
+}
+
+// contextAttrs returns the attributes added to a context by With
+func contextAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the attributes and trace span of the log call's context
+// to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := contextAttrs(ctx)
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() && span.IsSampled() {
+		attrs = append(attrs[:len(attrs):len(attrs)],
+			slog.String(TraceIDKey, span.TraceID().String()),
+			slog.String(SpanIDKey, span.SpanID().String()))
+	}
+	if len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
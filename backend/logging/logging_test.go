@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestContextAttributes(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = With(ctx, "league_id", "default", slog.Int("week", 3))
+	logger.DebugContext(ctx, "Hidden")
+	logger.InfoContext(ctx, "Week played", "matches", 2)
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a single JSON line, got %q: %v", out.String(), err)
+	}
+	want := map[string]any{"msg": "Week played", "request_id": "req-1", "league_id": "default", "week": 3.0, "matches": 2.0}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, line[key])
+		}
+	}
+	if id := RequestID(ctx); id != "req-1" {
+		t.Errorf("Expected request ID req-1, got %q", id)
+	}
+	if id := RequestID(context.Background()); id != "" {
+		t.Errorf("Expected no request ID, got %q", id)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, slog.LevelWarn, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	level, err := ParseLevel("DEBUG")
+	if err != nil || level != slog.LevelDebug {
+		t.Errorf("Expected the debug level, got %v, %v", level, err)
+	}
+	if _, err := New(&bytes.Buffer{}, level, "text"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLevelVar(t *testing.T) {
+	var out bytes.Buffer
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	logger, err := New(&out, &level, "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	logger.Info("Hidden")
+	level.Set(slog.LevelInfo)
+	logger.Info("Shown")
+	if text := out.String(); strings.Contains(text, "Hidden") || !strings.Contains(text, "Shown") {
+		t.Errorf("Expected only the line logged after lowering the level, got %q", text)
+	}
+}
@@ -1,10 +1,24 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
 	"stadia-backend/config"
 	"stadia-backend/handlers"
+	"stadia-backend/logging"
+	"stadia-backend/metrics"
+	"stadia-backend/models"
 	"stadia-backend/services"
+	"stadia-backend/tracing"
+	"sync"
+	"syscall"
+	"time"
 
 	docs "stadia-backend/docs"
 
@@ -12,6 +26,7 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // @title Stadia
@@ -25,57 +40,226 @@ import (
 
 // @BasePath /api
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Bearer token from /auth/login, as "Bearer <token>"
+
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key from /admin/keys
+
 func main() {
-	// Load configuration
+	printConfig := flag.Bool("print-config", false, "print the configuration with secrets redacted, then exit")
+	flag.Parse()
+
+	// Load configuration. Printing it does not need it to be valid.
+	if *printConfig {
+		config.MustPrint(".", os.Stdout)
+		return
+	}
 	config.MustLoad(".")
 
+	// The level can change while the server runs, see config.Watch below
+	var logLevel slog.LevelVar
+	level, err := logging.ParseLevel(config.AppConfig.Log.Level)
+	if err != nil {
+		fatal("Invalid log configuration", "error", err)
+	}
+	logLevel.Set(level)
+	logger, err := logging.New(os.Stderr, &logLevel, config.AppConfig.Log.Format)
+	if err != nil {
+		fatal("Invalid log configuration", "error", err)
+	}
+	slog.SetDefault(logger)
+
 	mode := config.AppConfig.App.Mode
 	port := config.GetPort()
-	log.Printf("Starting in %s mode on port %s", mode, port)
+	slog.Info("Starting", "mode", mode, "port", port, "version", config.AppConfig.App.Version)
 
-	// Set Gin mode
-	if mode == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	} else if mode == "test" {
-		gin.SetMode(gin.TestMode)
-	} else {
-		gin.SetMode(gin.DebugMode)
-	}
+	// Set Gin mode; the configuration only allows Gin's modes
+	gin.SetMode(mode)
 
 	// Initialize services
-	leagueService := services.NewLeagueService()
+	services.SetSimulationSettings(simulationSettings(config.AppConfig))
+	registry := services.NewLeagueRegistry(config.AppConfig.App.MaxTeams)
+	jobService := services.NewJobService(services.JobSettings{
+		Workers:   config.AppConfig.Jobs.Workers,
+		QueueSize: config.AppConfig.Jobs.QueueSize,
+		Timeout:   config.AppConfig.Jobs.Timeout,
+		Retention: config.AppConfig.Jobs.Retention,
+	})
+	healthService := services.NewHealthService(healthCheckTimeout)
+	if path := config.AppConfig.App.StateFile; path != "" {
+		healthService.Register("storage", func(ctx context.Context) error { return services.PingStateFile(path) })
+	}
+	healthService.Register("jobs", jobService.CheckBacklog)
+	healthService.Register("config", config.CheckFile)
+	var authService *services.AuthService
+	if config.AppConfig.Auth.Enabled {
+		var err error
+		authService, err = services.NewAuthService(services.AuthSettings{
+			Secret:     config.AppConfig.Auth.Secret,
+			Issuer:     config.AppConfig.Auth.Issuer,
+			TokenTTL:   config.AppConfig.Auth.TokenTTL,
+			Admin:      config.AppConfig.Auth.Admin,
+			AdminToken: config.AppConfig.Auth.AdminToken,
+		})
+		if err != nil {
+			fatal("Invalid auth configuration", "error", err)
+		}
+		slog.Info("Authentication enabled")
+	} else {
+		slog.Warn("Authentication disabled: every caller owns every league")
+	}
 
 	// Initialize handlers
-	leagueHandler := handlers.NewLeagueHandler(leagueService)
+	authHandler := handlers.NewAuthHandler(authService, registry)
+	leagueHandler := handlers.NewLeagueHandler(registry)
+	backtestHandler := handlers.NewBacktestHandler()
+	jobHandler := handlers.NewJobHandler(jobService, authHandler)
+	healthHandler := handlers.NewHealthHandler(healthService)
+
+	// Rate limits per client; the expensive limit applies on top of the general one
+	var limiter, expensiveLimiter *services.RateLimiter
+	if limits := config.AppConfig.App.RateLimit; limits.Enabled {
+		limiter = services.NewRateLimiter(rateLimitSettings(limits))
+		expensiveLimiter = services.NewRateLimiter(expensiveRateLimitSettings(limits))
+		slog.Info("Rate limits enabled",
+			"rate", limits.Rate, "burst", limits.Burst,
+			"expensive_rate", limits.ExpensiveRate, "expensive_burst", limits.ExpensiveBurst)
+	}
+	expensive := handlers.RateLimit(expensiveLimiter)
+
+	// Metrics of requests, simulations and the Go runtime
+	var serverMetrics *metrics.Metrics
+	if config.AppConfig.Metrics.Enabled {
+		serverMetrics = metrics.New()
+		serverMetrics.WatchLeagues(registry.Count)
+		services.SetInstrumentation(serverMetrics)
+	}
+
+	// Traces of requests, league operations, predictions and background jobs
+	if tracingConfig := config.AppConfig.Tracing; tracingConfig.Enabled {
+		shutdownTracing, err := tracing.Setup(context.Background(), tracing.Settings{
+			Endpoint:    tracingConfig.Endpoint,
+			Insecure:    tracingConfig.Insecure,
+			SampleRatio: tracingConfig.SampleRatio,
+			ServiceName: config.AppConfig.App.Name,
+			Version:     config.AppConfig.App.Version,
+		})
+		if err != nil {
+			fatal("Invalid tracing configuration", "error", err)
+		}
+		stopTracing = func() { flushTraces(shutdownTracing) }
+		defer stopTracing()
+		slog.Info("Tracing enabled", "endpoint", tracingConfig.Endpoint, "sample_ratio", tracingConfig.SampleRatio)
+	}
 
-	// Setup Gin router
-	router := gin.Default()
+	// Setup Gin router. Requests get a trace span and an ID first, so every
+	// later span and log line carries them.
+	router := gin.New()
+	if config.AppConfig.Tracing.Enabled {
+		untraced := []string{config.AppConfig.Metrics.Path, "/health", "/livez", "/readyz"}
+		router.Use(otelgin.Middleware(config.AppConfig.App.Name, otelgin.WithFilter(func(r *http.Request) bool {
+			// Scrapes and probes would crowd out the traces worth reading
+			return !slices.Contains(untraced, r.URL.Path)
+		})))
+	}
+	// Metrics come before the recovery, so requests that panic count as 500s
+	if serverMetrics != nil {
+		router.Use(serverMetrics.Middleware())
+	}
+	router.Use(handlers.RequestID(), handlers.AccessLog(), handlers.Recovery())
+	if err := router.SetTrustedProxies(config.AppConfig.App.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", "error", err)
+	}
 
 	// CORS configuration
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = config.AppConfig.App.AllowedOrigins
+	allowedOrigins := handlers.NewAllowedOrigins(config.AppConfig.App.AllowedOrigins)
+	corsConfig.AllowOriginFunc = allowedOrigins.Allow
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	log.Printf("CORS enabled for origins: %v", corsConfig.AllowOrigins)
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", handlers.ShareTokenHeader, handlers.APIKeyHeader, handlers.RequestIDHeader}
+	corsConfig.ExposeHeaders = []string{handlers.RequestIDHeader}
+	slog.Info("CORS enabled", "origins", config.AppConfig.App.AllowedOrigins)
 	router.Use(cors.New(corsConfig))
 
+	// Apply changes to the config file that need no restart
+	config.Watch(func(cfg config.Config) {
+		allowedOrigins.Set(cfg.App.AllowedOrigins)
+		if limiter != nil {
+			limiter.SetSettings(rateLimitSettings(cfg.App.RateLimit))
+			expensiveLimiter.SetSettings(expensiveRateLimitSettings(cfg.App.RateLimit))
+		}
+		if level, err := logging.ParseLevel(cfg.Log.Level); err == nil {
+			logLevel.Set(level)
+		}
+		services.SetSimulationSettings(simulationSettings(cfg))
+		applyPresets(registry)
+	})
+
 	// API routes
-	api := router.Group("/api")
+	api := router.Group("/api",
+		healthHandler.RejectWhileStarting(),
+		handlers.LimitBody(config.AppConfig.App.MaxBodyBytes),
+		authHandler.Authenticate(),
+		handlers.RateLimit(limiter))
 	{
-		league := api.Group("/league")
+		if authService != nil {
+			auth := api.Group("/auth")
+			{
+				auth.POST("/register", authHandler.Register)
+				auth.POST("/login", authHandler.Login)
+				auth.GET("/me", authHandler.RequireUser(), authHandler.Me)
+			}
+
+			keys := api.Group("/admin/keys", authHandler.RequireSession())
+			{
+				keys.POST("", authHandler.CreateAPIKey)
+				keys.GET("", authHandler.GetAPIKeys)
+				keys.DELETE("/:id", authHandler.RevokeAPIKey)
+			}
+		}
+
+		// The default league
+		registerLeagueRoutes(api.Group("/league"), authHandler, leagueHandler, jobHandler, expensive)
+
+		leagues := api.Group("/leagues")
 		{
-			league.POST("/initialize", leagueHandler.Initialize)
-			league.GET("", leagueHandler.GetLeague)
-			league.GET("/standings", leagueHandler.GetStandings)
-			league.POST("/play-next-week", leagueHandler.PlayNextWeek)
-			league.POST("/play-all-weeks", leagueHandler.PlayAllWeeks)
-			league.PUT("/match/:id", leagueHandler.UpdateMatch)
-			league.POST("/reset", leagueHandler.ResetLeague)
-			league.GET("/predictions", leagueHandler.GetPredictions)
+			leagues.POST("", authHandler.RequireUser(), authHandler.RequireScope(models.ScopeLeagueAdmin), leagueHandler.CreateLeague)
+			leagues.GET("", authHandler.RequireUser(), authHandler.RequireScope(models.ScopeLeagueRead), leagueHandler.GetLeagues)
+			leagues.POST("/import", authHandler.RequireUser(), authHandler.RequireScope(models.ScopeLeagueAdmin), leagueHandler.ImportLeague)
+
+			league := leagues.Group("/:leagueId")
+			league.DELETE("",
+				authHandler.RequireScope(models.ScopeLeagueAdmin),
+				authHandler.RequireLeagueRole(models.RoleOwner),
+				leagueHandler.DeleteLeague)
+			registerLeagueRoutes(league, authHandler, leagueHandler, jobHandler, expensive)
+		}
+
+		api.GET("/models", authHandler.RequireScope(models.ScopeLeagueRead), leagueHandler.GetModelPresets)
+
+		backtest := api.Group("/backtest", authHandler.RequireScope(models.ScopeLeagueRead))
+		{
+			backtest.POST("", expensive, backtestHandler.RunBacktest)
+			backtest.GET("/seasons", backtestHandler.GetSeasons)
+		}
+
+		jobs := api.Group("/jobs", authHandler.RequireScope(models.ScopeLeagueRead))
+		{
+			jobs.GET("", jobHandler.GetJobs)
+			jobs.GET("/:id", jobHandler.GetJob)
+			jobs.GET("/:id/events", jobHandler.StreamJob)
+			jobs.POST("/:id/cancel", authHandler.RequireScope(models.ScopeLeagueSimulate), jobHandler.CancelJob)
 		}
 	}
 
-	// Health check endpoint
+	// Probes. /health is kept for existing monitors; like /livez it runs no checks.
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":  "healthy",
@@ -83,12 +267,235 @@ func main() {
 		})
 	})
 
+	if serverMetrics != nil {
+		router.GET(config.AppConfig.Metrics.Path, serverMetrics.Handler())
+		slog.Info("Serving metrics", "path", config.AppConfig.Metrics.Path)
+	}
+
 	docs.SwaggerInfo.BasePath = "/api"
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Start server
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadTimeout:       config.AppConfig.App.ReadTimeout,
+		ReadHeaderTimeout: config.AppConfig.App.ReadHeaderTimeout,
+		WriteTimeout:      config.AppConfig.App.WriteTimeout,
+		IdleTimeout:       config.AppConfig.App.IdleTimeout,
+		MaxHeaderBytes:    config.AppConfig.App.MaxHeaderBytes,
+	}
+
+	// Start server. Probes are answered while the state is recovered; API
+	// requests get 503 until the server is ready.
+	stop, cancelSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancelSignals()
+	serverErrors := make(chan error, 1)
+	go func() {
+		slog.Info("Listening", "addr", server.Addr)
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	// Restore the users and leagues saved at the last shutdown
+	var accounts models.Accounts
+	if path := config.AppConfig.App.StateFile; path != "" {
+		var err error
+		accounts, err = registry.LoadStateFile(path)
+		if err == nil && authService != nil {
+			err = authService.RestoreAccounts(accounts)
+		}
+		switch {
+		case err == nil:
+			slog.Info("Restored state", "path", path, "users", len(accounts.Users), "leagues", registry.Count())
+			// An admin configured after registering claims the leagues now
+			if authService != nil {
+				if admin := authService.Admin(); admin != nil {
+					registry.ClaimUnowned(admin.ID)
+				}
+			}
+			// Presets may have changed since the state was saved
+			applyPresets(registry)
+		case errors.Is(err, os.ErrNotExist):
+			slog.Info("No state saved yet", "path", path)
+		default:
+			fatal("Failed to restore state", "path", path, "error", err)
+		}
+	}
+	healthService.SetPhase(models.PhaseReady)
+	slog.Info("Ready")
+
+	// Run until SIGINT or SIGTERM
+	select {
+	case err := <-serverErrors:
+		fatal("Failed to start server", "error", err)
+	case <-stop.Done():
+	}
+
+	// Fail readiness first, so load balancers stop sending new requests
+	// before the server stops accepting them
+	healthService.SetPhase(models.PhaseDraining)
+	if delay := config.AppConfig.App.DrainDelay; delay > 0 {
+		slog.Info("Draining before shutdown", "delay", delay.String())
+		time.Sleep(delay)
+	}
+
+	shutdown(server, jobService, func() {
+		// Accounts stay in the state file while authentication is disabled
+		if authService != nil {
+			accounts = authService.Accounts()
+		}
+		if path := config.AppConfig.App.StateFile; path != "" {
+			if err := registry.SaveStateFile(path, config.AppConfig.App.Version, accounts); err != nil {
+				slog.Error("Failed to save state", "path", path, "error", err)
+			} else {
+				slog.Info("Saved state", "path", path)
+			}
+		}
+	})
+}
+
+// healthCheckTimeout is the longest a readiness check may take
+const healthCheckTimeout = 2 * time.Second
+
+// rateLimitSettings returns the limit of every API route
+func rateLimitSettings(limits config.RateLimit) services.RateLimitSettings {
+	return services.RateLimitSettings{Rate: limits.Rate, Burst: limits.Burst}
+}
+
+// expensiveRateLimitSettings returns the limit of simulation and prediction routes
+func expensiveRateLimitSettings(limits config.RateLimit) services.RateLimitSettings {
+	return services.RateLimitSettings{Rate: limits.ExpensiveRate, Burst: limits.ExpensiveBurst}
+}
+
+// simulationSettings returns the simulation settings of a configuration
+func simulationSettings(cfg config.Config) services.SimulationSettings {
+	return services.SimulationSettings{
+		Simulations:   cfg.Simulation.Simulations,
+		DefaultPreset: cfg.Simulation.DefaultPreset,
+		Presets:       cfg.Simulation.ModelPresets(),
+	}
+}
+
+// applyPresets gives the leagues using a model preset its current parameters
+func applyPresets(registry *services.LeagueRegistry) {
+	changed, err := registry.ApplyPresets(context.Background(), services.CurrentSimulationSettings())
+	if len(changed) > 0 {
+		slog.Info("Applied model presets", "leagues", changed)
+	}
+	if err != nil {
+		slog.Error("Failed to apply model presets", "error", err)
 	}
 }
+
+// registerLeagueRoutes adds the endpoints of a single league to a group.
+// Viewers may read the league; changing it takes the owner. API keys also
+// need the scope of each group. Requests hold the league's lock, exclusively
+// when they change the league. Routes that simulate matches, run forecasts or
+// recalculate predictions also pass the expensive rate limit.
+func registerLeagueRoutes(
+	league *gin.RouterGroup,
+	authHandler *handlers.AuthHandler,
+	leagueHandler *handlers.LeagueHandler,
+	jobHandler *handlers.JobHandler,
+	expensive gin.HandlerFunc,
+) {
+	read := league.Group("",
+		authHandler.RequireScope(models.ScopeLeagueRead),
+		authHandler.RequireLeagueRole(models.RoleViewer),
+		handlers.LockLeague(false))
+	{
+		read.GET("", leagueHandler.GetLeague)
+		read.GET("/standings", leagueHandler.GetStandings)
+		read.GET("/predictions", expensive, leagueHandler.GetPredictions)
+		read.GET("/analysis/:teamId", expensive, leagueHandler.AnalyzeTeam)
+		read.GET("/odds", expensive, leagueHandler.GetOdds)
+		read.POST("/odds/compare", expensive, leagueHandler.CompareOdds)
+		read.GET("/export/:dataset", leagueHandler.Export)
+		read.GET("/snapshot", leagueHandler.ExportSnapshot)
+		read.POST("/scenarios/evaluate", expensive, leagueHandler.EvaluateScenario)
+		read.GET("/scenarios", leagueHandler.GetScenarios)
+		read.GET("/scenarios/compare", expensive, leagueHandler.CompareScenarios)
+		read.GET("/scenarios/:id", expensive, leagueHandler.GetScenario)
+		read.GET("/simulations", jobHandler.GetSeasonSimulations)
+		read.GET("/simulations/:id", jobHandler.GetSeasonSimulation)
+	}
+
+	simulate := league.Group("",
+		authHandler.RequireScope(models.ScopeLeagueSimulate),
+		authHandler.RequireLeagueRole(models.RoleOwner),
+		expensive)
+	{
+		simulate.POST("/play-next-week", handlers.LockLeague(true), leagueHandler.PlayNextWeek)
+		simulate.POST("/play-all-weeks", handlers.LockLeague(true), leagueHandler.PlayAllWeeks)
+		// Jobs run against a copy of the league
+		simulate.POST("/predictions/jobs", handlers.LockLeague(false), jobHandler.StartPrediction)
+		simulate.POST("/simulations", handlers.LockLeague(false), jobHandler.StartSeasonSimulation)
+	}
+
+	admin := league.Group("",
+		authHandler.RequireScope(models.ScopeLeagueAdmin),
+		authHandler.RequireLeagueRole(models.RoleOwner),
+		handlers.LockLeague(true))
+	{
+		admin.POST("/initialize", leagueHandler.Initialize)
+		admin.PUT("/match/:id", expensive, leagueHandler.UpdateMatch)
+		admin.POST("/reset", leagueHandler.ResetLeague)
+		admin.POST("/import/teams", leagueHandler.ImportTeams)
+		admin.POST("/import/results", expensive, leagueHandler.ImportResults)
+		admin.POST("/import/competition", expensive, leagueHandler.ImportCompetition)
+		admin.POST("/snapshot", leagueHandler.ImportSnapshot)
+		admin.POST("/scenarios", leagueHandler.SaveScenario)
+		admin.DELETE("/scenarios/:id", leagueHandler.DeleteScenario)
+		admin.POST("/shares", leagueHandler.CreateShare)
+		admin.GET("/shares", leagueHandler.GetShares)
+		admin.DELETE("/shares/:token", leagueHandler.RevokeShare)
+	}
+}
+
+// shutdown stops accepting requests, waits for in-flight requests and
+// background jobs until the shutdown timeout, and then saves the state
+func shutdown(server *http.Server, jobService *services.JobService, saveState func()) {
+	slog.Info("Shutting down, waiting for in-flight requests and background jobs", "jobs", jobService.Pending())
+	ctx, cancel := context.WithTimeout(context.Background(), config.AppConfig.App.ShutdownTimeout)
+	defer cancel()
+
+	// Jobs drain alongside the requests, as streams following a job end with it
+	var drained sync.WaitGroup
+	drained.Add(1)
+	go func() {
+		defer drained.Done()
+		if err := jobService.Shutdown(ctx); err != nil {
+			slog.Warn("Cancelled unfinished background jobs", "error", err)
+		}
+	}()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Closing unfinished requests", "error", err)
+		server.Close()
+	}
+	drained.Wait()
+
+	saveState()
+
+	slog.Info("Server stopped")
+}
+
+// stopTracing flushes and stops tracing once it is set up. fatal runs it as
+// well, since exiting skips deferred calls and the spans of a failed start
+// matter most.
+var stopTracing = func() {}
+
+// flushTraces exports the spans not yet exported and stops tracing
+func flushTraces(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		slog.Warn("Failed to export the last traces", "error", err)
+	}
+}
+
+// fatal logs an error that keeps the server from running, exports the traces
+// recorded so far and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	stopTracing()
+	os.Exit(1)
+}
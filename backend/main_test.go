@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"stadia-backend/handlers"
+	"stadia-backend/models"
+	"stadia-backend/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLeagueRouteAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authService, err := services.NewAuthService(services.AuthSettings{
+		Secret:   strings.Repeat("s", 32),
+		Issuer:   "stadia-test",
+		TokenTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	registry := services.NewLeagueRegistry(0)
+	jobService := services.NewJobService(services.JobSettings{Workers: 1, QueueSize: 1, Timeout: time.Minute, Retention: time.Minute})
+	defer jobService.Shutdown(context.Background())
+
+	bearer := func(username string) string {
+		user, err := authService.Register(username, "password123", "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		token, err := authService.IssueToken(user)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return "Bearer " + token.Token
+	}
+	owner, other := bearer("alice"), bearer("bob")
+	alice, _ := authService.Login("alice", "password123")
+	apiKey := func(scope models.Scope) string {
+		key, err := authService.CreateAPIKey(alice.ID, string(scope), []models.Scope{scope})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return key.Key
+	}
+	readKey, simulateKey, adminKey := apiKey(models.ScopeLeagueRead), apiKey(models.ScopeLeagueSimulate), apiKey(models.ScopeLeagueAdmin)
+
+	info, _ := registry.Create(alice.ID, "Friday League")
+	service, _, _ := registry.Get(info.ID)
+	if err := service.InitializeLeague([]*models.Team{
+		models.NewTeam("Team A", 85, ""),
+		models.NewTeam("Team B", 80, ""),
+		models.NewTeam("Team C", 70, ""),
+		models.NewTeam("Team D", 60, ""),
+	}); err != nil {
+		t.Fatalf("Failed to initialize league: %v", err)
+	}
+	share, _ := registry.CreateShare(info.ID)
+
+	authHandler := handlers.NewAuthHandler(authService, registry)
+	router := gin.New()
+	league := router.Group("/api/leagues/:leagueId", authHandler.Authenticate())
+	registerLeagueRoutes(league, authHandler, handlers.NewLeagueHandler(registry),
+		handlers.NewJobHandler(jobService, authHandler), func(c *gin.Context) { c.Next() })
+
+	// One route of each group: read, simulate and admin
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/standings"},
+		{http.MethodPost, "/play-next-week"},
+		{http.MethodPost, "/shares"},
+	}
+	callers := []struct {
+		name    string
+		headers map[string]string
+		want    [3]int // Status of each route
+	}{
+		{"owner", map[string]string{"Authorization": owner}, [3]int{200, 200, 201}},
+		{"other user", map[string]string{"Authorization": other}, [3]int{403, 403, 403}},
+		{"share holder", map[string]string{handlers.ShareTokenHeader: share.Token}, [3]int{200, 403, 403}},
+		{"other user with share", map[string]string{"Authorization": other, handlers.ShareTokenHeader: share.Token}, [3]int{200, 403, 403}},
+		{"anonymous", nil, [3]int{401, 401, 401}},
+		{"read key", map[string]string{handlers.APIKeyHeader: readKey}, [3]int{200, 403, 403}},
+		{"simulate key", map[string]string{handlers.APIKeyHeader: simulateKey}, [3]int{200, 200, 403}},
+		{"admin key", map[string]string{handlers.APIKeyHeader: adminKey}, [3]int{200, 200, 201}},
+		{"invalid key", map[string]string{handlers.APIKeyHeader: "nope"}, [3]int{401, 401, 401}},
+	}
+	for _, caller := range callers {
+		for i, route := range routes {
+			req := httptest.NewRequest(route.method, "/api/leagues/"+info.ID+route.path, nil)
+			for name, value := range caller.headers {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != caller.want[i] {
+				t.Errorf("%s %s %s: expected %d, got %d: %s",
+					caller.name, route.method, route.path, caller.want[i], recorder.Code, recorder.Body.String())
+			}
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/leagues/missing/standings", nil)
+	req.Header.Set("Authorization", owner)
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown league to answer 404, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,121 @@
+// Package metrics exports HTTP, simulation and Go runtime metrics in the
+// Prometheus text format
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "stadia"
+
+// unmatchedRoute labels requests that match no route, so unknown paths do not
+// each get their own series
+const unmatchedRoute = "unmatched"
+
+// Metrics collects the metrics of a server. It receives the measurements of
+// the services as their instrumentation.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests              *prometheus.CounterVec
+	requestDuration       *prometheus.HistogramVec
+	monteCarloDuration    *prometheus.HistogramVec
+	monteCarloSimulations *prometheus.CounterVec
+	matchesSimulated      prometheus.Counter
+}
+
+// New creates the metrics, along with Go runtime and process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		monteCarloDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "monte_carlo_run_duration_seconds",
+			Help:      "Time taken by Monte Carlo runs, by kind: predictions, positions or seasons.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14), // 5ms to about 41s
+		}, []string{"kind"}),
+		monteCarloSimulations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "monte_carlo_simulations_total",
+			Help:      "Simulations run by Monte Carlo runs, by kind: predictions, positions or seasons.",
+		}, []string{"kind"}),
+		matchesSimulated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "matches_simulated_total",
+			Help:      "Matches played by simulation in leagues.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.monteCarloDuration,
+		m.monteCarloSimulations,
+		m.matchesSimulated,
+	)
+	return m
+}
+
+// WatchLeagues reports the number of leagues from count at every scrape
+func (m *Metrics) WatchLeagues(count func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_leagues",
+		Help:      "Leagues held by the server.",
+	}, func() float64 { return float64(count()) }))
+}
+
+// Middleware counts and times every request by method, route and status. Use
+// it before any middleware that may abort requests, including the recovery
+// from panics, so those are counted too.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() gin.HandlerFunc {
+	handler := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return gin.WrapH(handler)
+}
+
+// MonteCarloRun records the duration and simulations of a Monte Carlo run
+func (m *Metrics) MonteCarloRun(kind string, simulations int, duration time.Duration) {
+	m.monteCarloDuration.WithLabelValues(kind).Observe(duration.Seconds())
+	m.monteCarloSimulations.WithLabelValues(kind).Add(float64(simulations))
+}
+
+// MatchesSimulated records matches a league played by simulation
+func (m *Metrics) MatchesSimulated(n int) {
+	m.matchesSimulated.Add(float64(n))
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	m.WatchLeagues(func() int { return 3 })
+
+	router := gin.New()
+	router.Use(m.Middleware(), gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.GET("/api/league/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/panic", func(c *gin.Context) { panic("broken handler") })
+	router.GET("/metrics", m.Handler())
+
+	for _, path := range []string{"/api/league/1", "/api/league/2", "/nowhere", "/api/panic"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	m.MonteCarloRun("predictions", 10000, 250*time.Millisecond)
+	m.MatchesSimulated(4)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", recorder.Code)
+	}
+	body, _ := io.ReadAll(recorder.Body)
+
+	for _, want := range []string{
+		`stadia_http_requests_total{method="GET",route="/api/league/:id",status="200"} 2`,
+		`stadia_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`stadia_http_requests_total{method="GET",route="/api/panic",status="500"} 1`,
+		`stadia_http_request_duration_seconds_count{method="GET",route="/api/league/:id",status="200"} 2`,
+		`stadia_monte_carlo_run_duration_seconds_count{kind="predictions"} 1`,
+		`stadia_monte_carlo_simulations_total{kind="predictions"} 10000`,
+		`stadia_matches_simulated_total 4`,
+		`stadia_active_leagues 3`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+}
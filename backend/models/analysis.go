@@ -0,0 +1,57 @@
+package models
+
+// MatchOutcome is the result of a match from the home team's point of view
+type MatchOutcome string
+
+const (
+	OutcomeHomeWin MatchOutcome = "home_win"
+	OutcomeDraw    MatchOutcome = "draw"
+	OutcomeAwayWin MatchOutcome = "away_win"
+)
+
+// RequiredResult is the outcome a remaining match needs to have
+type RequiredResult struct {
+	MatchID      string       `json:"matchId"`
+	Week         int          `json:"week"`
+	HomeTeamName string       `json:"homeTeamName"`
+	AwayTeamName string       `json:"awayTeamName"`
+	Outcome      MatchOutcome `json:"outcome"`
+}
+
+// ResultSet is a combination of remaining results and how likely it is
+type ResultSet struct {
+	Results     []RequiredResult `json:"results"`
+	Probability float64          `json:"probability"` // Percentage (0-100)
+}
+
+// MatchImportance shows how much each outcome of a remaining match moves a team's chances
+type MatchImportance struct {
+	MatchID      string  `json:"matchId"`
+	Week         int     `json:"week"`
+	HomeTeamName string  `json:"homeTeamName"`
+	AwayTeamName string  `json:"awayTeamName"`
+	HomeWin      float64 `json:"homeWin"`    // Target probability after a home win (0-100)
+	Draw         float64 `json:"draw"`       // Target probability after a draw (0-100)
+	AwayWin      float64 `json:"awayWin"`    // Target probability after an away win (0-100)
+	Importance   float64 `json:"importance"` // Gap between the best and worst outcome, in percentage points
+}
+
+// TeamAnalysis describes what has to happen for a team to reach a target finish
+type TeamAnalysis struct {
+	TeamID      string           `json:"teamId"`
+	TeamName    string           `json:"teamName"`
+	Target      int              `json:"target"` // Finish in the top N
+	Week        int              `json:"week"`
+	Method      PredictionMethod `json:"method"`
+	Probability float64          `json:"probability"` // Percentage (0-100)
+
+	// Guarantee lists minimal sets of results that secure the target on points alone.
+	// Allow lists minimal sets after which the team is at worst level on points with
+	// the last qualifying place, so tiebreakers can still take it there.
+	Guarantee         []ResultSet `json:"guarantee"`
+	Allow             []ResultSet `json:"allow"`
+	ResultSetsOmitted bool        `json:"resultSetsOmitted,omitempty"` // Too many matches left to enumerate sets
+
+	MostLikelyPath  *ResultSet        `json:"mostLikelyPath"`
+	MatchImportance []MatchImportance `json:"matchImportance"`
+}
@@ -0,0 +1,63 @@
+package models
+
+// HistoricalSeason is a completed season used to evaluate the predictors
+type HistoricalSeason struct {
+	Name    string            `json:"name"`
+	Teams   []HistoricalTeam  `json:"teams"`
+	Matches []HistoricalMatch `json:"matches"`
+}
+
+// HistoricalTeam is a team taking part in a historical season
+type HistoricalTeam struct {
+	Name  string `json:"name"`
+	Power int    `json:"power"` // Team strength (1-100) as rated before the season
+	Logo  string `json:"logo,omitempty"`
+}
+
+// HistoricalMatch is a played match of a historical season
+type HistoricalMatch struct {
+	Week      int    `json:"week"`
+	Home      string `json:"home"`
+	Away      string `json:"away"`
+	HomeScore int    `json:"homeScore"`
+	AwayScore int    `json:"awayScore"`
+}
+
+// ForecastScores summarises how well probability forecasts matched what happened.
+// Lower is better for every score.
+type ForecastScores struct {
+	Forecasts int      `json:"forecasts"`
+	Brier     float64  `json:"brier"`
+	LogLoss   float64  `json:"logLoss"`
+	RPS       *float64 `json:"rps,omitempty"` // Ranked probability score, when ordered probabilities are available
+}
+
+// CalibrationBucket compares forecast probabilities in a range with how often the events happened
+type CalibrationBucket struct {
+	Lower             float64 `json:"lower"`
+	Upper             float64 `json:"upper"`
+	Forecasts         int     `json:"forecasts"`
+	MeanPredicted     float64 `json:"meanPredicted"`
+	ObservedFrequency float64 `json:"observedFrequency"`
+}
+
+// SeasonBacktest holds the scores for one replayed season
+type SeasonBacktest struct {
+	Season   string         `json:"season"`
+	Weeks    int            `json:"weeks"`
+	Champion string         `json:"champion"`
+	Outright ForecastScores `json:"outright"` // Championship forecasts made before each week
+	Matches  ForecastScores `json:"matches"`  // Match outcome forecasts
+}
+
+// BacktestReport holds the scores and calibration of a backtest over one or more seasons
+type BacktestReport struct {
+	Method              PredictionMethod    `json:"method"`
+	Model               ModelParams         `json:"model"` // Match model the forecasts were made with
+	Seasons             []SeasonBacktest    `json:"seasons"`
+	Outright            ForecastScores      `json:"outright"`
+	Matches             ForecastScores      `json:"matches"`
+	OutrightCalibration []CalibrationBucket `json:"outrightCalibration"`
+	MatchCalibration    []CalibrationBucket `json:"matchCalibration"`
+	RuntimeMs           float64             `json:"runtimeMs"`
+}
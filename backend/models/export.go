@@ -0,0 +1,25 @@
+package models
+
+// ExportDataset names a kind of exportable data
+type ExportDataset string
+
+const (
+	ExportStandings   ExportDataset = "standings"
+	ExportMatches     ExportDataset = "matches"
+	ExportPredictions ExportDataset = "predictions"
+)
+
+// ExportFormat names a file format for exports
+type ExportFormat string
+
+const (
+	FormatCSV       ExportFormat = "csv"
+	FormatJSONLines ExportFormat = "jsonl"
+)
+
+// ExportTable is a flat table of exported data. Column names are stable and
+// shared by every format; nil values are written as empty fields or null.
+type ExportTable struct {
+	Columns []string
+	Rows    [][]any
+}
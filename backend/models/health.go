@@ -0,0 +1,34 @@
+package models
+
+// ServerPhase is the lifecycle stage of the server
+type ServerPhase string
+
+const (
+	PhaseStarting ServerPhase = "starting" // Recovering the saved state
+	PhaseReady    ServerPhase = "ready"    // Serving requests
+	PhaseDraining ServerPhase = "draining" // Shutting down, finishing in-flight requests and jobs
+)
+
+// CheckStatus is the outcome of a readiness check
+type CheckStatus string
+
+const (
+	CheckOK     CheckStatus = "ok"
+	CheckFailed CheckStatus = "failed"
+)
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Name      string      `json:"name"`
+	Status    CheckStatus `json:"status"`
+	LatencyMs float64     `json:"latencyMs"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// ReadinessReport tells whether the server can take traffic, with the outcome
+// of every readiness check
+type ReadinessReport struct {
+	Ready  bool          `json:"ready"` // Ready to serve and every check passed
+	Phase  ServerPhase   `json:"phase"`
+	Checks []CheckResult `json:"checks"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// ImportRowError describes why a row of an imported file was rejected
+type ImportRowError struct {
+	Row     int    `json:"row"` // Line number in the file, the header being line 1
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarises an applied import
+type ImportResult struct {
+	Imported int     `json:"imported"` // Number of rows applied
+	League   *League `json:"league"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// JobKind names the work a background job does
+type JobKind string
+
+const (
+	JobSeasonSimulation JobKind = "season_simulation" // Batch of simulated seasons
+	JobPrediction       JobKind = "prediction"        // Championship predictions
+)
+
+// Job is long-running work done in the background
+type Job struct {
+	ID             string     `json:"id"`
+	Kind           JobKind    `json:"kind"`
+	LeagueID       string     `json:"leagueId"` // League the job was submitted for
+	Status         JobStatus  `json:"status"`
+	Progress       float64    `json:"progress"` // Percentage done
+	TimeoutSeconds float64    `json:"timeoutSeconds"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"` // When a finished job and its result are dropped
+	Error          string     `json:"error,omitempty"`
+	Result         any        `json:"result,omitempty"`
+}
+
+// IsFinished reports whether the job has stopped, successfully or not
+func (j *Job) IsFinished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed || j.Status == JobCancelled
+}
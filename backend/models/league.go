@@ -9,6 +9,28 @@ type League struct {
 	CurrentWeek int                `json:"currentWeek"`
 	TotalWeeks  int                `json:"totalWeeks"`
 	Predictions map[string]float64 `json:"predictions,omitempty"` // Team ID -> Win probability
+
+	// Stored predictions after each week, oldest first
+	PredictionHistory []PredictionSnapshot `json:"predictionHistory,omitempty"`
+
+	Settings LeagueSettings `json:"settings"`
+	Seed     int64          `json:"seed"` // Seed of the match simulation random source
+}
+
+// LeagueSettings holds the per-league options
+type LeagueSettings struct {
+	PredictionMethod    PredictionMethod `json:"predictionMethod"`    // Method of the stored predictions
+	PredictionStartWeek int              `json:"predictionStartWeek"` // Predictions are stored from this week onwards
+	Model               ModelParams      `json:"model"`               // Match model the league is simulated with
+}
+
+// DefaultLeagueSettings returns the settings of a new league
+func DefaultLeagueSettings() LeagueSettings {
+	return LeagueSettings{
+		PredictionMethod:    MethodAuto,
+		PredictionStartWeek: 3,
+		Model:               DefaultModelParams(),
+	}
 }
 
 // NewLeague creates a new league
@@ -21,6 +43,7 @@ func NewLeague(name string) *League {
 		CurrentWeek: 0,
 		TotalWeeks:  0,
 		Predictions: make(map[string]float64),
+		Settings:    DefaultLeagueSettings(),
 	}
 }
 
@@ -64,3 +87,45 @@ func (l *League) GetAllMatches() []*Match {
 func (l *League) IsFinished() bool {
 	return l.CurrentWeek >= l.TotalWeeks
 }
+
+// Clone returns a deep copy of the league that shares no teams or matches with the original
+func (l *League) Clone() *League {
+	clone := *l
+
+	clone.Teams = make(map[string]*Team, len(l.Teams))
+	for id, team := range l.Teams {
+		teamCopy := *team
+		clone.Teams[id] = &teamCopy
+	}
+
+	clone.Fixtures = make([][]*Match, len(l.Fixtures))
+	for week, weekMatches := range l.Fixtures {
+		clone.Fixtures[week] = make([]*Match, len(weekMatches))
+		for i, match := range weekMatches {
+			matchCopy := *match
+			clone.Fixtures[week][i] = &matchCopy
+		}
+	}
+
+	clone.Predictions = make(map[string]float64, len(l.Predictions))
+	for id, probability := range l.Predictions {
+		clone.Predictions[id] = probability
+	}
+
+	// Snapshots are replaced rather than modified, so they can be shared
+	clone.PredictionHistory = append([]PredictionSnapshot(nil), l.PredictionHistory...)
+
+	return &clone
+}
+
+// GetMatch retrieves a match by ID
+func (l *League) GetMatch(id string) *Match {
+	for _, weekMatches := range l.Fixtures {
+		for _, match := range weekMatches {
+			if match.ID == id {
+				return match
+			}
+		}
+	}
+	return nil
+}
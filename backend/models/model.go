@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Names of the built-in match model presets
+const (
+	PresetRealistic   = "realistic"    // Goal rates of a typical European league
+	PresetHighScoring = "high-scoring" // More goals, and bigger wins for stronger teams
+	PresetChaotic     = "chaotic"      // Power matters less, so upsets are common
+)
+
+// ModelParams are the parameters of the match model a league is simulated
+// with. Each side expects BaseGoals × (its power / the other side's
+// power)^PowerExponent goals, times a random factor between NoiseMin and
+// NoiseMax, at most GoalCap; the home side's power is raised by HomeAdvantage
+// percent first.
+type ModelParams struct {
+	Preset              string  `json:"preset,omitempty"`    // Preset the parameters were taken from
+	HomeAdvantage       float64 `json:"homeAdvantage"`       // Percentage added to the power of the home team
+	BaseGoals           float64 `json:"baseGoals"`           // Expected goals of each side when powers are equal
+	PowerExponent       float64 `json:"powerExponent"`       // How strongly the power ratio scales expected goals
+	NoiseMin            float64 `json:"noiseMin"`            // Lowest random factor on expected goals
+	NoiseMax            float64 `json:"noiseMax"`            // Highest random factor on expected goals
+	GoalCap             float64 `json:"goalCap"`             // Most goals a side may be expected to score
+	WinProbabilityScale float64 `json:"winProbabilityScale"` // Slope of the logistic win probability per power point
+}
+
+// BuiltinModelPresets returns the match model presets available without
+// configuration. The realistic preset is the model leagues were simulated with
+// before models could be chosen.
+func BuiltinModelPresets() map[string]ModelParams {
+	return map[string]ModelParams{
+		PresetRealistic: {
+			Preset: PresetRealistic, HomeAdvantage: 10, BaseGoals: 1.5, PowerExponent: 0.4,
+			NoiseMin: 0.8, NoiseMax: 1.2, GoalCap: 4.5, WinProbabilityScale: 0.05,
+		},
+		PresetHighScoring: {
+			Preset: PresetHighScoring, HomeAdvantage: 10, BaseGoals: 2.2, PowerExponent: 0.5,
+			NoiseMin: 0.8, NoiseMax: 1.2, GoalCap: 6.5, WinProbabilityScale: 0.05,
+		},
+		PresetChaotic: {
+			Preset: PresetChaotic, HomeAdvantage: 5, BaseGoals: 1.5, PowerExponent: 0.2,
+			NoiseMin: 0.4, NoiseMax: 1.6, GoalCap: 4.5, WinProbabilityScale: 0.02,
+		},
+	}
+}
+
+// DefaultModelParams returns the parameters of the realistic preset
+func DefaultModelParams() ModelParams {
+	return BuiltinModelPresets()[PresetRealistic]
+}
+
+// Validate checks that the parameters describe a usable model
+func (p ModelParams) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(p.HomeAdvantage >= 0 && p.HomeAdvantage <= 100, "home advantage must be a percentage from 0 to 100")
+	check(p.BaseGoals > 0 && p.BaseGoals <= 10, "base goals must be above 0 and at most 10")
+	check(p.PowerExponent >= 0 && p.PowerExponent <= 5, "power exponent must be between 0 and 5")
+	check(p.NoiseMin > 0 && p.NoiseMin <= p.NoiseMax, "noise band must be above 0, with the minimum at most the maximum")
+	check(p.NoiseMax <= 5, "noise maximum must be at most 5")
+	check(p.GoalCap > 0 && p.GoalCap <= 15, "goal cap must be above 0 and at most 15")
+	check(p.WinProbabilityScale > 0, "win probability scale must be positive")
+
+	return errors.Join(problems...)
+}
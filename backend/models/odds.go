@@ -0,0 +1,96 @@
+package models
+
+// Odds expresses a price in the common odds formats
+type Odds struct {
+	Decimal    float64 `json:"decimal"`    // Total return per unit staked, e.g. 2.50
+	Fractional string  `json:"fractional"` // Profit to stake, e.g. "3/2"
+	American   string  `json:"american"`   // Moneyline, e.g. "+150" or "-200"
+}
+
+// Price is a model probability with the odds it converts to
+type Price struct {
+	Probability float64 `json:"probability"` // Percentage (0-100)
+	Odds        *Odds   `json:"odds"`        // Nil when the outcome cannot happen
+}
+
+// MatchPrices holds the prices of the three outcomes of an unplayed match
+type MatchPrices struct {
+	MatchID      string `json:"matchId"`
+	Week         int    `json:"week"`
+	HomeTeamName string `json:"homeTeamName"`
+	AwayTeamName string `json:"awayTeamName"`
+	HomeWin      Price  `json:"homeWin"`
+	Draw         Price  `json:"draw"`
+	AwayWin      Price  `json:"awayWin"`
+}
+
+// OutrightPrice holds the price of a team winning the league
+type OutrightPrice struct {
+	TeamID   string `json:"teamId"`
+	TeamName string `json:"teamName"`
+	Price
+}
+
+// OddsResponse contains the model's odds for unplayed matches and the outright winner
+type OddsResponse struct {
+	Week     int              `json:"week"`
+	Method   PredictionMethod `json:"method"` // Method used for the outright probabilities
+	Margin   float64          `json:"margin"` // Bookmaker margin built into the odds, percentage
+	Matches  []MatchPrices    `json:"matches"`
+	Outright []OutrightPrice  `json:"outright"`
+}
+
+// BookmakerMatchOdds are a bookmaker's decimal odds for the outcomes of a match
+type BookmakerMatchOdds struct {
+	MatchID string  `json:"matchId" binding:"required"`
+	HomeWin float64 `json:"homeWin" binding:"gt=1"`
+	Draw    float64 `json:"draw" binding:"gt=1"`
+	AwayWin float64 `json:"awayWin" binding:"gt=1"`
+}
+
+// BookmakerOutrightOdds are a bookmaker's decimal odds for a team winning the league
+type BookmakerOutrightOdds struct {
+	TeamID string  `json:"teamId" binding:"required"`
+	Odds   float64 `json:"odds" binding:"gt=1"`
+}
+
+// SelectionComparison compares the model with a bookmaker on one selection
+type SelectionComparison struct {
+	MatchID            string       `json:"matchId,omitempty"`
+	TeamID             string       `json:"teamId,omitempty"`
+	Selection          string       `json:"selection"` // Team name, or "Draw" for a drawn match
+	BookmakerOdds      float64      `json:"bookmakerOdds"`
+	ImpliedProbability float64      `json:"impliedProbability"` // 1 / odds, percentage including the overround
+	FairProbability    float64      `json:"fairProbability"`    // Implied probability with the overround removed, percentage
+	ModelProbability   float64      `json:"modelProbability"`   // Percentage
+	Difference         float64      `json:"difference"`         // Model minus fair probability, percentage points
+	Edge               float64      `json:"edge"`               // Expected return of a bet at the bookmaker's odds, percentage
+	Value              bool         `json:"value"`              // Edge at or above the threshold
+	Outcome            MatchOutcome `json:"outcome,omitempty"`
+}
+
+// MatchComparison compares the model with a bookmaker on a match
+type MatchComparison struct {
+	MatchID      string                `json:"matchId"`
+	Week         int                   `json:"week"`
+	HomeTeamName string                `json:"homeTeamName"`
+	AwayTeamName string                `json:"awayTeamName"`
+	Overround    float64               `json:"overround"` // Percentage
+	Selections   []SelectionComparison `json:"selections"`
+}
+
+// OutrightComparison compares the model with a bookmaker on the league winner
+type OutrightComparison struct {
+	Overround  float64               `json:"overround"` // Percentage
+	Selections []SelectionComparison `json:"selections"`
+}
+
+// OddsComparison contains the comparison of the model with bookmaker odds
+type OddsComparison struct {
+	Week       int                   `json:"week"`
+	Method     PredictionMethod      `json:"method,omitempty"` // Method used for the outright probabilities
+	Threshold  float64               `json:"threshold"`        // Minimum edge for a value spot, percentage
+	Matches    []MatchComparison     `json:"matches"`
+	Outright   *OutrightComparison   `json:"outright,omitempty"`
+	ValueSpots []SelectionComparison `json:"valueSpots"` // Selections with value, largest edge first
+}
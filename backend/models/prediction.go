@@ -1,5 +1,15 @@
 package models
 
+// PredictionMethod identifies the algorithm used to calculate predictions
+type PredictionMethod string
+
+const (
+	MethodAuto       PredictionMethod = "auto"        // Exact when feasible, Monte Carlo otherwise
+	MethodMonteCarlo PredictionMethod = "monte_carlo" // Random sampling of the remaining season
+	MethodHeuristic  PredictionMethod = "heuristic"   // Fast projection from expected points
+	MethodExact      PredictionMethod = "exact"       // Enumeration of every remaining outcome
+)
+
 // Prediction represents the championship probability for a team
 type Prediction struct {
 	TeamID      string  `json:"teamId"`
@@ -9,6 +19,15 @@ type Prediction struct {
 
 // PredictionResponse contains predictions for all teams
 type PredictionResponse struct {
-	Week        int          `json:"week"`
-	Predictions []Prediction `json:"predictions"`
+	Week        int              `json:"week"`
+	Method      PredictionMethod `json:"method,omitempty"`
+	RuntimeMs   float64          `json:"runtimeMs"` // Time spent calculating the predictions
+	Predictions []Prediction     `json:"predictions"`
+}
+
+// PredictionSnapshot holds the league predictions stored after a week
+type PredictionSnapshot struct {
+	Week          int                `json:"week"`
+	Method        PredictionMethod   `json:"method"`
+	Probabilities map[string]float64 `json:"probabilities"` // Team ID -> Win probability
 }
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ScenarioResult is a hypothetical result for an unplayed match
+type ScenarioResult struct {
+	MatchID   string `json:"matchId" binding:"required"`
+	HomeScore int    `json:"homeScore" binding:"min=0"`
+	AwayScore int    `json:"awayScore" binding:"min=0"`
+}
+
+// Scenario is a named set of hypothetical results saved for later comparison
+type Scenario struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Results   []ScenarioResult `json:"results"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+// ScenarioOutcome is the league as it would stand after a set of hypothetical results
+type ScenarioOutcome struct {
+	ScenarioID  string              `json:"scenarioId,omitempty"`
+	Name        string              `json:"name,omitempty"`
+	Results     []ScenarioResult    `json:"results"`
+	Standings   []Standing          `json:"standings"`
+	Predictions *PredictionResponse `json:"predictions"`
+}
+
+// ScenarioComparison places saved scenarios side by side with the current league
+type ScenarioComparison struct {
+	Baseline  *ScenarioOutcome   `json:"baseline"`
+	Scenarios []*ScenarioOutcome `json:"scenarios"`
+}
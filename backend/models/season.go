@@ -0,0 +1,45 @@
+package models
+
+// SeasonSimulationOptions configures a batch of simulated seasons
+type SeasonSimulationOptions struct {
+	Seasons          int   `json:"seasons"`          // Number of seasons to simulate
+	FromStart        bool  `json:"fromStart"`        // Replay the whole season from week 0 instead of the current standings
+	Seed             int64 `json:"seed"`             // Seed for the simulations; derived from the league when 0
+	QualifyingPlaces int   `json:"qualifyingPlaces"` // Positions that qualify; 2 when 0
+}
+
+// SeasonSimulationSummary aggregates the final tables of many simulated
+// completions of the season
+type SeasonSimulationSummary struct {
+	Seasons          int                 `json:"seasons"`
+	Week             int                 `json:"week"` // Week the simulations started from
+	Seed             int64               `json:"seed"`
+	QualifyingPlaces int                 `json:"qualifyingPlaces"`
+	RuntimeMs        float64             `json:"runtimeMs"`
+	WinningPoints    Distribution        `json:"winningPoints"` // Points of the champion
+	Teams            []TeamSeasonSummary `json:"teams"`         // Best average position first
+}
+
+// TeamSeasonSummary aggregates one team's simulated final positions and records
+type TeamSeasonSummary struct {
+	TeamID                   string       `json:"teamId"`
+	TeamName                 string       `json:"teamName"`
+	ChampionProbability      float64      `json:"championProbability"`      // Percentage (0-100)
+	QualificationProbability float64      `json:"qualificationProbability"` // Percentage finishing in the qualifying places
+	Positions                []float64    `json:"positions"`                // Percentage finishing in each position, first place first
+	AveragePosition          float64      `json:"averagePosition"`
+	Points                   Distribution `json:"points"`
+	GoalDifference           Distribution `json:"goalDifference"`
+}
+
+// Distribution describes the spread of a whole-number statistic over simulated seasons
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	Min    int     `json:"min"`
+	P5     int     `json:"p5"`
+	P25    int     `json:"p25"`
+	Median int     `json:"median"`
+	P75    int     `json:"p75"`
+	P95    int     `json:"p95"`
+	Max    int     `json:"max"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	// SnapshotKind identifies a league snapshot file
+	SnapshotKind = "stadia.league"
+
+	// SnapshotSchemaVersion is the snapshot schema written by this version.
+	// Version 1 is the plain league JSON of GET /api/league, without an envelope.
+	SnapshotSchemaVersion = 2
+)
+
+// LeagueSnapshot is a complete, self-describing copy of a league that can be
+// restored in another environment
+type LeagueSnapshot struct {
+	Kind          string      `json:"kind"`
+	SchemaVersion int         `json:"schemaVersion"`
+	AppVersion    string      `json:"appVersion,omitempty"` // Version of the application that wrote the snapshot
+	ExportedAt    time.Time   `json:"exportedAt"`
+	League        *League     `json:"league"`
+	Scenarios     []*Scenario `json:"scenarios"`
+}
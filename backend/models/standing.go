@@ -0,0 +1,25 @@
+package models
+
+// QualificationStatus describes whether a team can still finish within a position threshold
+type QualificationStatus string
+
+const (
+	QualificationClinched   QualificationStatus = "clinched"   // Guaranteed whatever the remaining results
+	QualificationEliminated QualificationStatus = "eliminated" // Cannot be reached even on tiebreakers
+	QualificationAlive      QualificationStatus = "alive"      // Still depends on remaining results
+)
+
+// PositionOutlook is a team's mathematical status for finishing in the top N
+type PositionOutlook struct {
+	Threshold    int                 `json:"threshold"` // Finish in the top N
+	Label        string              `json:"label"`     // winner, top2, top3
+	Status       QualificationStatus `json:"status"`
+	PointsNeeded *int                `json:"pointsNeeded"` // Points that guarantee the threshold, null if own results cannot
+}
+
+// Standing is a row of the league table with clinch and elimination flags
+type Standing struct {
+	*Team
+	Position int               `json:"position"`
+	Outlook  []PositionOutlook `json:"outlook"`
+}
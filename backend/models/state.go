@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+const (
+	// StateKind identifies a server state file
+	StateKind = "stadia.state"
+
+	// StateSchemaVersion is the state file schema written by this version
+	StateSchemaVersion = 1
+)
+
+// ServerState is everything the server keeps between restarts: the user
+// accounts and every league with its owner and sharing links
+type ServerState struct {
+	Kind          string    `json:"kind"`
+	SchemaVersion int       `json:"schemaVersion"`
+	AppVersion    string    `json:"appVersion,omitempty"`
+	SavedAt       time.Time `json:"savedAt"`
+	Accounts
+	Leagues []*LeagueRecord `json:"leagues"`
+}
+
+// Accounts are the users and their API keys
+type Accounts struct {
+	Users   []*UserRecord   `json:"users"`
+	APIKeys []*APIKeyRecord `json:"apiKeys"`
+}
+
+// LeagueRecord is a league with its ownership, as kept in the state file
+type LeagueRecord struct {
+	LeagueInfo
+	Shares   []*ShareLink    `json:"shares"`
+	Snapshot *LeagueSnapshot `json:"snapshot,omitempty"` // Missing while the league has no teams
+}
@@ -15,6 +15,7 @@ type Team struct {
 	GoalsAgainst int    `json:"goalsAgainst"`   // Goals conceded
 	Points       int    `json:"points"`         // Total points
 	Logo         string `json:"logo,omitempty"` // Team logo URL
+	Country      string `json:"country,omitempty"`
 }
 
 // NewTeam creates a new team with a unique ID
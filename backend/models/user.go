@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// Role is what a user may do with a league
+type Role string
+
+const (
+	RoleOwner  Role = "owner"  // Read and change the league, manage its sharing links
+	RoleViewer Role = "viewer" // Read the league only
+)
+
+// User is an account that can own leagues
+type User struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UserRecord is a user with the password hash, as kept in the state file
+type UserRecord struct {
+	User
+	PasswordHash string `json:"passwordHash"`
+}
+
+// Scope is what an API key may be used for
+type Scope string
+
+const (
+	ScopeLeagueRead     Scope = "league:read"     // Read leagues, standings, predictions and jobs
+	ScopeLeagueSimulate Scope = "league:simulate" // Play weeks and run simulation and prediction jobs; includes league:read
+	ScopeLeagueAdmin    Scope = "league:admin"    // Create, change, reset, import and share leagues; includes every scope
+)
+
+// Scopes are the known API key scopes
+var Scopes = []Scope{ScopeLeagueRead, ScopeLeagueSimulate, ScopeLeagueAdmin}
+
+// APIKey lets an automation client act for a user, limited to its scopes
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	UserID     string     `json:"userId"`
+	Prefix     string     `json:"prefix"` // Start of the key, to tell keys apart
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// HasScope reports whether the key grants a scope, directly or through a
+// broader scope
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		switch {
+		case granted == scope, granted == ScopeLeagueAdmin:
+			return true
+		case granted == ScopeLeagueSimulate && scope == ScopeLeagueRead:
+			return true
+		}
+	}
+	return false
+}
+
+// CreatedAPIKey is a new API key with the secret key, which is shown only once
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyRecord is an API key with the hash of its secret, as kept in the state file
+type APIKeyRecord struct {
+	APIKey
+	Hash string `json:"hash"` // Hex SHA-256 of the key
+}
+
+// AuthToken is a signed bearer token issued at login
+type AuthToken struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"tokenType"` // Always "Bearer"
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      *User     `json:"user"`
+}
+
+// LeagueInfo describes a league and who owns it
+type LeagueInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   string    `json:"ownerId,omitempty"` // Empty for a league nobody owns yet
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ShareLink gives read-only access to a league to anyone holding its token
+type ShareLink struct {
+	Token     string    `json:"token"`
+	LeagueID  string    `json:"leagueId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "testing"
+
+func TestAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		granted Scope
+		allowed []Scope
+		denied  []Scope
+	}{
+		{ScopeLeagueRead, []Scope{ScopeLeagueRead}, []Scope{ScopeLeagueSimulate, ScopeLeagueAdmin}},
+		{ScopeLeagueSimulate, []Scope{ScopeLeagueRead, ScopeLeagueSimulate}, []Scope{ScopeLeagueAdmin}},
+		{ScopeLeagueAdmin, Scopes, nil},
+	}
+
+	for _, test := range tests {
+		key := &APIKey{Scopes: []Scope{test.granted}}
+		for _, scope := range test.allowed {
+			if !key.HasScope(scope) {
+				t.Errorf("%s: expected %s to be granted", test.granted, scope)
+			}
+		}
+		for _, scope := range test.denied {
+			if key.HasScope(scope) {
+				t.Errorf("%s: expected %s to be denied", test.granted, scope)
+			}
+		}
+	}
+}
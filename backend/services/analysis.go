@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"stadia-backend/models"
+)
+
+const (
+	// maxPathMatches bounds the remaining matches for which result sets and the
+	// most likely path are enumerated
+	maxPathMatches = 8
+
+	// maxResultSets bounds the number of result sets reported per category
+	maxResultSets = 20
+)
+
+// ErrTeamNotFound is returned when a team does not exist in the league
+var ErrTeamNotFound = errors.New("team not found")
+
+// outcomeNames maps the solver's outcome index to its API name
+var outcomeNames = [3]models.MatchOutcome{models.OutcomeHomeWin, models.OutcomeDraw, models.OutcomeAwayWin}
+
+// AnalyzeTeam explains what has to happen for a team to finish in the top target
+// positions: the minimal sets of remaining results that guarantee or allow it,
+// the most likely way to get there and how much each remaining match matters
+func (ls *LeagueService) AnalyzeTeam(ctx context.Context, teamID string, target int) (*models.TeamAnalysis, error) {
+	team := ls.league.GetTeam(teamID)
+	if team == nil {
+		return nil, ErrTeamNotFound
+	}
+
+	teams := ls.league.GetTeamsList()
+	if target < 1 || target > len(teams) {
+		return nil, fmt.Errorf("target must be between 1 and %d", len(teams))
+	}
+
+	forecast, err := ls.predictionService.ForecastPositions(ctx, teams, ls.league.Fixtures)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &models.TeamAnalysis{
+		TeamID:          team.ID,
+		TeamName:        team.Name,
+		Target:          target,
+		Week:            ls.league.CurrentWeek,
+		Method:          forecast.Method,
+		Probability:     topProbability(forecast.Positions[team.ID], target) * 100,
+		Guarantee:       make([]models.ResultSet, 0),
+		Allow:           make([]models.ResultSet, 0),
+		MatchImportance: make([]models.MatchImportance, 0, len(forecast.Matches)),
+	}
+
+	for m, match := range forecast.Matches {
+		var chances [3]float64
+		for o := range chances {
+			chances[o] = topProbability(forecast.ByOutcome[m][o][team.ID], target) * 100
+		}
+		analysis.MatchImportance = append(analysis.MatchImportance, models.MatchImportance{
+			MatchID:      match.ID,
+			Week:         match.Week,
+			HomeTeamName: match.HomeTeamName,
+			AwayTeamName: match.AwayTeamName,
+			HomeWin:      chances[0],
+			Draw:         chances[1],
+			AwayWin:      chances[2],
+			Importance:   math.Max(chances[0], math.Max(chances[1], chances[2])) - math.Min(chances[0], math.Min(chances[1], chances[2])),
+		})
+	}
+	sort.SliceStable(analysis.MatchImportance, func(i, j int) bool {
+		return analysis.MatchImportance[i].Importance > analysis.MatchImportance[j].Importance
+	})
+
+	if len(forecast.Matches) > maxPathMatches {
+		analysis.ResultSetsOmitted = true
+		return analysis, nil
+	}
+
+	paths := newPathFinder(teams, team, target, forecast)
+	analysis.Guarantee = paths.minimalSets(paths.outright)
+	analysis.Allow = paths.minimalSets(paths.level)
+	analysis.MostLikelyPath = paths.mostLikely()
+
+	return analysis, nil
+}
+
+// topProbability sums the probabilities of finishing in the first target positions
+func topProbability(positions []float64, target int) float64 {
+	total := 0.0
+	for _, p := range positions[:target] {
+		total += p
+	}
+	return math.Min(1, total)
+}
+
+// pathFinder enumerates win/draw/loss combinations of the remaining matches.
+// Complete combinations are indexed in base 3 (one digit per match); partial
+// ones in base 4, where digit 0 leaves the match open.
+type pathFinder struct {
+	matches  []*models.Match
+	outcomes [][3]float64
+
+	// outright[c]: the team reaches the target on points in combination c.
+	// level[c]: the team is at worst level on points with the last qualifying place.
+	// probability[c]: model probability of combination c.
+	outright    []bool
+	level       []bool
+	probability []float64
+}
+
+// newPathFinder evaluates every complete combination for the team and target
+func newPathFinder(teams []*models.Team, team *models.Team, target int, forecast *PositionForecast) *pathFinder {
+	index := make(map[string]int)
+	for i, t := range teams {
+		index[t.ID] = i
+	}
+
+	combinations := int(math.Pow(3, float64(len(forecast.Matches))))
+	pf := &pathFinder{
+		matches:     forecast.Matches,
+		outcomes:    forecast.Outcomes,
+		outright:    make([]bool, combinations),
+		level:       make([]bool, combinations),
+		probability: make([]float64, combinations),
+	}
+
+	awarded := [3][2]int{{3, 0}, {1, 1}, {0, 3}}
+	points := make([]int, len(teams))
+	self := index[team.ID]
+	for c := 0; c < combinations; c++ {
+		for i, t := range teams {
+			points[i] = t.Points
+		}
+		pf.probability[c] = 1.0
+		code := c
+		for m, match := range pf.matches {
+			outcome := code % 3
+			code /= 3
+			points[index[match.HomeTeamID]] += awarded[outcome][0]
+			points[index[match.AwayTeamID]] += awarded[outcome][1]
+			pf.probability[c] *= pf.outcomes[m][outcome]
+		}
+
+		ahead, level := 0, 0
+		for i := range teams {
+			if i == self {
+				continue
+			}
+			if points[i] > points[self] {
+				ahead++
+			} else if points[i] == points[self] {
+				level++
+			}
+		}
+		pf.outright[c] = ahead+level < target
+		pf.level[c] = ahead < target
+	}
+
+	return pf
+}
+
+// minimalSets returns the smallest sets of results after which every completion
+// satisfies the condition, most likely first within each size
+func (pf *pathFinder) minimalSets(satisfied []bool) []models.ResultSet {
+	n := len(pf.matches)
+	partials := int(math.Pow(4, float64(n)))
+
+	// forced[p]: every completion of partial combination p satisfies the condition
+	forced := make([]bool, partials)
+	for p := partials - 1; p >= 0; p-- {
+		open := -1
+		complete := 0
+		code, weight := p, 1
+		for m := 0; m < n; m++ {
+			digit := code % 4
+			code /= 4
+			if digit == 0 {
+				open = m
+				break
+			}
+			complete += (digit - 1) * weight
+			weight *= 3
+		}
+
+		if open < 0 {
+			forced[p] = satisfied[complete]
+			continue
+		}
+
+		// Fixing the open match yields a larger code, which is already known
+		step := int(math.Pow(4, float64(open)))
+		forced[p] = forced[p+step] && forced[p+2*step] && forced[p+3*step]
+	}
+
+	sets := make([]models.ResultSet, 0)
+	for p := 0; p < partials; p++ {
+		if !forced[p] {
+			continue
+		}
+
+		// Minimal if freeing any single result breaks the guarantee
+		minimal := true
+		set := models.ResultSet{Results: make([]models.RequiredResult, 0), Probability: 100}
+		code := p
+		for m := 0; m < n; m++ {
+			digit := code % 4
+			code /= 4
+			if digit == 0 {
+				continue
+			}
+			if forced[p-digit*int(math.Pow(4, float64(m)))] {
+				minimal = false
+				break
+			}
+			set.Results = append(set.Results, pf.requiredResult(m, digit-1))
+			set.Probability *= pf.outcomes[m][digit-1]
+		}
+		if minimal {
+			sets = append(sets, set)
+		}
+	}
+
+	sort.Slice(sets, func(i, j int) bool {
+		if len(sets[i].Results) != len(sets[j].Results) {
+			return len(sets[i].Results) < len(sets[j].Results)
+		}
+		return sets[i].Probability > sets[j].Probability
+	})
+	if len(sets) > maxResultSets {
+		sets = sets[:maxResultSets]
+	}
+
+	return sets
+}
+
+// mostLikely returns the most probable complete combination in which the team
+// reaches the target, preferring outright finishes over ones level on points
+func (pf *pathFinder) mostLikely() *models.ResultSet {
+	for _, satisfied := range [][]bool{pf.outright, pf.level} {
+		best := -1
+		for c, ok := range satisfied {
+			if ok && (best < 0 || pf.probability[c] > pf.probability[best]) {
+				best = c
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		path := &models.ResultSet{
+			Results:     make([]models.RequiredResult, 0, len(pf.matches)),
+			Probability: pf.probability[best] * 100,
+		}
+		code := best
+		for m := range pf.matches {
+			path.Results = append(path.Results, pf.requiredResult(m, code%3))
+			code /= 3
+		}
+		return path
+	}
+
+	return nil
+}
+
+// requiredResult describes the given outcome of remaining match m
+func (pf *pathFinder) requiredResult(m, outcome int) models.RequiredResult {
+	match := pf.matches[m]
+	return models.RequiredResult{
+		MatchID:      match.ID,
+		Week:         match.Week,
+		HomeTeamName: match.HomeTeamName,
+		AwayTeamName: match.AwayTeamName,
+		Outcome:      outcomeNames[outcome],
+	}
+}
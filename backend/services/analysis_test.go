@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"stadia-backend/models"
+	"testing"
+)
+
+// newLastWeekLeagueService creates a league with one week left:
+// A 12 points, B 6, C 4, D 1, with A v D and B v C to play
+func newLastWeekLeagueService() (*LeagueService, []*models.Team) {
+	teams := []*models.Team{
+		teamWithPoints("Team A", 4, 0, 1),
+		teamWithPoints("Team B", 2, 0, 3),
+		teamWithPoints("Team C", 1, 1, 3),
+		teamWithPoints("Team D", 0, 1, 4),
+	}
+
+	service := NewLeagueService()
+	league := service.GetLeague()
+	for _, team := range teams {
+		league.AddTeam(team)
+	}
+	league.Fixtures = [][]*models.Match{{
+		models.NewMatch(teams[0].ID, teams[3].ID, teams[0].Name, teams[3].Name, 1),
+		models.NewMatch(teams[1].ID, teams[2].ID, teams[1].Name, teams[2].Name, 1),
+	}}
+	league.TotalWeeks = 1
+
+	return service, teams
+}
+
+func TestAnalyzeTeamGuaranteeAndImportance(t *testing.T) {
+	service, teams := newLastWeekLeagueService()
+	teamB := teams[1]
+
+	analysis, err := service.AnalyzeTeam(context.Background(), teamB.ID, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Team B finishes second with a win or a draw against Team C
+	if len(analysis.Guarantee) != 2 {
+		t.Fatalf("Expected 2 guaranteeing result sets, got %d", len(analysis.Guarantee))
+	}
+	for _, set := range analysis.Guarantee {
+		if len(set.Results) != 1 || set.Results[0].AwayTeamName != "Team C" {
+			t.Errorf("Each guarantee should be a single result against Team C, got %+v", set.Results)
+		}
+		if set.Results[0].Outcome == models.OutcomeAwayWin {
+			t.Error("A defeat cannot guarantee second place")
+		}
+	}
+
+	if analysis.MatchImportance[0].AwayTeamName != "Team C" {
+		t.Errorf("The match against Team C should matter most, got %s v %s",
+			analysis.MatchImportance[0].HomeTeamName, analysis.MatchImportance[0].AwayTeamName)
+	}
+	if analysis.MatchImportance[0].Importance < 99.9 {
+		t.Errorf("The match against Team C decides everything, importance %.2f", analysis.MatchImportance[0].Importance)
+	}
+	if analysis.MatchImportance[1].Importance > 0.1 {
+		t.Errorf("Team A v Team D cannot change Team B's finish, importance %.2f", analysis.MatchImportance[1].Importance)
+	}
+}
+
+func TestAnalyzeTeamAllowOnTiebreakers(t *testing.T) {
+	service, teams := newLastWeekLeagueService()
+	teamD := teams[3]
+
+	analysis, err := service.AnalyzeTeam(context.Background(), teamD.ID, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(analysis.Guarantee) != 0 {
+		t.Errorf("Team D cannot guarantee third place, got %d result sets", len(analysis.Guarantee))
+	}
+
+	// Team D must beat Team A while Team C loses to Team B, leaving D level with C
+	if len(analysis.Allow) != 1 || len(analysis.Allow[0].Results) != 2 {
+		t.Fatalf("Expected one allowing set of two results, got %+v", analysis.Allow)
+	}
+	if analysis.MostLikelyPath == nil || len(analysis.MostLikelyPath.Results) != 2 {
+		t.Error("Expected a most likely path through both remaining matches")
+	}
+}
+
+func TestAnalyzeTeamErrors(t *testing.T) {
+	service, teams := newLastWeekLeagueService()
+
+	if _, err := service.AnalyzeTeam(context.Background(), "missing", 1); err != ErrTeamNotFound {
+		t.Errorf("Expected ErrTeamNotFound, got %v", err)
+	}
+	if _, err := service.AnalyzeTeam(context.Background(), teams[0].ID, 5); err == nil {
+		t.Error("Expected an error for a target beyond the number of teams")
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"stadia-backend/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to recognise
+const apiKeyPrefix = "sk_"
+
+var (
+	// ErrAPIKeyNotFound is returned for an unknown or revoked API key ID
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
+	// ErrInvalidAPIKey is returned for a key that does not exist or was revoked
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// apiKey is a stored API key
+type apiKey struct {
+	models.APIKey
+}
+
+// copy returns a copy that shares nothing with the stored key
+func (k *apiKey) copy() models.APIKey {
+	copied := k.APIKey
+	copied.Scopes = slices.Clone(k.Scopes)
+	if k.LastUsedAt != nil {
+		lastUsedAt := *k.LastUsedAt
+		copied.LastUsedAt = &lastUsedAt
+	}
+	return copied
+}
+
+// hashAPIKey returns the hash a key is stored by. Keys are long and random,
+// so a fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey creates an API key that acts for a user with the given scopes.
+// The key itself is returned only here; only its hash is kept.
+func (s *AuthService) CreateAPIKey(userID, name string, scopes []models.Scope) (*models.CreatedAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("API key name is required")
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accounts[userID] == nil {
+		return nil, ErrInvalidToken
+	}
+	stored := &apiKey{models.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		UserID:    userID,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedAt: time.Now().UTC(),
+	}}
+	s.apiKeys[hashAPIKey(key)] = stored
+
+	return &models.CreatedAPIKey{APIKey: stored.copy(), Key: key}, nil
+}
+
+// APIKeys returns the API keys of a user, oldest first
+func (s *AuthService) APIKeys(userID string) []*models.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*models.APIKey, 0)
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			copied := key.copy()
+			keys = append(keys, &copied)
+		}
+	}
+	sortByCreation(keys, func(key *models.APIKey) time.Time { return key.CreatedAt })
+	return keys
+}
+
+// RevokeAPIKey deletes one of a user's API keys, so it no longer authenticates
+func (s *AuthService) RevokeAPIKey(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, key := range s.apiKeys {
+		if key.ID == id && key.UserID == userID {
+			delete(s.apiKeys, hash)
+			return nil
+		}
+	}
+	return ErrAPIKeyNotFound
+}
+
+// AuthenticateAPIKey returns the user an API key acts for and the key with
+// its scopes, and records that the key was used
+func (s *AuthService) AuthenticateAPIKey(key string) (*models.User, *models.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.apiKeys[hashAPIKey(key)]
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	a, ok := s.accounts[stored.UserID]
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	now := time.Now().UTC()
+	stored.LastUsedAt = &now
+
+	user := a.user
+	copied := stored.copy()
+	return &user, &copied, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"stadia-backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	service := newTestAuthService(t, time.Hour)
+	user, _ := service.Register("alice", "correct horse", "")
+
+	created, err := service.CreateAPIKey(user.ID, "Dashboard", []models.Scope{models.ScopeLeagueRead, models.ScopeLeagueRead})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) || len(created.Scopes) != 1 {
+		t.Errorf("Expected the key to start with its prefix and duplicate scopes to be dropped, got %+v", created)
+	}
+	if created.LastUsedAt != nil {
+		t.Error("Expected a new key to be unused")
+	}
+
+	authenticated, key, err := service.AuthenticateAPIKey(created.Key)
+	if err != nil || authenticated.ID != user.ID {
+		t.Fatalf("Expected the key to act for alice, got %v, %v", authenticated, err)
+	}
+	if key.LastUsedAt == nil {
+		t.Error("Expected the last-used time to be recorded")
+	}
+	if listed := service.APIKeys(user.ID); len(listed) != 1 || listed[0].LastUsedAt == nil {
+		t.Errorf("Expected the listed key to show its last use, got %+v", listed)
+	}
+
+	// Only the hash is kept
+	for _, record := range service.Accounts().APIKeys {
+		if record.Hash == created.Key || strings.Contains(record.Hash, created.Key[len(created.Prefix):]) {
+			t.Error("Expected the key to be stored hashed")
+		}
+	}
+
+	if err := service.RevokeAPIKey("someone-else", created.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected other users not to revoke the key, got %v", err)
+	}
+	if err := service.RevokeAPIKey(user.ID, created.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, _, err := service.AuthenticateAPIKey(created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected a revoked key to be rejected, got %v", err)
+	}
+}
+
+func TestAPIKeyValidation(t *testing.T) {
+	service := newTestAuthService(t, time.Hour)
+	user, _ := service.Register("alice", "correct horse", "")
+
+	if _, err := service.CreateAPIKey(user.ID, "", []models.Scope{models.ScopeLeagueRead}); err == nil {
+		t.Error("Expected a key without a name to be rejected")
+	}
+	if _, err := service.CreateAPIKey(user.ID, "Script", nil); err == nil {
+		t.Error("Expected a key without scopes to be rejected")
+	}
+	if _, err := service.CreateAPIKey(user.ID, "Script", []models.Scope{"league:write"}); err == nil {
+		t.Error("Expected an unknown scope to be rejected")
+	}
+	if _, _, err := service.AuthenticateAPIKey("sk_unknown"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected an unknown key to be rejected, got %v", err)
+	}
+}
+
+func TestAPIKeyAccountsRoundTrip(t *testing.T) {
+	service := newTestAuthService(t, time.Hour)
+	user, _ := service.Register("alice", "correct horse", "")
+	created, _ := service.CreateAPIKey(user.ID, "Script", []models.Scope{models.ScopeLeagueSimulate})
+
+	restored := newTestAuthService(t, time.Hour)
+	if err := restored.RestoreAccounts(service.Accounts()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, key, err := restored.AuthenticateAPIKey(created.Key); err != nil || !key.HasScope(models.ScopeLeagueSimulate) {
+		t.Errorf("Expected the key to work after restoring, got %v", err)
+	}
+	if _, err := restored.Login("alice", "correct horse"); err != nil {
+		t.Errorf("Expected the user to be restored, got %v", err)
+	}
+
+	orphaned := service.Accounts()
+	orphaned.Users = nil
+	if err := restored.RestoreAccounts(orphaned); err == nil {
+		t.Error("Expected a key of an unknown user to be rejected")
+	}
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"regexp"
+	"stadia-backend/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// minPasswordLength is the shortest password accepted at registration
+	minPasswordLength = 8
+
+	// minSecretLength is the shortest signing key accepted, in bytes
+	minSecretLength = 32
+)
+
+var (
+	// ErrUsernameTaken is returned when registering a username that is in use
+	ErrUsernameTaken = errors.New("username is already taken")
+
+	// ErrAdminToken is returned when registering the admin account without
+	// the admin token
+	ErrAdminToken = errors.New("registering the admin account requires the admin token")
+
+	// ErrInvalidCredentials is returned for an unknown username or a wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+
+	// ErrInvalidToken is returned for a bearer token that is malformed, badly
+	// signed, expired or issued to a user that no longer exists
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// usernamePattern is the form of a valid username
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{2,31}$`)
+
+// dummyHash is compared against for unknown usernames
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("stadia-unknown-user"), bcrypt.DefaultCost)
+	return hash
+})
+
+// AuthSettings configures how tokens are signed and validated
+type AuthSettings struct {
+	Secret   string        // HMAC key that signs and validates tokens
+	Issuer   string        // Issuer written to and required in tokens
+	TokenTTL time.Duration // How long an issued token is valid
+	Admin    string        // Username of the account that owns leagues nobody owns; none when empty
+	// Token that registering the Admin account requires, so that nobody else
+	// can take the username first. Without it the account cannot register.
+	AdminToken string
+}
+
+// account is a user with the password hash
+type account struct {
+	user         models.User
+	passwordHash []byte
+}
+
+// AuthService manages user accounts and issues and validates signed bearer
+// tokens (HS256 JWTs signed with a local key)
+type AuthService struct {
+	settings   AuthSettings
+	mu         sync.RWMutex
+	accounts   map[string]*account // By user ID
+	byUsername map[string]*account
+	apiKeys    map[string]*apiKey // By hash of the key
+}
+
+// NewAuthService creates an auth service, refusing signing keys that are too short
+func NewAuthService(settings AuthSettings) (*AuthService, error) {
+	if len(settings.Secret) < minSecretLength {
+		return nil, fmt.Errorf("the token signing secret must be at least %d bytes", minSecretLength)
+	}
+	if settings.TokenTTL <= 0 {
+		return nil, errors.New("the token lifetime must be positive")
+	}
+	return &AuthService{
+		settings:   settings,
+		accounts:   make(map[string]*account),
+		byUsername: make(map[string]*account),
+		apiKeys:    make(map[string]*apiKey),
+	}, nil
+}
+
+// Register creates a user account. Usernames are case-insensitive. Only the
+// admin account needs adminToken, which must match AuthSettings.AdminToken.
+func (s *AuthService) Register(username, password, adminToken string) (*models.User, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return nil, errors.New("username must be 3 to 32 letters, digits, dots, dashes or underscores")
+	}
+	if s.settings.Admin != "" && username == strings.ToLower(s.settings.Admin) &&
+		(s.settings.AdminToken == "" || subtle.ConstantTimeCompare([]byte(adminToken), []byte(s.settings.AdminToken)) != 1) {
+		return nil, ErrAdminToken
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byUsername[username]; ok {
+		return nil, ErrUsernameTaken
+	}
+	a := &account{
+		user: models.User{
+			ID:        uuid.New().String(),
+			Username:  username,
+			CreatedAt: time.Now().UTC(),
+		},
+		passwordHash: hash,
+	}
+	s.accounts[a.user.ID] = a
+	s.byUsername[username] = a
+
+	user := a.user
+	return &user, nil
+}
+
+// Login checks a username and password and returns the user
+func (s *AuthService) Login(username, password string) (*models.User, error) {
+	s.mu.RLock()
+	a, ok := s.byUsername[strings.ToLower(strings.TrimSpace(username))]
+	s.mu.RUnlock()
+	if !ok {
+		// Compare anyway so that unknown usernames take as long as wrong passwords
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword(a.passwordHash, []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	user := a.user
+	return &user, nil
+}
+
+// IssueToken signs a bearer token for a user
+func (s *AuthService) IssueToken(user *models.User) (*models.AuthToken, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.settings.TokenTTL)
+	claims := jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Issuer:    s.settings.Issuer,
+		Subject:   user.ID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.settings.Secret))
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuthToken{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: expiresAt.Truncate(time.Second),
+		User:      user,
+	}, nil
+}
+
+// Authenticate validates a bearer token and returns the user it was issued to
+func (s *AuthService) Authenticate(token string) (*models.User, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return []byte(s.settings.Secret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(s.settings.Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return s.GetUser(claims.Subject)
+}
+
+// GetUser returns a user by ID
+func (s *AuthService) GetUser(id string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.accounts[id]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	user := a.user
+	return &user, nil
+}
+
+// Accounts returns every user with the password hash and every API key with
+// the hash of the key, oldest first, for the state file
+func (s *AuthService) Accounts() models.Accounts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accounts := models.Accounts{
+		Users:   make([]*models.UserRecord, 0, len(s.accounts)),
+		APIKeys: make([]*models.APIKeyRecord, 0, len(s.apiKeys)),
+	}
+	for _, a := range s.accounts {
+		accounts.Users = append(accounts.Users, &models.UserRecord{User: a.user, PasswordHash: string(a.passwordHash)})
+	}
+	for hash, key := range s.apiKeys {
+		accounts.APIKeys = append(accounts.APIKeys, &models.APIKeyRecord{APIKey: key.copy(), Hash: hash})
+	}
+	sortByCreation(accounts.Users, func(r *models.UserRecord) time.Time { return r.CreatedAt })
+	sortByCreation(accounts.APIKeys, func(r *models.APIKeyRecord) time.Time { return r.CreatedAt })
+	return accounts
+}
+
+// RestoreAccounts replaces every user and API key with the ones from the state file
+func (s *AuthService) RestoreAccounts(restored models.Accounts) error {
+	accounts := make(map[string]*account, len(restored.Users))
+	byUsername := make(map[string]*account, len(restored.Users))
+	for _, record := range restored.Users {
+		if record == nil || record.ID == "" || record.PasswordHash == "" {
+			return errors.New("user without an ID or password hash")
+		}
+		if _, ok := byUsername[record.Username]; ok {
+			return fmt.Errorf("username %q is used twice", record.Username)
+		}
+		a := &account{user: record.User, passwordHash: []byte(record.PasswordHash)}
+		accounts[record.ID] = a
+		byUsername[record.Username] = a
+	}
+
+	apiKeys := make(map[string]*apiKey, len(restored.APIKeys))
+	for _, record := range restored.APIKeys {
+		if record == nil || record.ID == "" || record.Hash == "" {
+			return errors.New("API key without an ID or hash")
+		}
+		if accounts[record.UserID] == nil {
+			return fmt.Errorf("API key %s belongs to unknown user %s", record.ID, record.UserID)
+		}
+		apiKeys[record.Hash] = &apiKey{APIKey: record.APIKey}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts = accounts
+	s.byUsername = byUsername
+	s.apiKeys = apiKeys
+	return nil
+}
+
+// Admin returns the account named by AuthSettings.Admin, or nil when none is
+// configured or it has not registered yet
+func (s *AuthService) Admin() *models.User {
+	if s.settings.Admin == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.byUsername[strings.ToLower(s.settings.Admin)]
+	if !ok {
+		return nil
+	}
+	user := a.user
+	return &user
+}
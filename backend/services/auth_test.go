@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// newTestAuthService creates an auth service with a test key
+func newTestAuthService(t *testing.T, ttl time.Duration) *AuthService {
+	t.Helper()
+	service, err := NewAuthService(AuthSettings{Secret: testSecret, Issuer: "stadia", TokenTTL: ttl})
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	return service
+}
+
+func TestAuthRegisterAndLogin(t *testing.T) {
+	service := newTestAuthService(t, time.Hour)
+
+	user, err := service.Register("Alice", "correct horse", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("Expected the username to be lowercased, got %q", user.Username)
+	}
+	if _, err := service.Register("alice", "another password", ""); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("Expected a taken username error, got %v", err)
+	}
+	for _, invalid := range [][2]string{{"al", "long enough"}, {"bob smith", "long enough"}, {"bob", "short"}} {
+		if _, err := service.Register(invalid[0], invalid[1], ""); err == nil {
+			t.Errorf("Expected %q with password %q to be rejected", invalid[0], invalid[1])
+		}
+	}
+
+	if loggedIn, err := service.Login("ALICE", "correct horse"); err != nil || loggedIn.ID != user.ID {
+		t.Errorf("Expected to log in as alice, got %v, %v", loggedIn, err)
+	}
+	if _, err := service.Login("alice", "wrong horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected invalid credentials for a wrong password, got %v", err)
+	}
+	if _, err := service.Login("nobody", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected invalid credentials for an unknown user, got %v", err)
+	}
+}
+
+func TestAuthAdmin(t *testing.T) {
+	service, err := NewAuthService(AuthSettings{Secret: testSecret, Issuer: "stadia", TokenTTL: time.Hour, Admin: "root", AdminToken: "bootstrap-token"})
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+
+	// Registering first does not make another user the admin
+	if _, err := service.Register("alice", "correct horse", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if admin := service.Admin(); admin != nil {
+		t.Errorf("Expected no admin before it registers, got %v", admin)
+	}
+	// Nobody else can take the admin's username
+	for _, token := range []string{"", "wrong-token"} {
+		if _, err := service.Register("root", "correct horse", token); !errors.Is(err, ErrAdminToken) {
+			t.Errorf("Expected the admin token to be required, got %v", err)
+		}
+	}
+	root, err := service.Register("Root", "correct horse", "bootstrap-token")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if admin := service.Admin(); admin == nil || admin.ID != root.ID {
+		t.Errorf("Expected root to be the admin, got %v", admin)
+	}
+	// Without an admin token the admin cannot register
+	noToken, _ := NewAuthService(AuthSettings{Secret: testSecret, Issuer: "stadia", TokenTTL: time.Hour, Admin: "root"})
+	if _, err := noToken.Register("root", "correct horse", ""); !errors.Is(err, ErrAdminToken) {
+		t.Errorf("Expected the admin to need a configured token, got %v", err)
+	}
+	if admin := newTestAuthService(t, time.Hour).Admin(); admin != nil {
+		t.Errorf("Expected no admin without one configured, got %v", admin)
+	}
+}
+
+func TestAuthTokens(t *testing.T) {
+	service := newTestAuthService(t, time.Hour)
+	user, _ := service.Register("alice", "correct horse", "")
+
+	token, err := service.IssueToken(user)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if authenticated, err := service.Authenticate(token.Token); err != nil || authenticated.ID != user.ID {
+		t.Fatalf("Expected the token to authenticate alice, got %v, %v", authenticated, err)
+	}
+
+	// Tokens signed with another key, expired or unsigned are rejected, and so
+	// are tokens for users that do not exist
+	other, _ := NewAuthService(AuthSettings{Secret: strings.Repeat("x", 32), Issuer: "stadia", TokenTTL: time.Hour})
+	forged, _ := other.IssueToken(user)
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    "stadia",
+		Subject:   user.ID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}).SignedString([]byte(testSecret))
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.RegisteredClaims{
+		Issuer:    "stadia",
+		Subject:   user.ID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	for name, token := range map[string]string{
+		"other key": forged.Token,
+		"expired":   expired,
+		"unsigned":  unsigned,
+		"malformed": "not-a-token",
+	} {
+		if _, err := service.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected an invalid token error, got %v", name, err)
+		}
+	}
+	if _, err := newTestAuthService(t, time.Hour).Authenticate(token.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a token for an unknown user to be rejected, got %v", err)
+	}
+}
+
+func TestAuthRejectsShortSecret(t *testing.T) {
+	if _, err := NewAuthService(AuthSettings{Secret: "short", TokenTTL: time.Hour}); err == nil {
+		t.Error("Expected a short secret to be rejected")
+	}
+}
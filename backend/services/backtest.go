@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"stadia-backend/models"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// minForecastProbability keeps the log loss finite when an event forecast at 0% happens
+	minForecastProbability = 1e-6
+
+	// calibrationBuckets is the number of equal-width probability buckets
+	calibrationBuckets = 10
+)
+
+// ErrSeasonNotFound is returned when a historical season file does not exist
+var ErrSeasonNotFound = errors.New("season not found")
+
+// BacktestService replays historical seasons week by week and scores the
+// forecasts made along the way against what actually happened
+type BacktestService struct {
+	simulationService *SimulationService
+	predictionService *PredictionService
+}
+
+// NewBacktestService creates a new backtest service
+func NewBacktestService() *BacktestService {
+	return &BacktestService{
+		simulationService: NewSimulationService(),
+		predictionService: NewPredictionService(),
+	}
+}
+
+// SetParams replaces the match model the forecasts are made with
+func (bs *BacktestService) SetParams(params models.ModelParams) {
+	bs.simulationService.SetParams(params)
+	bs.predictionService.simulationService.SetParams(params)
+}
+
+// LoadSeason reads a historical season from a JSON file
+func LoadSeason(path string) (*models.HistoricalSeason, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), ErrSeasonNotFound)
+		}
+		return nil, err
+	}
+
+	var season models.HistoricalSeason
+	if err := json.Unmarshal(data, &season); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if season.Name == "" {
+		season.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := validateSeason(&season); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	return &season, nil
+}
+
+// ListSeasons returns the names of the season files in a directory
+func ListSeasons(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// LoadSeasons reads the named seasons from a directory, or all of them if no names are given
+func LoadSeasons(dir string, names []string) ([]*models.HistoricalSeason, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = ListSeasons(dir); err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no seasons found in %s", dir)
+		}
+	}
+
+	seasons := make([]*models.HistoricalSeason, 0, len(names))
+	for _, name := range names {
+		// Names must not escape the season directory
+		if name == "" || filepath.Base(name) != name || strings.HasPrefix(name, ".") {
+			return nil, fmt.Errorf("invalid season name %q", name)
+		}
+		season, err := LoadSeason(filepath.Join(dir, name+".json"))
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, season)
+	}
+
+	return seasons, nil
+}
+
+// validateSeason checks that a historical season can be replayed
+func validateSeason(season *models.HistoricalSeason) error {
+	if len(season.Teams) < 2 {
+		return errors.New("at least 2 teams are required")
+	}
+
+	names := make(map[string]bool)
+	for _, team := range season.Teams {
+		if team.Name == "" {
+			return errors.New("team name is required")
+		}
+		if team.Power < 1 || team.Power > 100 {
+			return fmt.Errorf("team %s: power must be between 1 and 100", team.Name)
+		}
+		if names[team.Name] {
+			return fmt.Errorf("team %s appears more than once", team.Name)
+		}
+		names[team.Name] = true
+	}
+
+	if len(season.Matches) == 0 {
+		return errors.New("at least one match is required")
+	}
+	for i, match := range season.Matches {
+		if !names[match.Home] || !names[match.Away] || match.Home == match.Away {
+			return fmt.Errorf("match %d: unknown or identical teams %q and %q", i+1, match.Home, match.Away)
+		}
+		if match.Week < 1 {
+			return fmt.Errorf("match %d: week must be at least 1", i+1)
+		}
+		if match.HomeScore < 0 || match.AwayScore < 0 {
+			return fmt.Errorf("match %d: scores cannot be negative", i+1)
+		}
+	}
+
+	return nil
+}
+
+// Run replays every season with the given prediction method and scores the
+// forecasts. It stops with ctx's error once ctx is done.
+func (bs *BacktestService) Run(ctx context.Context, seasons []*models.HistoricalSeason, method models.PredictionMethod) (*models.BacktestReport, error) {
+	start := time.Now()
+	// A backtest makes a forecast for every week of every season; they are
+	// not traced one by one
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+
+	report := &models.BacktestReport{
+		Method:  method,
+		Model:   bs.simulationService.params,
+		Seasons: make([]models.SeasonBacktest, 0, len(seasons)),
+	}
+	outright, matches := newScoreAccumulator(), newScoreAccumulator()
+
+	for _, season := range seasons {
+		result, err := bs.replaySeason(ctx, season, method, outright, matches)
+		if err != nil {
+			return nil, fmt.Errorf("season %s: %w", season.Name, err)
+		}
+		report.Seasons = append(report.Seasons, *result)
+	}
+
+	report.Outright = outright.scores()
+	report.Matches = matches.scores()
+	report.OutrightCalibration = outright.calibration()
+	report.MatchCalibration = matches.calibration()
+	report.RuntimeMs = float64(time.Since(start).Microseconds()) / 1000
+
+	return report, nil
+}
+
+// replaySeason forecasts a season before every week and adds the scores to the
+// overall accumulators as well as returning the season's own scores
+func (bs *BacktestService) replaySeason(
+	ctx context.Context,
+	season *models.HistoricalSeason,
+	method models.PredictionMethod,
+	overallOutright, overallMatches *scoreAccumulator,
+) (*models.SeasonBacktest, error) {
+	teams := make([]*models.Team, 0, len(season.Teams))
+	teamsByName := make(map[string]*models.Team)
+	for _, t := range season.Teams {
+		team := models.NewTeam(t.Name, t.Power, t.Logo)
+		teams = append(teams, team)
+		teamsByName[t.Name] = team
+	}
+
+	// Build the fixture list without results; results are revealed week by week
+	totalWeeks := 0
+	for _, match := range season.Matches {
+		totalWeeks = max(totalWeeks, match.Week)
+	}
+	fixtures := make([][]*models.Match, totalWeeks)
+	results := make(map[string]models.HistoricalMatch)
+	for _, m := range season.Matches {
+		home, away := teamsByName[m.Home], teamsByName[m.Away]
+		match := models.NewMatch(home.ID, away.ID, home.Name, away.Name, m.Week)
+		fixtures[m.Week-1] = append(fixtures[m.Week-1], match)
+		results[match.ID] = m
+	}
+
+	// Final table, to score the forecasts against
+	finalTable := make([]*models.Team, len(teams))
+	finalByID := make(map[string]*models.Team)
+	for i, team := range teams {
+		teamCopy := *team
+		finalTable[i] = &teamCopy
+		finalByID[team.ID] = &teamCopy
+	}
+	for _, m := range season.Matches {
+		finalByID[teamsByName[m.Home].ID].UpdateStats(m.HomeScore, m.AwayScore)
+		finalByID[teamsByName[m.Away].ID].UpdateStats(m.AwayScore, m.HomeScore)
+	}
+	sortStandings(finalTable)
+	finalPosition := make(map[string]int)
+	for position, team := range finalTable {
+		finalPosition[team.ID] = position
+	}
+	champion := finalTable[0]
+
+	outright, matches := newScoreAccumulator(), newScoreAccumulator()
+	for week := 0; week < totalWeeks; week++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Championship forecast with the results known so far
+		positions, _, err := bs.predictionService.CalculatePositionProbabilities(ctx, method, teams, fixtures)
+		if err != nil {
+			return nil, err
+		}
+		winner := make(map[string]float64)
+		if positions != nil {
+			for teamID, probabilities := range positions {
+				winner[teamID] = probabilities[0]
+			}
+		} else {
+			result, err := bs.predictionService.Predict(ctx, method, teams, fixtures, week, totalWeeks)
+			if err != nil {
+				return nil, err
+			}
+			winner = result.Probabilities
+		}
+
+		probabilities := make([]float64, 0, len(teams))
+		observed := make([]bool, 0, len(teams))
+		for _, team := range teams {
+			probabilities = append(probabilities, winner[team.ID])
+			observed = append(observed, team.ID == champion.ID)
+		}
+		rps := -1.0
+		if positions != nil {
+			// Average ranked probability score of every team's finishing position
+			rps = 0
+			for _, team := range teams {
+				rps += rankedProbabilityScore(positions[team.ID], finalPosition[team.ID])
+			}
+			rps /= float64(len(teams))
+		}
+		outright.add(probabilities, observed, rps)
+		overallOutright.add(probabilities, observed, rps)
+
+		// Match outcome forecasts for the coming week, then reveal the results
+		for _, match := range fixtures[week] {
+			home, away := teamsByName[match.HomeTeamName], teamsByName[match.AwayTeamName]
+			homeWin, draw, awayWin := bs.simulationService.OutcomeProbabilities(home, away)
+			result := results[match.ID]
+
+			outcome := matchOutcome(result.HomeScore, result.AwayScore)
+			probabilities := []float64{homeWin, draw, awayWin}
+			observed := []bool{outcome == 0, outcome == 1, outcome == 2}
+			rps := rankedProbabilityScore(probabilities, outcome)
+			matches.add(probabilities, observed, rps)
+			overallMatches.add(probabilities, observed, rps)
+
+			match.SetResult(result.HomeScore, result.AwayScore)
+			home.UpdateStats(result.HomeScore, result.AwayScore)
+			away.UpdateStats(result.AwayScore, result.HomeScore)
+		}
+	}
+
+	return &models.SeasonBacktest{
+		Season:   season.Name,
+		Weeks:    totalWeeks,
+		Champion: champion.Name,
+		Outright: outright.scores(),
+		Matches:  matches.scores(),
+	}, nil
+}
+
+// rankedProbabilityScore scores probabilities over ordered categories against the
+// category that happened. 0 is a perfect forecast and 1 the worst possible.
+func rankedProbabilityScore(probabilities []float64, observed int) float64 {
+	if len(probabilities) < 2 {
+		return 0
+	}
+
+	score := 0.0
+	cumulative := 0.0
+	for i := 0; i < len(probabilities)-1; i++ {
+		cumulative += probabilities[i]
+		outcome := 0.0
+		if i >= observed {
+			outcome = 1.0
+		}
+		score += (cumulative - outcome) * (cumulative - outcome)
+	}
+
+	return score / float64(len(probabilities)-1)
+}
+
+// scoreAccumulator sums forecast scores and calibration counts
+type scoreAccumulator struct {
+	forecasts int
+	brier     float64
+	logLoss   float64
+	rps       float64
+	rpsCount  int
+
+	bucketCount     [calibrationBuckets]int
+	bucketPredicted [calibrationBuckets]float64
+	bucketObserved  [calibrationBuckets]int
+}
+
+// newScoreAccumulator creates an empty score accumulator
+func newScoreAccumulator() *scoreAccumulator {
+	return &scoreAccumulator{}
+}
+
+// add scores one forecast: probabilities over mutually exclusive outcomes, which
+// of them happened and the forecast's ranked probability score (negative if none)
+func (sa *scoreAccumulator) add(probabilities []float64, observed []bool, rps float64) {
+	sa.forecasts++
+
+	for i, p := range probabilities {
+		o := 0.0
+		if observed[i] {
+			o = 1.0
+			sa.logLoss -= math.Log(math.Max(p, minForecastProbability))
+		}
+		sa.brier += (p - o) * (p - o)
+
+		bucket := min(int(p*calibrationBuckets), calibrationBuckets-1)
+		sa.bucketCount[bucket]++
+		sa.bucketPredicted[bucket] += p
+		if observed[i] {
+			sa.bucketObserved[bucket]++
+		}
+	}
+
+	if rps >= 0 {
+		sa.rps += rps
+		sa.rpsCount++
+	}
+}
+
+// scores returns the average scores per forecast
+func (sa *scoreAccumulator) scores() models.ForecastScores {
+	scores := models.ForecastScores{Forecasts: sa.forecasts}
+	if sa.forecasts == 0 {
+		return scores
+	}
+
+	scores.Brier = sa.brier / float64(sa.forecasts)
+	scores.LogLoss = sa.logLoss / float64(sa.forecasts)
+	if sa.rpsCount > 0 {
+		rps := sa.rps / float64(sa.rpsCount)
+		scores.RPS = &rps
+	}
+
+	return scores
+}
+
+// calibration returns the non-empty calibration buckets
+func (sa *scoreAccumulator) calibration() []models.CalibrationBucket {
+	buckets := make([]models.CalibrationBucket, 0, calibrationBuckets)
+	for i := 0; i < calibrationBuckets; i++ {
+		if sa.bucketCount[i] == 0 {
+			continue
+		}
+		buckets = append(buckets, models.CalibrationBucket{
+			Lower:             float64(i) / calibrationBuckets,
+			Upper:             float64(i+1) / calibrationBuckets,
+			Forecasts:         sa.bucketCount[i],
+			MeanPredicted:     sa.bucketPredicted[i] / float64(sa.bucketCount[i]),
+			ObservedFrequency: float64(sa.bucketObserved[i]) / float64(sa.bucketCount[i]),
+		})
+	}
+	return buckets
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"stadia-backend/models"
+	"testing"
+)
+
+// newTestSeason creates a finished two-week season between three teams
+func newTestSeason() *models.HistoricalSeason {
+	return &models.HistoricalSeason{
+		Name: "test",
+		Teams: []models.HistoricalTeam{
+			{Name: "Team A", Power: 90},
+			{Name: "Team B", Power: 60},
+			{Name: "Team C", Power: 30},
+		},
+		Matches: []models.HistoricalMatch{
+			{Week: 1, Home: "Team A", Away: "Team B", HomeScore: 2, AwayScore: 0},
+			{Week: 2, Home: "Team B", Away: "Team C", HomeScore: 1, AwayScore: 1},
+			{Week: 3, Home: "Team C", Away: "Team A", HomeScore: 0, AwayScore: 3},
+		},
+	}
+}
+
+func TestRankedProbabilityScore(t *testing.T) {
+	if score := rankedProbabilityScore([]float64{1, 0, 0}, 0); score != 0 {
+		t.Errorf("A certain, correct forecast should score 0, got %f", score)
+	}
+	if score := rankedProbabilityScore([]float64{1, 0, 0}, 2); score != 1 {
+		t.Errorf("A certain forecast of the opposite outcome should score 1, got %f", score)
+	}
+
+	// Being one category off is better than being two off
+	near := rankedProbabilityScore([]float64{0, 1, 0}, 0)
+	far := rankedProbabilityScore([]float64{0, 0, 1}, 0)
+	if near >= far {
+		t.Errorf("Expected a nearer miss to score better: %f vs %f", near, far)
+	}
+}
+
+func TestScoreAccumulator(t *testing.T) {
+	acc := newScoreAccumulator()
+	acc.add([]float64{0.7, 0.2, 0.1}, []bool{true, false, false}, -1)
+	acc.add([]float64{0.7, 0.2, 0.1}, []bool{false, true, false}, -1)
+
+	scores := acc.scores()
+	if scores.Forecasts != 2 {
+		t.Errorf("Expected 2 forecasts, got %d", scores.Forecasts)
+	}
+	expectedBrier := ((0.09 + 0.04 + 0.01) + (0.49 + 0.64 + 0.01)) / 2
+	if math.Abs(scores.Brier-expectedBrier) > 1e-9 {
+		t.Errorf("Expected Brier %f, got %f", expectedBrier, scores.Brier)
+	}
+	expectedLogLoss := -(math.Log(0.7) + math.Log(0.2)) / 2
+	if math.Abs(scores.LogLoss-expectedLogLoss) > 1e-9 {
+		t.Errorf("Expected log loss %f, got %f", expectedLogLoss, scores.LogLoss)
+	}
+	if scores.RPS != nil {
+		t.Error("RPS should be omitted when no forecast had one")
+	}
+
+	for _, bucket := range acc.calibration() {
+		if bucket.Lower == 0.7 && (bucket.Forecasts != 2 || bucket.ObservedFrequency != 0.5) {
+			t.Errorf("Expected the 70%% bucket to hold 2 forecasts with half observed, got %+v", bucket)
+		}
+	}
+}
+
+func TestBacktestRun(t *testing.T) {
+	service := NewBacktestService()
+
+	for _, method := range []models.PredictionMethod{models.MethodAuto, models.MethodExact, models.MethodHeuristic} {
+		report, err := service.Run(context.Background(), []*models.HistoricalSeason{newTestSeason()}, method)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", method, err)
+		}
+
+		season := report.Seasons[0]
+		if season.Champion != "Team A" {
+			t.Errorf("%s: expected Team A as champion, got %s", method, season.Champion)
+		}
+		if season.Outright.Forecasts != 3 || season.Matches.Forecasts != 3 {
+			t.Errorf("%s: expected 3 outright and 3 match forecasts, got %d and %d",
+				method, season.Outright.Forecasts, season.Matches.Forecasts)
+		}
+		if report.Outright.Brier < 0 || report.Outright.Brier > 2 {
+			t.Errorf("%s: Brier score out of range: %f", method, report.Outright.Brier)
+		}
+		if (report.Outright.RPS == nil) != (method == models.MethodHeuristic) {
+			t.Errorf("%s: RPS should be reported only for position forecasts", method)
+		}
+	}
+
+	if _, err := service.Run(context.Background(), []*models.HistoricalSeason{newTestSeason()}, "unknown"); err == nil {
+		t.Error("Expected an error for an unknown method")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.Run(ctx, []*models.HistoricalSeason{newTestSeason()}, models.MethodExact); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled backtest to stop, got %v", err)
+	}
+}
+
+func TestBacktestSetParams(t *testing.T) {
+	season := []*models.HistoricalSeason{newTestSeason()}
+	realistic, err := NewBacktestService().Run(context.Background(), season, models.MethodHeuristic)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	service := NewBacktestService()
+	chaotic, _ := DefaultSimulationSettings.Preset(models.PresetChaotic)
+	service.SetParams(chaotic)
+	report, err := service.Run(context.Background(), season, models.MethodHeuristic)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Model != chaotic {
+		t.Errorf("Expected the report to name the model, got %+v", report.Model)
+	}
+	if report.Matches.Brier == realistic.Matches.Brier {
+		t.Error("Expected another model to change the match forecasts")
+	}
+}
+
+func TestLoadSeasons(t *testing.T) {
+	dir := t.TempDir()
+
+	data, _ := json.Marshal(newTestSeason())
+	if err := os.WriteFile(filepath.Join(dir, "test.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := newTestSeason()
+	invalid.Matches[0].Away = "Team Z"
+	data, _ = json.Marshal(invalid)
+	if err := os.WriteFile(filepath.Join(dir, "invalid.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	seasons, err := LoadSeasons(dir, []string{"test"})
+	if err != nil || len(seasons) != 1 {
+		t.Fatalf("Expected one season, got %d (%v)", len(seasons), err)
+	}
+
+	if _, err := LoadSeasons(dir, nil); err == nil {
+		t.Error("Expected loading all seasons to fail on the invalid one")
+	}
+	if _, err := LoadSeasons(dir, []string{"missing"}); err == nil {
+		t.Error("Expected an error for a missing season")
+	}
+	if _, err := LoadSeasons(dir, []string{"../test"}); err == nil {
+		t.Error("Expected an error for a season name outside the directory")
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"stadia-backend/models"
+)
+
+// maxClinchOutcomes bounds the number of win/draw/loss combinations the clinch
+// analysis enumerates before falling back to points bounds
+const maxClinchOutcomes = 59049 // 3^10, i.e. ten remaining matches
+
+// clinchThresholds are the position thresholds reported for each team
+var clinchThresholds = []int{1, 2, 3}
+
+// ClinchService determines which positions teams have mathematically clinched
+// or can no longer reach, based on points alone. Tiebreakers are assumed to go
+// against a team when deciding a clinch and in its favour when deciding an
+// elimination, so both flags are proofs rather than estimates.
+type ClinchService struct{}
+
+// NewClinchService creates a new clinch service
+func NewClinchService() *ClinchService {
+	return &ClinchService{}
+}
+
+// Analyze returns the outlook of every team for each position threshold
+func (cs *ClinchService) Analyze(teams []*models.Team, fixtures [][]*models.Match) map[string][]models.PositionOutlook {
+	remaining := remainingMatches(fixtures)
+
+	thresholds := make([]int, 0, len(clinchThresholds))
+	for _, threshold := range clinchThresholds {
+		// Finishing in the top N of an N-team league is not worth reporting
+		if threshold < len(teams) {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return cs.analyzeFinal(teams, thresholds)
+	}
+	if math.Pow(3, float64(len(remaining))) <= maxClinchOutcomes {
+		return cs.analyzeExhaustive(teams, remaining, thresholds)
+	}
+	return cs.analyzeBounds(teams, remaining, thresholds)
+}
+
+// analyzeFinal reports the final table, where tiebreakers have been decided
+func (cs *ClinchService) analyzeFinal(teams []*models.Team, thresholds []int) map[string][]models.PositionOutlook {
+	table := make([]*models.Team, len(teams))
+	copy(table, teams)
+	sortStandings(table)
+
+	outlooks := make(map[string][]models.PositionOutlook)
+	for position, team := range table {
+		outlooks[team.ID] = make([]models.PositionOutlook, len(thresholds))
+		for k, threshold := range thresholds {
+			status := models.QualificationEliminated
+			var needed *int
+			if position < threshold {
+				status = models.QualificationClinched
+				needed = new(int)
+			}
+			outlooks[team.ID][k] = newPositionOutlook(threshold, status, needed)
+		}
+	}
+
+	return outlooks
+}
+
+// analyzeExhaustive checks every win/draw/loss combination of the remaining matches
+func (cs *ClinchService) analyzeExhaustive(
+	teams []*models.Team,
+	remaining []*models.Match,
+	thresholds []int,
+) map[string][]models.PositionOutlook {
+	index := make(map[string]int)
+	points := make([]int, len(teams))
+	earned := make([]int, len(teams))
+	maxEarned := make([]int, len(teams))
+	for i, team := range teams {
+		index[team.ID] = i
+		points[i] = team.Points
+	}
+	home := make([]int, len(remaining))
+	away := make([]int, len(remaining))
+	for m, match := range remaining {
+		home[m] = index[match.HomeTeamID]
+		away[m] = index[match.AwayTeamID]
+		maxEarned[home[m]] += 3
+		maxEarned[away[m]] += 3
+	}
+
+	// canFinish[t][k]: some combination lets team t finish in the top k on tiebreakers
+	// failsWith[t][k][e]: some combination where t earns e points leaves it outside the top k
+	// reachable[t][e]: team t can earn exactly e points from its remaining matches
+	canFinish := make([][]bool, len(teams))
+	failsWith := make([][][]bool, len(teams))
+	reachable := make([][]bool, len(teams))
+	for t := range teams {
+		canFinish[t] = make([]bool, len(thresholds))
+		reachable[t] = make([]bool, maxEarned[t]+1)
+		failsWith[t] = make([][]bool, len(thresholds))
+		for k := range thresholds {
+			failsWith[t][k] = make([]bool, maxEarned[t]+1)
+		}
+	}
+
+	var enumerate func(m int)
+	enumerate = func(m int) {
+		if m == len(remaining) {
+			for t := range teams {
+				reachable[t][earned[t]] = true
+				ahead, level := 0, 0
+				for o := range teams {
+					if o == t {
+						continue
+					}
+					if points[o] > points[t] {
+						ahead++
+					} else if points[o] == points[t] {
+						level++
+					}
+				}
+				for k, threshold := range thresholds {
+					if ahead < threshold {
+						canFinish[t][k] = true
+					}
+					if ahead+level >= threshold {
+						failsWith[t][k][earned[t]] = true
+					}
+				}
+			}
+			return
+		}
+
+		awarded := [3][2]int{{3, 0}, {1, 1}, {0, 3}}
+		for _, pts := range awarded {
+			points[home[m]] += pts[0]
+			points[away[m]] += pts[1]
+			earned[home[m]] += pts[0]
+			earned[away[m]] += pts[1]
+			enumerate(m + 1)
+			points[home[m]] -= pts[0]
+			points[away[m]] -= pts[1]
+			earned[home[m]] -= pts[0]
+			earned[away[m]] -= pts[1]
+		}
+	}
+	enumerate(0)
+
+	outlooks := make(map[string][]models.PositionOutlook)
+	for t, team := range teams {
+		outlooks[team.ID] = make([]models.PositionOutlook, len(thresholds))
+		for k, threshold := range thresholds {
+			// The fewest points after which no combination leaves the team outside the top k
+			var needed *int
+			for e := maxEarned[t]; e >= 0 && !failsWith[t][k][e]; e-- {
+				if reachable[t][e] {
+					value := e
+					needed = &value
+				}
+			}
+
+			status := models.QualificationAlive
+			if needed != nil && *needed == 0 {
+				status = models.QualificationClinched
+			} else if !canFinish[t][k] {
+				status = models.QualificationEliminated
+				needed = nil
+			}
+			outlooks[team.ID][k] = newPositionOutlook(threshold, status, needed)
+		}
+	}
+
+	return outlooks
+}
+
+// analyzeBounds compares each team's current points with the most every other
+// team can still reach. It ignores that rivals take points off each other, so
+// it may report a team as alive when it is not, but never the other way round.
+func (cs *ClinchService) analyzeBounds(
+	teams []*models.Team,
+	remaining []*models.Match,
+	thresholds []int,
+) map[string][]models.PositionOutlook {
+	maxEarned := make(map[string]int)
+	for _, match := range remaining {
+		maxEarned[match.HomeTeamID] += 3
+		maxEarned[match.AwayTeamID] += 3
+	}
+
+	outlooks := make(map[string][]models.PositionOutlook)
+	for _, team := range teams {
+		teamMax := team.Points + maxEarned[team.ID]
+
+		// Best possible totals of the other teams, highest first
+		rivalMax := make([]int, 0, len(teams)-1)
+		alreadyAhead := 0
+		for _, other := range teams {
+			if other.ID == team.ID {
+				continue
+			}
+			rivalMax = append(rivalMax, other.Points+maxEarned[other.ID])
+			if other.Points > teamMax {
+				alreadyAhead++
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(rivalMax)))
+
+		outlooks[team.ID] = make([]models.PositionOutlook, len(thresholds))
+		for k, threshold := range thresholds {
+			// Finishing above the threshold-th best rival total guarantees the spot
+			value := max(0, rivalMax[threshold-1]+1-team.Points)
+			needed := &value
+			if value > maxEarned[team.ID] {
+				needed = nil
+			}
+
+			status := models.QualificationAlive
+			if value == 0 {
+				status = models.QualificationClinched
+			} else if alreadyAhead >= threshold {
+				status = models.QualificationEliminated
+				needed = nil
+			}
+			outlooks[team.ID][k] = newPositionOutlook(threshold, status, needed)
+		}
+	}
+
+	return outlooks
+}
+
+// newPositionOutlook builds the outlook for a threshold with its label
+func newPositionOutlook(threshold int, status models.QualificationStatus, pointsNeeded *int) models.PositionOutlook {
+	label := "winner"
+	if threshold > 1 {
+		label = fmt.Sprintf("top%d", threshold)
+	}
+	return models.PositionOutlook{
+		Threshold:    threshold,
+		Label:        label,
+		Status:       status,
+		PointsNeeded: pointsNeeded,
+	}
+}
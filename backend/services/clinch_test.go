@@ -0,0 +1,127 @@
+package services
+
+import (
+	"stadia-backend/models"
+	"testing"
+)
+
+// teamWithPoints creates a team with the given wins, draws and losses
+func teamWithPoints(name string, won, drawn, lost int) *models.Team {
+	team := models.NewTeam(name, 70, "")
+	for i := 0; i < won; i++ {
+		team.UpdateStats(1, 0)
+	}
+	for i := 0; i < drawn; i++ {
+		team.UpdateStats(1, 1)
+	}
+	for i := 0; i < lost; i++ {
+		team.UpdateStats(0, 1)
+	}
+	return team
+}
+
+func outlookFor(t *testing.T, outlooks map[string][]models.PositionOutlook, team *models.Team, threshold int) models.PositionOutlook {
+	t.Helper()
+	for _, outlook := range outlooks[team.ID] {
+		if outlook.Threshold == threshold {
+			return outlook
+		}
+	}
+	t.Fatalf("%s: no outlook for top %d", team.Name, threshold)
+	return models.PositionOutlook{}
+}
+
+func TestAnalyzeLastWeek(t *testing.T) {
+	service := NewClinchService()
+
+	teamA := teamWithPoints("Team A", 4, 0, 1) // 12 points
+	teamB := teamWithPoints("Team B", 2, 0, 3) // 6 points
+	teamC := teamWithPoints("Team C", 1, 1, 3) // 4 points
+	teamD := teamWithPoints("Team D", 0, 1, 4) // 1 point
+	teams := []*models.Team{teamA, teamB, teamC, teamD}
+
+	fixtures := [][]*models.Match{{
+		models.NewMatch(teamA.ID, teamD.ID, teamA.Name, teamD.Name, 6),
+		models.NewMatch(teamB.ID, teamC.ID, teamB.Name, teamC.Name, 6),
+	}}
+	outlooks := service.Analyze(teams, fixtures)
+
+	if outlook := outlookFor(t, outlooks, teamA, 1); outlook.Status != models.QualificationClinched {
+		t.Errorf("Team A should have clinched the group, got %s", outlook.Status)
+	}
+	if outlook := outlookFor(t, outlooks, teamB, 1); outlook.Status != models.QualificationEliminated {
+		t.Errorf("Team B cannot catch Team A, got %s", outlook.Status)
+	}
+
+	// Team B needs a draw against Team C to finish in the top two
+	outlook := outlookFor(t, outlooks, teamB, 2)
+	if outlook.Status != models.QualificationAlive {
+		t.Errorf("Team B should still be alive for the top two, got %s", outlook.Status)
+	}
+	if outlook.PointsNeeded == nil || *outlook.PointsNeeded != 1 {
+		t.Errorf("Team B should need 1 point for the top two, got %v", outlook.PointsNeeded)
+	}
+
+	// Team D can only draw level with Team C for third
+	if outlook := outlookFor(t, outlooks, teamD, 2); outlook.Status != models.QualificationEliminated {
+		t.Errorf("Team D should be eliminated from the top two, got %s", outlook.Status)
+	}
+	outlook = outlookFor(t, outlooks, teamD, 3)
+	if outlook.Status != models.QualificationAlive {
+		t.Errorf("Team D should be alive for the top three on tiebreakers, got %s", outlook.Status)
+	}
+	if outlook.PointsNeeded != nil {
+		t.Errorf("Team D cannot guarantee third with its own result, got %d", *outlook.PointsNeeded)
+	}
+}
+
+func TestAnalyzeFinishedLeague(t *testing.T) {
+	service := NewClinchService()
+	teams, fixtures := newTestLeague(6)
+	outlooks := service.Analyze(teams, fixtures)
+
+	for _, team := range teams {
+		for _, outlook := range outlooks[team.ID] {
+			if outlook.Status == models.QualificationAlive {
+				t.Errorf("%s: no team can be alive for the top %d once the league is over",
+					team.Name, outlook.Threshold)
+			}
+		}
+	}
+}
+
+func TestAnalyzeFinishedLeagueLevelOnPoints(t *testing.T) {
+	service := NewClinchService()
+
+	teamA := teamWithPoints("Team A", 1, 0, 0)
+	teamB := teamWithPoints("Team B", 1, 0, 0)
+	teamB.GoalsFor += 2 // Same points, better goal difference
+	outlooks := service.Analyze([]*models.Team{teamA, teamB}, nil)
+
+	if outlook := outlookFor(t, outlooks, teamB, 1); outlook.Status != models.QualificationClinched {
+		t.Errorf("Team B wins on goal difference, got %s", outlook.Status)
+	}
+	if outlook := outlookFor(t, outlooks, teamA, 1); outlook.Status != models.QualificationEliminated {
+		t.Errorf("Team A loses on goal difference, got %s", outlook.Status)
+	}
+}
+
+func TestAnalyzeBoundsForLongSeason(t *testing.T) {
+	service := NewClinchService()
+	teams, fixtures := newTestLeague(0)
+	outlooks := service.Analyze(teams, fixtures)
+
+	for _, team := range teams {
+		if len(outlooks[team.ID]) != 3 {
+			t.Fatalf("%s: expected 3 thresholds, got %d", team.Name, len(outlooks[team.ID]))
+		}
+
+		outlook := outlookFor(t, outlooks, team, 1)
+		if outlook.Status != models.QualificationAlive {
+			t.Errorf("%s: every team is alive before a ball is kicked, got %s", team.Name, outlook.Status)
+		}
+		if outlook.PointsNeeded != nil {
+			t.Errorf("%s: no team can guarantee the title in advance", team.Name)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"stadia-backend/models"
+	"strings"
+)
+
+// CompetitionFormat names a file format for real competitions
+type CompetitionFormat string
+
+const (
+	FormatOpenFootballJSON CompetitionFormat = "openfootball-json"
+	FormatOpenFootballTXT  CompetitionFormat = "openfootball-txt"
+	FormatFootballData     CompetitionFormat = "football-data"
+)
+
+// competitionExtensions maps file extensions to competition formats
+var competitionExtensions = map[string]CompetitionFormat{
+	".json": FormatOpenFootballJSON,
+	".txt":  FormatOpenFootballTXT,
+	".csv":  FormatFootballData,
+}
+
+// CompetitionFormatForFile guesses the format of a competition file from its
+// extension, returning an empty format when it is not recognised
+func CompetitionFormatForFile(filename string) CompetitionFormat {
+	return competitionExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+const (
+	// defaultImportedPower is given to every team when no match has been played
+	defaultImportedPower = 70
+
+	// Derived powers are spread between these bounds
+	minImportedPower = 40
+	maxImportedPower = 95
+)
+
+// competitionMatch is a match read from a competition file
+type competitionMatch struct {
+	line      int // Line or entry number in the file, for error messages
+	round     int // Zero-based round, in the order the file lists them
+	home      string
+	away      string
+	played    bool
+	homeScore int
+	awayScore int
+}
+
+// competition is a parsed competition file
+type competition struct {
+	name    string
+	matches []competitionMatch
+}
+
+// ImportCompetition creates a league from a real competition in one of the
+// supported formats: the fixture order of the file is kept and played matches
+// count towards the standings, so the rest of the season can be forecast.
+// Team powers are derived from the results played so far. name overrides the
+// competition name from the file when given.
+func (ls *LeagueService) ImportCompetition(ctx context.Context, r io.Reader, format CompetitionFormat, name string) (*models.ImportResult, error) {
+	var parsed *competition
+	var err error
+	switch format {
+	case FormatOpenFootballJSON:
+		parsed, err = parseOpenFootballJSON(r)
+	case FormatOpenFootballTXT:
+		parsed, err = parseOpenFootballTXT(r)
+	case FormatFootballData:
+		parsed, err = parseFootballData(r)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = parsed.name
+	}
+	teams, fixtures, err := buildCompetition(parsed.matches)
+	if err != nil {
+		return nil, err
+	}
+	if err := ls.InitializeLeagueWithFixtures(ctx, name, teams, fixtures); err != nil {
+		return nil, err
+	}
+
+	return &models.ImportResult{Imported: len(parsed.matches), League: ls.league}, nil
+}
+
+// buildCompetition creates the teams and week-by-week fixtures of a competition
+func buildCompetition(matches []competitionMatch) ([]*models.Team, [][]*models.Match, error) {
+	if len(matches) == 0 {
+		return nil, nil, errors.New("file has no matches")
+	}
+
+	importErr := &ImportError{}
+	teamsByName := make(map[string]*models.Team)
+	teams := make([]*models.Team, 0)
+	rounds := 0
+	for _, m := range matches {
+		if m.home == m.away {
+			importErr.add(m.line, "", "%s cannot play itself", m.home)
+			continue
+		}
+		for _, name := range []string{m.home, m.away} {
+			if teamsByName[name] == nil {
+				teamsByName[name] = models.NewTeam(name, defaultImportedPower, "")
+				teams = append(teams, teamsByName[name])
+			}
+		}
+		rounds = max(rounds, m.round+1)
+	}
+	if len(importErr.Rows) > 0 {
+		return nil, nil, importErr
+	}
+
+	fixtures := make([][]*models.Match, rounds)
+	for _, m := range matches {
+		home, away := teamsByName[m.home], teamsByName[m.away]
+		match := models.NewMatch(home.ID, away.ID, home.Name, away.Name, m.round+1)
+		if m.played {
+			match.SetResult(m.homeScore, m.awayScore)
+		}
+		fixtures[m.round] = append(fixtures[m.round], match)
+	}
+
+	// Drop rounds without matches, keeping the order of the others
+	weeks := make([][]*models.Match, 0, rounds)
+	for _, weekMatches := range fixtures {
+		if len(weekMatches) == 0 {
+			continue
+		}
+		for _, match := range weekMatches {
+			match.Week = len(weeks) + 1
+		}
+		weeks = append(weeks, weekMatches)
+	}
+
+	derivePowers(teams, weeks)
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+
+	return teams, weeks, nil
+}
+
+// derivePowers rates teams by points and goal difference per game in the played
+// matches, spread linearly between minImportedPower and maxImportedPower
+func derivePowers(teams []*models.Team, fixtures [][]*models.Match) {
+	type record struct{ games, points, goalDiff int }
+	records := make(map[string]*record)
+	for _, team := range teams {
+		records[team.ID] = &record{}
+	}
+
+	for _, weekMatches := range fixtures {
+		for _, match := range weekMatches {
+			if !match.IsPlayed() {
+				continue
+			}
+			home, away := records[match.HomeTeamID], records[match.AwayTeamID]
+			home.games++
+			away.games++
+			home.goalDiff += match.HomeScore - match.AwayScore
+			away.goalDiff += match.AwayScore - match.HomeScore
+			switch matchOutcome(match.HomeScore, match.AwayScore) {
+			case 0:
+				home.points += 3
+			case 1:
+				home.points++
+				away.points++
+			default:
+				away.points += 3
+			}
+		}
+	}
+
+	ratings := make(map[string]float64)
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, team := range teams {
+		r := records[team.ID]
+		if r.games == 0 {
+			continue
+		}
+		rating := (float64(r.points) + 0.1*float64(r.goalDiff)) / float64(r.games)
+		ratings[team.ID] = rating
+		lowest = math.Min(lowest, rating)
+		highest = math.Max(highest, rating)
+	}
+
+	for _, team := range teams {
+		rating, ok := ratings[team.ID]
+		if !ok || highest == lowest {
+			team.Power = defaultImportedPower
+			continue
+		}
+		scaled := (rating - lowest) / (highest - lowest)
+		team.Power = int(math.Round(minImportedPower + scaled*(maxImportedPower-minImportedPower)))
+	}
+}
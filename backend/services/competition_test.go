@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const openFootballTXT = `= Test League 2024/25
+
+Matchday 1
+[Sat Aug/17]
+  15.00  Northbridge FC     2-1 (1-0)  Eastfield Town
+         Westhaven AFC      0-0        Southport City   @ Harbour Park
+
+» Matchday 2
+  Fri Aug 23 2024
+  Eastfield Town     v  Westhaven AFC    1-3 (0-1)
+  Southport City     -  Northbridge FC
+
+Matchday 3
+  Northbridge FC     v  Westhaven AFC
+  Eastfield Town     vs Southport City    # rearranged
+`
+
+func TestImportOpenFootballTXT(t *testing.T) {
+	service := NewLeagueService()
+
+	result, err := service.ImportCompetition(context.Background(), strings.NewReader(openFootballTXT), FormatOpenFootballTXT, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	league := result.League
+	if league.Name != "Test League 2024/25" || len(league.Teams) != 4 || league.TotalWeeks != 3 {
+		t.Fatalf("Unexpected league %s with %d teams and %d weeks", league.Name, len(league.Teams), league.TotalWeeks)
+	}
+	if league.CurrentWeek != 1 {
+		t.Errorf("Only week 1 is complete, got current week %d", league.CurrentWeek)
+	}
+
+	played := 0
+	for _, match := range league.GetAllMatches() {
+		if match.IsPlayed() {
+			played++
+		}
+	}
+	if played != 3 {
+		t.Errorf("Expected 3 played matches, got %d", played)
+	}
+
+	second := league.GetMatchesByWeek(2)[0]
+	if second.HomeTeamName != "Eastfield Town" || second.AwayTeamName != "Westhaven AFC" || second.AwayScore != 3 {
+		t.Errorf("Unexpected week 2 match %+v", second)
+	}
+
+	// Northbridge won its only match; Eastfield lost twice
+	powers := make(map[string]int)
+	for _, team := range league.Teams {
+		powers[team.Name] = team.Power
+	}
+	if powers["Northbridge FC"] != maxImportedPower || powers["Eastfield Town"] != minImportedPower {
+		t.Errorf("Unexpected derived powers %v", powers)
+	}
+
+	// The rest of the season can be played
+	if err := service.PlayAllWeeks(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second.AwayScore != 3 {
+		t.Error("Playing the season should not replay imported results")
+	}
+}
+
+func TestImportOpenFootballJSON(t *testing.T) {
+	current := `{
+	  "name": "Test League",
+	  "matches": [
+	    {"round": "Matchday 1", "team1": "A", "team2": "B", "score": {"ft": [1, 0], "ht": [0, 0]}},
+	    {"round": "Matchday 1", "team1": "C", "team2": "D", "score": {"ft": [2, 2]}},
+	    {"round": "Matchday 2", "team1": "B", "team2": "C"},
+	    {"round": "Matchday 2", "team1": "D", "team2": "A"}
+	  ]
+	}`
+	legacy := `{
+	  "name": "Test League",
+	  "rounds": [
+	    {"name": "Round 1", "matches": [
+	      {"team1": {"key": "a", "name": "A"}, "team2": {"key": "b", "name": "B"}, "score1": 1, "score2": 0},
+	      {"team1": {"key": "c", "name": "C"}, "team2": {"key": "d", "name": "D"}, "score1": 2, "score2": 2}
+	    ]},
+	    {"name": "Round 2", "matches": [
+	      {"team1": {"key": "b", "name": "B"}, "team2": {"key": "c", "name": "C"}, "score1": null, "score2": null},
+	      {"team1": {"key": "d", "name": "D"}, "team2": {"key": "a", "name": "A"}}
+	    ]}
+	  ]
+	}`
+
+	for name, file := range map[string]string{"current": current, "legacy": legacy} {
+		service := NewLeagueService()
+		result, err := service.ImportCompetition(context.Background(), strings.NewReader(file), FormatOpenFootballJSON, "Renamed")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		league := result.League
+		if league.Name != "Renamed" || league.TotalWeeks != 2 || league.CurrentWeek != 1 {
+			t.Errorf("%s: unexpected league %s, %d weeks, current week %d", name, league.Name, league.TotalWeeks, league.CurrentWeek)
+		}
+		if league.GetMatchesByWeek(2)[0].IsPlayed() {
+			t.Errorf("%s: a match without a score should not be played", name)
+		}
+	}
+}
+
+func TestImportFootballData(t *testing.T) {
+	file := `Div,Date,HomeTeam,AwayTeam,FTHG,FTAG,FTR,B365H
+E0,17/08/2024,A,B,2,0,H,1.5
+E0,17/08/2024,C,D,1,1,D,2.1
+E0,24/08/2024,B,C,0,3,A,3.0
+E0,24/08/2024,D,A,,,,
+`
+	service := NewLeagueService()
+
+	result, err := service.ImportCompetition(context.Background(), strings.NewReader(file), FormatFootballData, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	league := result.League
+	if league.Name != "E0" || league.TotalWeeks != 2 || len(league.GetMatchesByWeek(2)) != 2 {
+		t.Fatalf("Expected rounds derived from the match order, got %d weeks", league.TotalWeeks)
+	}
+	if league.GetMatchesByWeek(2)[1].IsPlayed() {
+		t.Error("A fixture without goals should not be played")
+	}
+
+	_, err = service.ImportCompetition(context.Background(), strings.NewReader("HomeTeam,AwayTeam,FTHG,FTAG\nA,B,x,1\nA,A,1,1\n"), FormatFootballData, "")
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || len(importErr.Rows) != 1 || importErr.Rows[0].Row != 2 {
+		t.Errorf("Expected an error on row 2, got %v", err)
+	}
+	if service.GetLeague() != league {
+		t.Error("A rejected file should leave the league unchanged")
+	}
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"stadia-backend/models"
+)
+
+const (
+	// exactGoalCap is the highest number of goals per side the exact solver enumerates.
+	// Anything above it is counted as exactGoalCap goals.
+	exactGoalCap = 5
+
+	// exactBudget bounds the number of final tables the exact solver evaluates
+	exactBudget = 500000
+)
+
+// scoreline is a possible result of a match with its probability
+type scoreline struct {
+	home, away  int
+	probability float64
+}
+
+// exactSolver calculates finishing position probabilities by enumerating every
+// win/draw/loss combination of the remaining matches. When teams finish level
+// on points, the scorelines of their remaining matches are enumerated as well
+// so that goal difference and goals scored decide the order.
+type exactSolver struct {
+	teams   []*models.Team
+	index   map[string]int
+	matches []*models.Match
+	home    []int
+	away    []int
+
+	// outcomes[m] holds the home win, draw and away win probabilities of match m;
+	// scorelines[m][o] lists the scorelines of that outcome, conditional on it.
+	outcomes   [][3]float64
+	scorelines [][3][]scoreline
+
+	points    []int
+	results   []int // Outcome chosen for each remaining match
+	budget    int
+	positions [][]float64 // Team index -> position probabilities
+
+	// Checked every cancelCheckInterval tables, to stop a cancelled forecast
+	ctx context.Context
+
+	// byOutcome[m][o][t] holds the position probabilities of team t jointly with
+	// outcome o of match m. Only filled when trackOutcomes is set.
+	trackOutcomes bool
+	byOutcome     [][3][][]float64
+}
+
+// newExactSolver prepares the solver for the remaining matches of the fixtures
+func newExactSolver(ctx context.Context, simulationService *SimulationService, teams []*models.Team, fixtures [][]*models.Match) (*exactSolver, error) {
+	remaining := remainingMatches(fixtures)
+	if math.Pow(3, float64(len(remaining))) > maxExactOutcomes {
+		return nil, ErrTooManyOutcomes
+	}
+
+	solver := &exactSolver{
+		teams:      teams,
+		index:      make(map[string]int),
+		matches:    remaining,
+		home:       make([]int, len(remaining)),
+		away:       make([]int, len(remaining)),
+		outcomes:   make([][3]float64, len(remaining)),
+		scorelines: make([][3][]scoreline, len(remaining)),
+		points:     make([]int, len(teams)),
+		results:    make([]int, len(remaining)),
+		budget:     exactBudget,
+		positions:  make([][]float64, len(teams)),
+		ctx:        ctx,
+	}
+	for i, team := range teams {
+		solver.index[team.ID] = i
+		solver.points[i] = team.Points
+		solver.positions[i] = make([]float64, len(teams))
+	}
+
+	for m, match := range remaining {
+		solver.home[m] = solver.index[match.HomeTeamID]
+		solver.away[m] = solver.index[match.AwayTeamID]
+
+		dist := simulationService.ScoreDistribution(teams[solver.home[m]], teams[solver.away[m]], exactGoalCap)
+		for h := range dist {
+			for a := range dist[h] {
+				outcome := matchOutcome(h, a)
+				solver.outcomes[m][outcome] += dist[h][a]
+				solver.scorelines[m][outcome] = append(solver.scorelines[m][outcome], scoreline{h, a, dist[h][a]})
+			}
+		}
+		for outcome := range solver.scorelines[m] {
+			for i := range solver.scorelines[m][outcome] {
+				solver.scorelines[m][outcome][i].probability /= solver.outcomes[m][outcome]
+			}
+		}
+	}
+
+	return solver, nil
+}
+
+// matchOutcome returns 0 for a home win, 1 for a draw and 2 for an away win
+func matchOutcome(homeScore, awayScore int) int {
+	switch {
+	case homeScore > awayScore:
+		return 0
+	case homeScore == awayScore:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// record adds the probability of a final table to each team's position
+func (s *exactSolver) record(table []int, probability float64) {
+	for position, team := range table {
+		s.positions[team][position] += probability
+	}
+	if !s.trackOutcomes {
+		return
+	}
+	for m, outcome := range s.results {
+		for position, team := range table {
+			s.byOutcome[m][outcome][team][position] += probability
+		}
+	}
+}
+
+// solve enumerates all outcomes and returns the position probabilities of each team
+func (s *exactSolver) solve() (map[string][]float64, error) {
+	if s.trackOutcomes {
+		s.byOutcome = make([][3][][]float64, len(s.matches))
+		for m := range s.byOutcome {
+			for o := range s.byOutcome[m] {
+				s.byOutcome[m][o] = make([][]float64, len(s.teams))
+				for t := range s.teams {
+					s.byOutcome[m][o][t] = make([]float64, len(s.teams))
+				}
+			}
+		}
+	}
+
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.enumerate(0, 1.0); err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string][]float64)
+	for i, team := range s.teams {
+		positions[team.ID] = s.positions[i]
+	}
+	return positions, nil
+}
+
+// enumerate walks the win/draw/loss combinations from match m onwards
+func (s *exactSolver) enumerate(m int, probability float64) error {
+	if probability == 0 {
+		return nil
+	}
+	if m == len(s.matches) {
+		return s.resolveTable(probability)
+	}
+
+	awarded := [3][2]int{{3, 0}, {1, 1}, {0, 3}}
+	for outcome, pts := range awarded {
+		s.points[s.home[m]] += pts[0]
+		s.points[s.away[m]] += pts[1]
+		s.results[m] = outcome
+		err := s.enumerate(m+1, probability*s.outcomes[m][outcome])
+		s.points[s.home[m]] -= pts[0]
+		s.points[s.away[m]] -= pts[1]
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spend counts one evaluated table against the budget, and stops the
+// enumeration when the budget runs out or the context is done
+func (s *exactSolver) spend() error {
+	s.budget--
+	if s.budget < 0 {
+		return ErrTooManyOutcomes
+	}
+	if s.budget%cancelCheckInterval == 0 {
+		return s.ctx.Err()
+	}
+	return nil
+}
+
+// resolveTable assigns positions for one combination of outcomes. Teams level on
+// points are ordered by every scoreline their remaining matches can produce.
+func (s *exactSolver) resolveTable(probability float64) error {
+	order := make([]int, len(s.teams))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return s.points[order[i]] > s.points[order[j]]
+	})
+
+	// Group teams level on points
+	tied := make([]bool, len(s.teams))
+	groups := make([][]int, 0)
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && s.points[order[end]] == s.points[order[start]] {
+			end++
+		}
+		if end-start > 1 {
+			groups = append(groups, order[start:end])
+			for _, team := range order[start:end] {
+				tied[team] = true
+			}
+		}
+		start = end
+	}
+
+	if len(groups) == 0 {
+		if err := s.spend(); err != nil {
+			return err
+		}
+		s.record(order, probability)
+		return nil
+	}
+
+	// Only matches involving tied teams affect their order
+	relevant := make([]int, 0)
+	for m := range s.matches {
+		if tied[s.home[m]] || tied[s.away[m]] {
+			relevant = append(relevant, m)
+		}
+	}
+
+	goalDiff := make([]int, len(s.teams))
+	goalsFor := make([]int, len(s.teams))
+	for i, team := range s.teams {
+		goalDiff[i] = team.GoalDifference()
+		goalsFor[i] = team.GoalsFor
+	}
+
+	var walk func(r int, probability float64) error
+	walk = func(r int, probability float64) error {
+		if r < len(relevant) {
+			m := relevant[r]
+			for _, score := range s.scorelines[m][s.results[m]] {
+				goalDiff[s.home[m]] += score.home - score.away
+				goalDiff[s.away[m]] += score.away - score.home
+				goalsFor[s.home[m]] += score.home
+				goalsFor[s.away[m]] += score.away
+				err := walk(r+1, probability*score.probability)
+				goalDiff[s.home[m]] -= score.home - score.away
+				goalDiff[s.away[m]] -= score.away - score.home
+				goalsFor[s.home[m]] -= score.home
+				goalsFor[s.away[m]] -= score.away
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if err := s.spend(); err != nil {
+			return err
+		}
+
+		final := make([]int, len(order))
+		copy(final, order)
+		sort.SliceStable(final, func(i, j int) bool {
+			a, b := final[i], final[j]
+			if s.points[a] != s.points[b] {
+				return s.points[a] > s.points[b]
+			}
+			if goalDiff[a] != goalDiff[b] {
+				return goalDiff[a] > goalDiff[b]
+			}
+			if goalsFor[a] != goalsFor[b] {
+				return goalsFor[a] > goalsFor[b]
+			}
+			return s.teams[a].Name < s.teams[b].Name
+		})
+		s.record(final, probability)
+		return nil
+	}
+
+	return walk(0, probability)
+}
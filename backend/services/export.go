@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"stadia-backend/models"
+	"strconv"
+)
+
+var (
+	standingsColumns = []string{
+		"week", "position", "team_id", "team", "played", "won", "drawn", "lost",
+		"goals_for", "goals_against", "goal_difference", "points",
+	}
+	matchesColumns = []string{
+		"match_id", "week", "home_team_id", "home_team", "away_team_id", "away_team",
+		"home_score", "away_score", "status",
+	}
+	predictionsColumns = []string{
+		"week", "method", "team_id", "team", "probability",
+	}
+)
+
+// Export returns a dataset as a flat table. A week of nil exports the standings
+// as they are now, every match and the full prediction history; a week narrows
+// each dataset to the standings after that week, the matches of that week and
+// the predictions stored after it.
+func (ls *LeagueService) Export(dataset models.ExportDataset, week *int) (*models.ExportTable, error) {
+	if week != nil && (*week < 0 || *week > ls.league.TotalWeeks) {
+		return nil, fmt.Errorf("week must be between 0 and %d", ls.league.TotalWeeks)
+	}
+
+	switch dataset {
+	case models.ExportStandings:
+		return ls.exportStandings(week), nil
+	case models.ExportMatches:
+		return ls.exportMatches(week), nil
+	case models.ExportPredictions:
+		return ls.exportPredictions(week), nil
+	default:
+		return nil, fmt.Errorf("unknown dataset %q", dataset)
+	}
+}
+
+// exportStandings exports the standings now or after a week
+func (ls *LeagueService) exportStandings(week *int) *models.ExportTable {
+	standings := ls.GetStandings()
+	standingsWeek := ls.league.CurrentWeek
+	if week != nil && *week != ls.league.CurrentWeek {
+		standings = ls.standingsAfterWeek(*week)
+		standingsWeek = *week
+	}
+
+	table := &models.ExportTable{Columns: standingsColumns, Rows: make([][]any, 0, len(standings))}
+	for i, team := range standings {
+		table.Rows = append(table.Rows, []any{
+			standingsWeek, i + 1, team.ID, team.Name, team.Played, team.Won, team.Drawn, team.Lost,
+			team.GoalsFor, team.GoalsAgainst, team.GoalDifference(), team.Points,
+		})
+	}
+	return table
+}
+
+// standingsAfterWeek recalculates the standings from the results of the first weeks
+func (ls *LeagueService) standingsAfterWeek(week int) []*models.Team {
+	league := ls.league.Clone()
+	for _, team := range league.Teams {
+		team.ResetStats()
+	}
+	for _, match := range league.GetAllMatches() {
+		if match.IsPlayed() && match.Week <= week {
+			league.GetTeam(match.HomeTeamID).UpdateStats(match.HomeScore, match.AwayScore)
+			league.GetTeam(match.AwayTeamID).UpdateStats(match.AwayScore, match.HomeScore)
+		}
+	}
+
+	teams := league.GetTeamsList()
+	sortStandings(teams)
+	return teams
+}
+
+// exportMatches exports every match or the matches of a week
+func (ls *LeagueService) exportMatches(week *int) *models.ExportTable {
+	matches := ls.league.GetAllMatches()
+	if week != nil {
+		matches = ls.league.GetMatchesByWeek(*week)
+	}
+
+	table := &models.ExportTable{Columns: matchesColumns, Rows: make([][]any, 0, len(matches))}
+	for _, match := range matches {
+		var homeScore, awayScore any
+		if match.IsPlayed() {
+			homeScore, awayScore = match.HomeScore, match.AwayScore
+		}
+		table.Rows = append(table.Rows, []any{
+			match.ID, match.Week, match.HomeTeamID, match.HomeTeamName, match.AwayTeamID, match.AwayTeamName,
+			homeScore, awayScore, string(match.Status),
+		})
+	}
+	return table
+}
+
+// exportPredictions exports the stored predictions of every week or of one week,
+// in the same percentages and order as the predictions endpoint
+func (ls *LeagueService) exportPredictions(week *int) *models.ExportTable {
+	table := &models.ExportTable{Columns: predictionsColumns, Rows: make([][]any, 0)}
+	for _, snapshot := range ls.league.PredictionHistory {
+		if week != nil && snapshot.Week != *week {
+			continue
+		}
+		response := ls.buildPredictionResponse(snapshot.Probabilities, snapshot.Method, 0)
+		for _, prediction := range response.Predictions {
+			table.Rows = append(table.Rows, []any{
+				snapshot.Week, string(snapshot.Method), prediction.TeamID, prediction.TeamName, prediction.Probability,
+			})
+		}
+	}
+	return table
+}
+
+// WriteExport writes a table in the given format
+func WriteExport(w io.Writer, table *models.ExportTable, format models.ExportFormat) error {
+	switch format {
+	case models.FormatCSV:
+		return writeCSV(w, table)
+	case models.FormatJSONLines:
+		return writeJSONLines(w, table)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// writeCSV writes a table as CSV with a header row
+func writeCSV(w io.Writer, table *models.ExportTable) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(table.Columns))
+	for _, row := range table.Rows {
+		for i, value := range row {
+			switch v := value.(type) {
+			case nil:
+				record[i] = ""
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeJSONLines writes a table as one JSON object per row, keys in column order
+func writeJSONLines(w io.Writer, table *models.ExportTable) error {
+	buffered := bufio.NewWriter(w)
+
+	keys := make([][]byte, len(table.Columns))
+	for i, column := range table.Columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	for _, row := range table.Rows {
+		buffered.WriteByte('{')
+		for i, value := range row {
+			if i > 0 {
+				buffered.WriteByte(',')
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buffered.Write(keys[i])
+			buffered.WriteByte(':')
+			buffered.Write(encoded)
+		}
+		buffered.WriteString("}\n")
+	}
+
+	return buffered.Flush()
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"stadia-backend/models"
+	"strings"
+	"testing"
+)
+
+func TestExportStandingsAfterWeek(t *testing.T) {
+	service := newPlayedLeagueService(t, 4)
+
+	table, err := service.Export(models.ExportStandings, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, team := range service.GetStandings() {
+		if table.Rows[i][3] != team.Name || table.Rows[i][11] != team.Points {
+			t.Errorf("Row %d should match the standings: %v", i, table.Rows[i])
+		}
+	}
+
+	week := 2
+	table, err = service.Export(models.ExportStandings, &week)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, row := range table.Rows {
+		if row[0] != 2 || row[4] != 2 {
+			t.Errorf("Expected every team to have played 2 matches after week 2, got %v", row)
+		}
+	}
+	if service.GetLeague().CurrentWeek != 4 {
+		t.Error("Exporting an earlier week should not change the league")
+	}
+
+	week = 99
+	if _, err := service.Export(models.ExportStandings, &week); err == nil {
+		t.Error("Expected an error for a week beyond the season")
+	}
+	if _, err := service.Export("unknown", nil); err == nil {
+		t.Error("Expected an error for an unknown dataset")
+	}
+}
+
+func TestExportPredictionHistory(t *testing.T) {
+	service := newPlayedLeagueService(t, 5)
+
+	table, err := service.Export(models.ExportPredictions, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Predictions are stored from week 3 onwards
+	teams := len(service.GetLeague().Teams)
+	if len(table.Rows) != 3*teams {
+		t.Fatalf("Expected %d rows for weeks 3 to 5, got %d", 3*teams, len(table.Rows))
+	}
+
+	// The latest week matches the predictions endpoint
+	latest := service.GetPredictions()
+	for i, prediction := range latest.Predictions {
+		row := table.Rows[2*teams+i]
+		if row[0] != 5 || row[2] != prediction.TeamID || row[4] != prediction.Probability {
+			t.Errorf("Row %v does not match prediction %+v", row, prediction)
+		}
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	table := &models.ExportTable{
+		Columns: []string{"week", "team", "home_score", "probability"},
+		Rows:    [][]any{{1, "Team, A", nil, 12.5}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExport(&buf, table, models.FormatCSV); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Expected a header and one row, got %v (%v)", records, err)
+	}
+	if strings.Join(records[1], "|") != "1|Team, A||12.5" {
+		t.Errorf("Unexpected CSV row %v", records[1])
+	}
+
+	buf.Reset()
+	if err := WriteExport(&buf, table, models.FormatJSONLines); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	line := strings.TrimSpace(buf.String())
+	if line != `{"week":1,"team":"Team, A","home_score":null,"probability":12.5}` {
+		t.Errorf("Unexpected JSON line %s", line)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+		t.Errorf("JSON line should be valid: %v", err)
+	}
+
+	if err := WriteExport(&buf, table, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
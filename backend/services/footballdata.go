@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"strconv"
+)
+
+// parseFootballData reads a football-data.co.uk CSV file. Files list matches
+// by date without rounds, so each match is placed in the round after the
+// latest round either team already plays in. Rows without a full-time score
+// are fixtures still to be played.
+func parseFootballData(r io.Reader) (*competition, error) {
+	table, err := readCSV(r, []string{"home_team", "away_team", "fthg", "ftag"}, map[string]string{
+		"home": "hometeam",
+		"away": "awayteam",
+		"hg":   "fthg",
+		"ag":   "ftag",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &competition{}
+	importErr := &ImportError{}
+	lastRound := make(map[string]int)
+	for i, row := range table.rows {
+		line := i + 2
+		if blank(row) {
+			continue
+		}
+		if parsed.name == "" {
+			parsed.name = table.value(row, "div")
+		}
+
+		match := competitionMatch{line: line, home: table.value(row, "home_team"), away: table.value(row, "away_team")}
+		if match.home == "" || match.away == "" {
+			importErr.add(line, "", "home and away teams are required")
+			continue
+		}
+
+		homeGoals, awayGoals := table.value(row, "fthg"), table.value(row, "ftag")
+		if homeGoals != "" || awayGoals != "" {
+			homeScore, homeErr := strconv.Atoi(homeGoals)
+			awayScore, awayErr := strconv.Atoi(awayGoals)
+			if homeErr != nil || awayErr != nil || homeScore < 0 || awayScore < 0 {
+				importErr.add(line, "", "full-time goals must be non-negative whole numbers")
+				continue
+			}
+			match.played, match.homeScore, match.awayScore = true, homeScore, awayScore
+		}
+
+		homeRound, homeOK := lastRound[match.home]
+		awayRound, awayOK := lastRound[match.away]
+		switch {
+		case homeOK && awayOK:
+			match.round = max(homeRound, awayRound) + 1
+		case homeOK:
+			match.round = homeRound + 1
+		case awayOK:
+			match.round = awayRound + 1
+		}
+		lastRound[match.home], lastRound[match.away] = match.round, match.round
+
+		parsed.matches = append(parsed.matches, match)
+	}
+
+	if len(importErr.Rows) > 0 {
+		return nil, importErr
+	}
+	if len(parsed.matches) == 0 {
+		return nil, errors.New("file has no matches")
+	}
+	return parsed, nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"stadia-backend/models"
+	"sync"
+	"time"
+)
+
+// HealthCheck checks a dependency of the server, returning an error when it
+// is not usable. It should return once ctx is done.
+type HealthCheck func(ctx context.Context) error
+
+// namedCheck is a registered readiness check
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthService tracks the lifecycle of the server and runs the readiness
+// checks registered with it. The server starts in the starting phase.
+type HealthService struct {
+	mu      sync.RWMutex
+	phase   models.ServerPhase
+	checks  []namedCheck
+	timeout time.Duration // Longest a check may take
+}
+
+// NewHealthService creates a health service whose checks fail once they take
+// longer than timeout
+func NewHealthService(timeout time.Duration) *HealthService {
+	return &HealthService{phase: models.PhaseStarting, timeout: timeout}
+}
+
+// Register adds a readiness check. Checks are reported in the order they
+// were registered.
+func (s *HealthService) Register(name string, check HealthCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks = append(s.checks, namedCheck{name, check})
+}
+
+// SetPhase records a lifecycle change
+func (s *HealthService) SetPhase(phase models.ServerPhase) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phase = phase
+}
+
+// Phase returns the lifecycle stage of the server
+func (s *HealthService) Phase() models.ServerPhase {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.phase
+}
+
+// Readiness runs every check at the same time and reports whether the server
+// can take traffic: it must be in the ready phase and pass every check
+func (s *HealthService) Readiness(ctx context.Context) *models.ReadinessReport {
+	s.mu.RLock()
+	phase, checks := s.phase, s.checks
+	s.mu.RUnlock()
+
+	report := &models.ReadinessReport{
+		Phase:  phase,
+		Checks: make([]models.CheckResult, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = s.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report.Ready = phase == models.PhaseReady
+	for _, result := range report.Checks {
+		if result.Status != models.CheckOK {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+// run runs a check with the check timeout. A check that outlives the timeout
+// fails, even if it ignores its context.
+func (s *HealthService) run(ctx context.Context, c namedCheck) models.CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check failed unexpectedly: %v", r)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", s.timeout)
+	}
+
+	result := models.CheckResult{
+		Name:      c.name,
+		Status:    models.CheckOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = models.CheckFailed
+		result.Error = err.Error()
+	}
+	return result
+}
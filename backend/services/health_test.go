@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"stadia-backend/models"
+	"testing"
+	"time"
+)
+
+func TestHealthReadiness(t *testing.T) {
+	health := NewHealthService(50 * time.Millisecond)
+	healthy := true
+	health.Register("config", func(ctx context.Context) error { return nil })
+	health.Register("storage", func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("disk gone")
+		}
+		return nil
+	})
+
+	// Not ready while starting, even with every check passing
+	report := health.Readiness(context.Background())
+	if report.Ready || report.Phase != models.PhaseStarting || len(report.Checks) != 2 {
+		t.Fatalf("Expected a starting server not to be ready, got %+v", report)
+	}
+
+	health.SetPhase(models.PhaseReady)
+	if report := health.Readiness(context.Background()); !report.Ready {
+		t.Errorf("Expected the server to be ready, got %+v", report)
+	}
+
+	healthy = false
+	report = health.Readiness(context.Background())
+	if report.Ready {
+		t.Error("Expected a failing check to make the server unready")
+	}
+	if check := report.Checks[1]; check.Name != "storage" || check.Status != models.CheckFailed || check.Error != "disk gone" {
+		t.Errorf("Unexpected storage check %+v", check)
+	}
+	if check := report.Checks[0]; check.Name != "config" || check.Status != models.CheckOK {
+		t.Errorf("Unexpected config check %+v", check)
+	}
+
+	healthy = true
+	health.SetPhase(models.PhaseDraining)
+	if report := health.Readiness(context.Background()); report.Ready || report.Phase != models.PhaseDraining {
+		t.Errorf("Expected a draining server not to be ready, got %+v", report)
+	}
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	health := NewHealthService(20 * time.Millisecond)
+	health.SetPhase(models.PhaseReady)
+	health.Register("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second) // Ignores its context
+		return nil
+	})
+	health.Register("panics", func(ctx context.Context) error { panic("boom") })
+
+	start := time.Now()
+	report := health.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected a stuck check to be given up on, took %s", elapsed)
+	}
+	if report.Ready {
+		t.Error("Expected the server to be unready")
+	}
+	for _, check := range report.Checks {
+		if check.Status != models.CheckFailed {
+			t.Errorf("Expected check %s to fail, got %+v", check.Name, check)
+		}
+	}
+}
+
+func TestPingStateFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := PingStateFile(filepath.Join(dir, "state.json")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the ping to leave nothing behind, found %d files", len(entries))
+	}
+	if err := PingStateFile(filepath.Join(dir, "missing", "state.json")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
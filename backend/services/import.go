@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"stadia-backend/models"
+	"strconv"
+	"strings"
+)
+
+// ImportError is returned when an imported file has invalid rows. Nothing is
+// applied when it is returned.
+type ImportError struct {
+	Rows []models.ImportRowError
+}
+
+// Error implements the error interface
+func (e *ImportError) Error() string {
+	if len(e.Rows) == 1 {
+		return "1 invalid row"
+	}
+	return fmt.Sprintf("%d invalid rows", len(e.Rows))
+}
+
+// add records an error for a row
+func (e *ImportError) add(row int, column, format string, args ...any) {
+	e.Rows = append(e.Rows, models.ImportRowError{Row: row, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+// csvTable is a parsed CSV file with its columns looked up by name
+type csvTable struct {
+	columns map[string]int
+	rows    [][]string
+}
+
+// normalizeColumn makes column names case and separator insensitive
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(name)
+}
+
+// readCSV parses a CSV file with a header row and checks the required columns.
+// aliases maps accepted alternative column names to their canonical name.
+func readCSV(r io.Reader, required []string, aliases map[string]string) (*csvTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			importErr := &ImportError{}
+			importErr.add(parseErr.Line, "", "%v", parseErr.Err)
+			return nil, importErr
+		}
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	table := &csvTable{columns: make(map[string]int), rows: records[1:]}
+	for i, name := range records[0] {
+		name = normalizeColumn(strings.TrimPrefix(name, "\ufeff"))
+		if canonical, ok := aliases[name]; ok {
+			name = canonical
+		}
+		table.columns[name] = i
+	}
+
+	missing := make([]string, 0)
+	for _, name := range required {
+		if _, ok := table.columns[normalizeColumn(name)]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing columns: %s", strings.Join(missing, ", "))
+	}
+
+	return table, nil
+}
+
+// value returns a column of a row, or "" if the row is too short or the column absent
+func (t *csvTable) value(row []string, column string) string {
+	i, ok := t.columns[normalizeColumn(column)]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// blank reports whether every field of a row is empty
+func blank(row []string) bool {
+	for _, field := range row {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportTeamsCSV initializes the league from a CSV file with the columns name,
+// power and optionally logo and country. Every row is validated first and the
+// league is left unchanged if any row is invalid.
+func (ls *LeagueService) ImportTeamsCSV(r io.Reader) (*models.ImportResult, error) {
+	table, err := readCSV(r, []string{"name", "power"}, map[string]string{"team": "name", "teamname": "name"})
+	if err != nil {
+		return nil, err
+	}
+
+	importErr := &ImportError{}
+	teams := make([]*models.Team, 0, len(table.rows))
+	names := make(map[string]int)
+	for i, row := range table.rows {
+		line := i + 2
+		if blank(row) {
+			continue
+		}
+
+		name := table.value(row, "name")
+		if name == "" {
+			importErr.add(line, "name", "name is required")
+		} else if first, ok := names[strings.ToLower(name)]; ok {
+			importErr.add(line, "name", "team %s already appears on row %d", name, first)
+		} else {
+			names[strings.ToLower(name)] = line
+		}
+
+		power, err := strconv.Atoi(table.value(row, "power"))
+		if err != nil || power < 1 || power > 100 {
+			importErr.add(line, "power", "power must be a whole number between 1 and 100")
+		}
+
+		team := models.NewTeam(name, power, table.value(row, "logo"))
+		team.Country = table.value(row, "country")
+		teams = append(teams, team)
+	}
+
+	if len(importErr.Rows) > 0 {
+		return nil, importErr
+	}
+	if err := ls.InitializeLeague(teams); err != nil {
+		return nil, err
+	}
+
+	return &models.ImportResult{Imported: len(teams), League: ls.league}, nil
+}
+
+// pendingResult is a validated result waiting to be applied
+type pendingResult struct {
+	match                *models.Match
+	homeScore, awayScore int
+}
+
+// ImportResultsCSV fills in scores from a CSV file with the columns week,
+// home_team, away_team, home_score and away_score. Rows are matched to fixtures
+// by week and team names. Every row is validated first and no result is applied
+// if any row is invalid.
+func (ls *LeagueService) ImportResultsCSV(ctx context.Context, r io.Reader) (*models.ImportResult, error) {
+	if len(ls.league.Teams) < 2 {
+		return nil, errors.New("league not initialized")
+	}
+
+	table, err := readCSV(r, []string{"week", "home_team", "away_team", "home_score", "away_score"}, map[string]string{
+		"home":      "hometeam",
+		"away":      "awayteam",
+		"homegoals": "homescore",
+		"awaygoals": "awayscore",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	importErr := &ImportError{}
+	results := make([]pendingResult, 0, len(table.rows))
+	seen := make(map[string]int)
+	for i, row := range table.rows {
+		line := i + 2
+		if blank(row) {
+			continue
+		}
+
+		week, err := strconv.Atoi(table.value(row, "week"))
+		if err != nil || week < 1 || week > ls.league.TotalWeeks {
+			importErr.add(line, "week", "week must be a whole number between 1 and %d", ls.league.TotalWeeks)
+			continue
+		}
+
+		home, away := table.value(row, "home_team"), table.value(row, "away_team")
+		var match *models.Match
+		for _, m := range ls.league.GetMatchesByWeek(week) {
+			if strings.EqualFold(m.HomeTeamName, home) && strings.EqualFold(m.AwayTeamName, away) {
+				match = m
+				break
+			}
+		}
+		if match == nil {
+			importErr.add(line, "", "no fixture %s v %s in week %d", home, away, week)
+			continue
+		}
+		if first, ok := seen[match.ID]; ok {
+			importErr.add(line, "", "fixture %s v %s already appears on row %d", home, away, first)
+			continue
+		}
+		seen[match.ID] = line
+
+		homeScore, homeErr := strconv.Atoi(table.value(row, "home_score"))
+		if homeErr != nil || homeScore < 0 {
+			importErr.add(line, "home_score", "score must be a non-negative whole number")
+		}
+		awayScore, awayErr := strconv.Atoi(table.value(row, "away_score"))
+		if awayErr != nil || awayScore < 0 {
+			importErr.add(line, "away_score", "score must be a non-negative whole number")
+		}
+		if homeErr != nil || awayErr != nil || homeScore < 0 || awayScore < 0 {
+			continue
+		}
+
+		results = append(results, pendingResult{match, homeScore, awayScore})
+	}
+
+	if len(importErr.Rows) > 0 {
+		return nil, importErr
+	}
+	if len(results) == 0 {
+		return nil, errors.New("file has no results")
+	}
+
+	for _, result := range results {
+		ls.applyResult(result.match, result.homeScore, result.awayScore)
+	}
+
+	// Weeks completed by the import count as played
+	ls.advanceCompletedWeeks()
+
+	if ls.predictionsDue() {
+		ls.updatePredictions(ctx)
+	}
+
+	return &models.ImportResult{Imported: len(results), League: ls.league}, nil
+}
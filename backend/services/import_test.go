@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const teamsCSV = `name,power,logo,country
+Manchester City,90,,England
+Bayern Munich,88,,Germany
+Real Madrid,92,,Spain
+PSG,85,,France
+`
+
+func TestImportTeamsCSV(t *testing.T) {
+	service := NewLeagueService()
+
+	result, err := service.ImportTeamsCSV(strings.NewReader(teamsCSV))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Imported != 4 || len(service.GetLeague().Teams) != 4 {
+		t.Fatalf("Expected 4 teams, got %d", len(service.GetLeague().Teams))
+	}
+	for _, team := range service.GetLeague().Teams {
+		if team.Name == "Bayern Munich" && (team.Power != 88 || team.Country != "Germany") {
+			t.Errorf("Unexpected team %+v", team)
+		}
+	}
+}
+
+func TestImportTeamsCSVRowErrors(t *testing.T) {
+	service := NewLeagueService()
+	service.ImportTeamsCSV(strings.NewReader(teamsCSV))
+	before := service.GetLeague()
+
+	_, err := service.ImportTeamsCSV(strings.NewReader("Name,Power\nAjax,70\n,50\najax,101\n"))
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("Expected an ImportError, got %v", err)
+	}
+
+	// Row 3 has no name; row 4 repeats Ajax and has an invalid power
+	if len(importErr.Rows) != 3 {
+		t.Fatalf("Expected 3 row errors, got %+v", importErr.Rows)
+	}
+	if importErr.Rows[0].Row != 3 || importErr.Rows[1].Row != 4 || importErr.Rows[2].Column != "power" {
+		t.Errorf("Unexpected row errors %+v", importErr.Rows)
+	}
+	if service.GetLeague() != before || len(before.Teams) != 4 {
+		t.Error("A rejected file should leave the league unchanged")
+	}
+
+	if _, err := service.ImportTeamsCSV(strings.NewReader("name,logo\nAjax,\n")); err == nil || !strings.Contains(err.Error(), "power") {
+		t.Errorf("Expected a missing column error, got %v", err)
+	}
+}
+
+func TestImportResultsCSV(t *testing.T) {
+	service := NewLeagueService()
+	service.ImportTeamsCSV(strings.NewReader(teamsCSV))
+	league := service.GetLeague()
+
+	var rows strings.Builder
+	rows.WriteString("week,home_team,away_team,home_score,away_score\n")
+	for _, match := range league.GetMatchesByWeek(1) {
+		fmt.Fprintf(&rows, "1,%s,%s,2,1\n", match.HomeTeamName, match.AwayTeamName)
+	}
+	second := league.GetMatchesByWeek(2)[0]
+	fmt.Fprintf(&rows, "2,%s,%s,0,0\n", strings.ToUpper(second.HomeTeamName), second.AwayTeamName)
+
+	result, err := service.ImportResultsCSV(context.Background(), strings.NewReader(rows.String()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Imported != 3 {
+		t.Errorf("Expected 3 results, got %d", result.Imported)
+	}
+	if league.CurrentWeek != 1 {
+		t.Errorf("Only week 1 is complete, got current week %d", league.CurrentWeek)
+	}
+	if !second.IsPlayed() || second.HomeScore != 0 {
+		t.Error("Expected the week 2 result to be applied")
+	}
+
+	points := 0
+	for _, team := range league.Teams {
+		points += team.Points
+	}
+	if points != 8 {
+		t.Errorf("Expected 8 points awarded for two wins and a draw, got %d", points)
+	}
+}
+
+func TestImportResultsCSVRowErrors(t *testing.T) {
+	service := NewLeagueService()
+	service.ImportTeamsCSV(strings.NewReader(teamsCSV))
+	match := service.GetLeague().GetMatchesByWeek(1)[0]
+
+	file := fmt.Sprintf("week,home,away,home_score,away_score\n"+
+		"1,%[1]s,%[2]s,1,0\n"+
+		"1,%[2]s,%[1]s,1,0\n"+
+		"9,%[1]s,%[2]s,1,0\n"+
+		"1,%[1]s,%[2]s,-1,x\n", match.HomeTeamName, match.AwayTeamName)
+
+	_, err := service.ImportResultsCSV(context.Background(), strings.NewReader(file))
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("Expected an ImportError, got %v", err)
+	}
+	if len(importErr.Rows) != 3 {
+		t.Fatalf("Expected errors on rows 3, 4 and 5, got %+v", importErr.Rows)
+	}
+	if match.IsPlayed() {
+		t.Error("A rejected file should not apply any result")
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Kinds of Monte Carlo run reported to the instrumentation
+const (
+	MonteCarloPredictions = "predictions" // Championship probabilities
+	MonteCarloPositions   = "positions"   // Position forecasts
+	MonteCarloSeasons     = "seasons"     // Batch season simulations
+)
+
+// Instrumentation receives measurements from the services, for example to
+// export them as metrics. Its methods may be called from many goroutines.
+type Instrumentation interface {
+	// MonteCarloRun is called after a Monte Carlo run of the given kind
+	// completes its simulations
+	MonteCarloRun(kind string, simulations int, duration time.Duration)
+
+	// MatchesSimulated is called after a league simulates matches it plays
+	MatchesSimulated(n int)
+}
+
+// noInstrumentation discards every measurement
+type noInstrumentation struct{}
+
+func (noInstrumentation) MonteCarloRun(string, int, time.Duration) {}
+func (noInstrumentation) MatchesSimulated(int)                     {}
+
+// instrumentation receives the measurements of every service
+var instrumentation Instrumentation = noInstrumentation{}
+
+// SetInstrumentation sets where the services report measurements, or discards
+// them when nil. Call it once at startup, before the services are used.
+func SetInstrumentation(i Instrumentation) {
+	if i == nil {
+		i = noInstrumentation{}
+	}
+	instrumentation = i
+}
+
+// tracerName names the tracer of the services. Spans are recorded once a
+// tracer provider is set with otel.SetTracerProvider.
+const tracerName = "stadia-backend/services"
+
+// startSpan starts a span for a service operation, as a child of the span in
+// ctx. Without a span in ctx nothing is traced, so that calls made outside
+// a traced request or job, such as those of the command line tool, do not
+// each start a trace of their own.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends a span, marking it as failed when err is not nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
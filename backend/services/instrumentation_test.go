@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"stadia-backend/models"
+	"stadia-backend/tracing"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordingInstrumentation keeps the measurements it receives
+type recordingInstrumentation struct {
+	mu          sync.Mutex
+	runs        map[string]int // Runs by kind
+	simulations map[string]int // Simulations by kind
+	matches     int
+}
+
+func (r *recordingInstrumentation) MonteCarloRun(kind string, simulations int, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[kind]++
+	r.simulations[kind] += simulations
+}
+
+func (r *recordingInstrumentation) MatchesSimulated(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matches += n
+}
+
+// recordInstrumentation makes the services report to a recorder until the test ends
+func recordInstrumentation(t *testing.T) *recordingInstrumentation {
+	t.Helper()
+	recorder := &recordingInstrumentation{runs: make(map[string]int), simulations: make(map[string]int)}
+	SetInstrumentation(recorder)
+	t.Cleanup(func() { SetInstrumentation(nil) })
+	return recorder
+}
+
+func TestInstrumentationMatchesSimulated(t *testing.T) {
+	service := newPlayedLeagueService(t, 1)
+	recorder := recordInstrumentation(t)
+
+	if err := service.PlayNextWeek(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if recorder.matches != 2 {
+		t.Errorf("Expected 2 simulated matches, got %d", recorder.matches)
+	}
+
+	// A match entered by hand is not simulated again
+	week := service.GetLeague().GetMatchesByWeek(3)
+	if err := service.UpdateMatchResult(context.Background(), week[0].ID, 1, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.PlayNextWeek(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if recorder.matches != 3 {
+		t.Errorf("Expected 3 simulated matches, got %d", recorder.matches)
+	}
+}
+
+func TestInstrumentationMonteCarloRuns(t *testing.T) {
+	teams, fixtures := newTestLeague(2)
+	recorder := recordInstrumentation(t)
+
+	predictions := NewPredictionService()
+	if _, err := predictions.Predict(context.Background(), models.MethodMonteCarlo, teams, fixtures, 2, 6); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := predictions.Predict(context.Background(), models.MethodHeuristic, teams, fixtures, 2, 6); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if recorder.runs[MonteCarloPredictions] != 1 || recorder.simulations[MonteCarloPredictions] != 10000 {
+		t.Errorf("Expected one prediction run of 10000 simulations, got %d runs of %d simulations",
+			recorder.runs[MonteCarloPredictions], recorder.simulations[MonteCarloPredictions])
+	}
+
+	service := newPlayedLeagueService(t, 2)
+	if _, err := service.SimulateSeasons(models.SeasonSimulationOptions{Seasons: 300}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if recorder.runs[MonteCarloSeasons] != 1 || recorder.simulations[MonteCarloSeasons] != 300 {
+		t.Errorf("Expected one season run of 300 simulations, got %d runs of %d simulations",
+			recorder.runs[MonteCarloSeasons], recorder.simulations[MonteCarloSeasons])
+	}
+}
+
+// recordSpans makes the services export every span to memory until the test ends
+func recordSpans(t *testing.T) (*tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), tracing.Settings{SampleRatio: 1})
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter, provider
+}
+
+func TestTracingSpans(t *testing.T) {
+	service := newPlayedLeagueService(t, 2)
+	service.league.Settings.PredictionMethod = models.MethodMonteCarlo
+	exporter, provider := recordSpans(t)
+
+	// Untraced calls start no trace of their own
+	if err := service.PlayNextWeek(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("Expected no spans outside a trace, got %d", len(spans))
+	}
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "request")
+	if err := service.PlayNextWeek(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	request.End()
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	parents := map[string]string{
+		"LeagueService.PlayNextWeek":             "request",
+		"LeagueService.updatePredictions":        "LeagueService.PlayNextWeek",
+		"PredictionService.Predict":              "LeagueService.updatePredictions",
+		"PredictionService.CalculatePredictions": "PredictionService.Predict",
+	}
+	for name, parent := range parents {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("Expected a %s span", name)
+			continue
+		}
+		if span.Parent.SpanID() != spans[parent].SpanContext.SpanID() {
+			t.Errorf("Expected %s to be a child of %s", name, parent)
+		}
+	}
+
+	// A failed operation marks its span as failed
+	exporter.Reset()
+	ctx, request = provider.Tracer("test").Start(context.Background(), "request")
+	if err := service.UpdateMatchResult(ctx, "missing", 1, 0); err == nil {
+		t.Fatal("Expected an error for an unknown match")
+	}
+	request.End()
+	failed := exporter.GetSpans()[0]
+	if failed.Name != "LeagueService.UpdateMatchResult" || failed.Status.Code != codes.Error || len(failed.Events) == 0 {
+		t.Errorf("Expected a failed UpdateMatchResult span with the error recorded, got %s with status %v", failed.Name, failed.Status)
+	}
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"stadia-backend/logging"
+	"stadia-backend/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrJobNotFound is returned for an unknown or expired job ID
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobQueueFull is returned when every worker is busy and the queue is full
+	ErrJobQueueFull = errors.New("job queue is full")
+
+	// ErrJobServiceClosed is returned for jobs submitted after shutdown started
+	ErrJobServiceClosed = errors.New("job service is shutting down")
+)
+
+// JobFunc is the work of a job. It should return early once ctx is done, as
+// its worker waits for it, and may report its progress as a percentage.
+type JobFunc func(ctx context.Context, progress func(percent float64)) (any, error)
+
+// JobSettings configures the job service
+type JobSettings struct {
+	Workers   int           // Jobs run at the same time
+	QueueSize int           // Jobs waiting for a worker before submissions are refused
+	Timeout   time.Duration // Default and longest run time of a job
+	Retention time.Duration // How long finished jobs and their results are kept
+}
+
+// job is a job with its work and subscribers
+type job struct {
+	models.Job
+	run         JobFunc
+	timeout     time.Duration
+	ctx         context.Context
+	cancel      context.CancelFunc
+	subscribers map[chan models.Job]struct{}
+}
+
+// JobService runs long work on a bounded pool of workers. Jobs can be polled,
+// followed through subscriptions and cancelled; finished jobs are kept for the
+// retention period.
+type JobService struct {
+	settings JobSettings
+	mu       sync.Mutex
+	jobs     map[string]*job
+	queue    chan *job
+	closed   bool
+	workers  sync.WaitGroup
+
+	// Cancels every job when a shutdown runs out of time
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewJobService creates a job service and starts its workers
+func NewJobService(settings JobSettings) *JobService {
+	settings.Workers = max(settings.Workers, 1)
+	settings.QueueSize = max(settings.QueueSize, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &JobService{
+		settings: settings,
+		jobs:     make(map[string]*job),
+		queue:    make(chan *job, settings.QueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for i := 0; i < settings.Workers; i++ {
+		s.workers.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+// Submit queues work done for a league. A timeout of 0, or one longer than the
+// configured timeout, uses the configured timeout.
+func (s *JobService) Submit(kind models.JobKind, leagueID string, timeout time.Duration, run JobFunc) (*models.Job, error) {
+	if timeout <= 0 || (s.settings.Timeout > 0 && timeout > s.settings.Timeout) {
+		timeout = s.settings.Timeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrJobServiceClosed
+	}
+	s.prune()
+
+	id := uuid.New().String()
+	ctx, cancel := context.WithCancel(logging.With(s.ctx, "job_id", id, "job_kind", kind, "league_id", leagueID))
+	j := &job{
+		Job: models.Job{
+			ID:             id,
+			Kind:           kind,
+			LeagueID:       leagueID,
+			Status:         models.JobQueued,
+			TimeoutSeconds: timeout.Seconds(),
+			CreatedAt:      time.Now().UTC(),
+		},
+		run:         run,
+		timeout:     timeout,
+		ctx:         ctx,
+		cancel:      cancel,
+		subscribers: make(map[chan models.Job]struct{}),
+	}
+
+	select {
+	case s.queue <- j:
+	default:
+		cancel()
+		return nil, ErrJobQueueFull
+	}
+	s.jobs[j.ID] = j
+
+	snapshot := j.Job
+	return &snapshot, nil
+}
+
+// work runs queued jobs until the queue is closed
+func (s *JobService) work() {
+	defer s.workers.Done()
+	for j := range s.queue {
+		s.execute(j)
+	}
+}
+
+// execute runs a job with its timeout. The job is finished as soon as its
+// context is done, but the worker only takes the next job once the work has
+// returned, so cancelled work cannot pile up beyond the worker count.
+func (s *JobService) execute(j *job) {
+	s.mu.Lock()
+	if j.Status != models.JobQueued {
+		// Cancelled while queued
+		s.mu.Unlock()
+		return
+	}
+	startedAt := time.Now().UTC()
+	j.Status = models.JobRunning
+	j.StartedAt = &startedAt
+	s.notify(j)
+	s.mu.Unlock()
+
+	// Every job is a trace of its own, as it outlives the request that submitted it
+	ctx, span := otel.Tracer(tracerName).Start(j.ctx, "Job "+string(j.Kind), trace.WithAttributes(
+		attribute.String("job.id", j.ID),
+		attribute.String("league.id", j.LeagueID),
+	))
+	defer span.End()
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("job failed unexpectedly: %v", r)}
+			}
+		}()
+		result, err := j.run(ctx, func(percent float64) { s.setProgress(j, percent) })
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		s.finish(j, o.result, o.err)
+		if o.err != nil {
+			span.RecordError(o.err)
+			span.SetStatus(codes.Error, o.err.Error())
+		}
+	case <-ctx.Done():
+		s.finish(j, nil, ctx.Err())
+		span.SetStatus(codes.Error, ctx.Err().Error())
+		<-done
+	}
+}
+
+// setProgress records a job's progress
+func (s *JobService) setProgress(j *job, percent float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.Status != models.JobRunning {
+		return
+	}
+	j.Progress = min(max(percent, 0), 100)
+	s.notify(j)
+}
+
+// finish records the outcome of a job and closes its subscriptions
+func (s *JobService) finish(j *job, result any, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.IsFinished() {
+		return
+	}
+
+	finishedAt := time.Now().UTC()
+	expiresAt := finishedAt.Add(s.settings.Retention)
+	j.FinishedAt = &finishedAt
+	j.ExpiresAt = &expiresAt
+	switch {
+	case err == nil:
+		j.Status = models.JobCompleted
+		j.Progress = 100
+		j.Result = result
+	case errors.Is(err, context.DeadlineExceeded):
+		j.Status = models.JobFailed
+		j.Error = fmt.Sprintf("timed out after %s", j.timeout)
+	case errors.Is(err, context.Canceled):
+		j.Status = models.JobCancelled
+		j.Error = "cancelled"
+	default:
+		j.Status = models.JobFailed
+		j.Error = err.Error()
+	}
+	j.cancel()
+
+	// The job context carries its ID, which the submitting request logs as well
+	attrs := []any{"status", j.Status, "duration_ms", float64(finishedAt.Sub(j.CreatedAt).Microseconds()) / 1000}
+	level := slog.LevelInfo
+	if j.Status == models.JobFailed {
+		level = slog.LevelWarn
+		attrs = append(attrs, "error", j.Error)
+	}
+	slog.Log(j.ctx, level, "Job finished", attrs...)
+
+	s.notify(j)
+	for ch := range j.subscribers {
+		close(ch)
+	}
+	j.subscribers = nil
+}
+
+// notify sends a job's state to its subscribers. Subscribers that have not
+// read the previous state only get the latest one. The caller must hold the lock.
+func (s *JobService) notify(j *job) {
+	for ch := range j.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- j.Job
+	}
+}
+
+// prune drops finished jobs whose retention has passed. The caller must hold the lock.
+func (s *JobService) prune() {
+	now := time.Now()
+	for id, j := range s.jobs {
+		if j.ExpiresAt != nil && now.After(*j.ExpiresAt) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// lookup returns a stored job. The caller must hold the lock.
+func (s *JobService) lookup(id string) (*job, error) {
+	s.prune()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return j, nil
+}
+
+// Get returns a copy of a job with its current progress and, once completed, its result
+func (s *JobService) Get(id string) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := j.Job
+	return &snapshot, nil
+}
+
+// List returns the stored jobs of a kind, or of every kind when kind is empty,
+// newest first and without their results
+func (s *JobService) List(kind models.JobKind) []*models.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+
+	jobs := make([]*models.Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		if kind != "" && j.Kind != kind {
+			continue
+		}
+		snapshot := j.Job
+		snapshot.Result = nil
+		jobs = append(jobs, &snapshot)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Cancel stops a queued or running job. Cancelling a finished job has no effect.
+func (s *JobService) Cancel(id string) (*models.Job, error) {
+	s.mu.Lock()
+	j, err := s.lookup(id)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// A queued job is skipped by the workers once finished; the work of a
+	// running job is stopped through its context
+	s.finish(j, nil, context.Canceled)
+
+	return s.Get(id)
+}
+
+// Subscribe follows a job. The channel receives the job's state whenever it
+// changes and is closed once the job has finished; it starts with the current
+// state. Call the returned function to stop following early.
+func (s *JobService) Subscribe(id string) (<-chan models.Job, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.lookup(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan models.Job, 1)
+	ch <- j.Job
+	if j.IsFinished() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+
+	j.subscribers[ch] = struct{}{}
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := j.subscribers[ch]; ok {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// Pending returns the number of queued and running jobs
+func (s *JobService) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := 0
+	for _, j := range s.jobs {
+		if !j.IsFinished() {
+			pending++
+		}
+	}
+	return pending
+}
+
+// CheckBacklog fails once the job queue is full, as new jobs would be
+// refused, or once shutdown has started
+func (s *JobService) CheckBacklog(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrJobServiceClosed
+	}
+	if queued := len(s.queue); cap(s.queue) > 0 && queued >= cap(s.queue) {
+		return fmt.Errorf("%w: %d jobs waiting for a worker", ErrJobQueueFull, queued)
+	}
+	return nil
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones to
+// finish. Once ctx is done, the remaining jobs are cancelled.
+func (s *JobService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-drained
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stadia-backend/models"
+	"testing"
+	"time"
+)
+
+// waitForJob polls a job until it has finished
+func waitForJob(t *testing.T, jobs *JobService, id string) *models.Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := jobs.Get(id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if job.IsFinished() {
+			return job
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return nil
+}
+
+// blockingJob runs until its context is done
+func blockingJob(ctx context.Context, progress func(float64)) (any, error) {
+	progress(50)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestJobCompletes(t *testing.T) {
+	jobs := NewJobService(JobSettings{Workers: 2, QueueSize: 4, Timeout: time.Minute, Retention: time.Minute})
+
+	job, err := jobs.Submit(models.JobPrediction, "", 0, func(ctx context.Context, progress func(float64)) (any, error) {
+		progress(50)
+		return "done", nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if job.Status != models.JobQueued || job.TimeoutSeconds != 60 {
+		t.Errorf("Expected a queued job with the default timeout, got %+v", job)
+	}
+
+	job = waitForJob(t, jobs, job.ID)
+	if job.Status != models.JobCompleted || job.Result != "done" || job.Progress != 100 || job.ExpiresAt == nil {
+		t.Errorf("Unexpected finished job %+v", job)
+	}
+
+	failed, _ := jobs.Submit(models.JobPrediction, "", 0, func(ctx context.Context, progress func(float64)) (any, error) {
+		return nil, errors.New("no luck")
+	})
+	if job := waitForJob(t, jobs, failed.ID); job.Status != models.JobFailed || job.Error != "no luck" {
+		t.Errorf("Expected a failed job, got %+v", job)
+	}
+}
+
+func TestJobCancelAndTimeout(t *testing.T) {
+	jobs := NewJobService(JobSettings{Workers: 1, QueueSize: 4, Timeout: time.Minute, Retention: time.Minute})
+
+	running, _ := jobs.Submit(models.JobPrediction, "", 0, blockingJob)
+	queued, _ := jobs.Submit(models.JobPrediction, "", 0, blockingJob)
+
+	if job, _ := jobs.Cancel(queued.ID); job.Status != models.JobCancelled {
+		t.Errorf("Expected the queued job to be cancelled at once, got %s", job.Status)
+	}
+	jobs.Cancel(running.ID)
+	if job := waitForJob(t, jobs, running.ID); job.Status != models.JobCancelled {
+		t.Errorf("Expected the running job to be cancelled, got %s", job.Status)
+	}
+
+	timed, _ := jobs.Submit(models.JobPrediction, "", 20*time.Millisecond, blockingJob)
+	if job := waitForJob(t, jobs, timed.ID); job.Status != models.JobFailed || job.Error == "" {
+		t.Errorf("Expected the job to time out, got %+v", job)
+	}
+
+	if _, err := jobs.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobWorkerWaitsForCancelledWork(t *testing.T) {
+	jobs := NewJobService(JobSettings{Workers: 1, QueueSize: 1, Timeout: time.Minute, Retention: time.Minute})
+	release := make(chan struct{})
+
+	slow, _ := jobs.Submit(models.JobPrediction, "", 0, func(ctx context.Context, progress func(float64)) (any, error) {
+		<-release
+		return nil, ctx.Err()
+	})
+	for job, _ := jobs.Get(slow.ID); job.Status != models.JobRunning; job, _ = jobs.Get(slow.ID) {
+		time.Sleep(time.Millisecond)
+	}
+	next, _ := jobs.Submit(models.JobPrediction, "", 0, func(ctx context.Context, progress func(float64)) (any, error) {
+		return "done", nil
+	})
+
+	jobs.Cancel(slow.ID)
+	if job := waitForJob(t, jobs, slow.ID); job.Status != models.JobCancelled {
+		t.Errorf("Expected the job to be cancelled at once, got %s", job.Status)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if job, _ := jobs.Get(next.ID); job.Status != models.JobQueued {
+		t.Errorf("Expected the next job to wait until the cancelled work stops, got %s", job.Status)
+	}
+
+	close(release)
+	if job := waitForJob(t, jobs, next.ID); job.Status != models.JobCompleted {
+		t.Errorf("Expected the next job to run once the worker is free, got %s", job.Status)
+	}
+}
+
+func TestJobQueueFullAndRetention(t *testing.T) {
+	jobs := NewJobService(JobSettings{Workers: 1, QueueSize: 1, Timeout: time.Minute, Retention: 10 * time.Millisecond})
+
+	first, _ := jobs.Submit(models.JobPrediction, "", 0, blockingJob)
+	// Wait for the worker to take the first job off the queue
+	for job, _ := jobs.Get(first.ID); job.Status != models.JobRunning; job, _ = jobs.Get(first.ID) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := jobs.CheckBacklog(context.Background()); err != nil {
+		t.Errorf("Expected room in the queue, got %v", err)
+	}
+	second, _ := jobs.Submit(models.JobPrediction, "", 0, blockingJob)
+	if _, err := jobs.Submit(models.JobPrediction, "", 0, blockingJob); !errors.Is(err, ErrJobQueueFull) {
+		t.Errorf("Expected ErrJobQueueFull, got %v", err)
+	}
+	if err := jobs.CheckBacklog(context.Background()); !errors.Is(err, ErrJobQueueFull) {
+		t.Errorf("Expected the backlog check to fail with ErrJobQueueFull, got %v", err)
+	}
+	if pending := jobs.Pending(); pending != 2 {
+		t.Errorf("Expected 2 pending jobs, got %d", pending)
+	}
+
+	jobs.Cancel(first.ID)
+	jobs.Cancel(second.ID)
+	waitForJob(t, jobs, first.ID)
+	time.Sleep(20 * time.Millisecond)
+	if _, err := jobs.Get(first.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Error("Expected the finished job to expire after the retention period")
+	}
+}
+
+func TestJobSubscribe(t *testing.T) {
+	jobs := NewJobService(JobSettings{Workers: 1, QueueSize: 1, Timeout: time.Minute, Retention: time.Minute})
+	release := make(chan struct{})
+
+	job, _ := jobs.Submit(models.JobPrediction, "", 0, func(ctx context.Context, progress func(float64)) (any, error) {
+		<-release
+		progress(50)
+		return 42, nil
+	})
+	updates, unsubscribe, err := jobs.Subscribe(job.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer unsubscribe()
+	close(release)
+
+	var last models.Job
+	for update := range updates {
+		last = update
+	}
+	if last.Status != models.JobCompleted || last.Result != 42 {
+		t.Errorf("Expected the last update to be the completed job, got %+v", last)
+	}
+}
+
+func TestJobShutdown(t *testing.T) {
+	jobs := NewJobService(JobSettings{Workers: 1, QueueSize: 2, Timeout: time.Minute, Retention: time.Minute})
+
+	quick, _ := jobs.Submit(models.JobPrediction, "", 0, func(ctx context.Context, progress func(float64)) (any, error) {
+		return "done", nil
+	})
+	stuck, _ := jobs.Submit(models.JobPrediction, "", 0, blockingJob)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := jobs.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shutdown to run out of time, got %v", err)
+	}
+
+	if job, _ := jobs.Get(quick.ID); job.Status != models.JobCompleted {
+		t.Errorf("Expected queued work to be drained, got %s", job.Status)
+	}
+	if job, _ := jobs.Get(stuck.ID); job.Status != models.JobCancelled {
+		t.Errorf("Expected unfinished work to be cancelled, got %s", job.Status)
+	}
+	if _, err := jobs.Submit(models.JobPrediction, "", 0, blockingJob); !errors.Is(err, ErrJobServiceClosed) {
+		t.Errorf("Expected ErrJobServiceClosed, got %v", err)
+	}
+	if err := jobs.CheckBacklog(context.Background()); !errors.Is(err, ErrJobServiceClosed) {
+		t.Errorf("Expected the backlog check to fail once shut down, got %v", err)
+	}
+}
+
+func TestSubmitPrediction(t *testing.T) {
+	// One week left keeps exact enumeration within its budget whatever the results
+	service := newPlayedLeagueService(t, 5)
+	jobs := NewJobService(JobSettings{Workers: 1, QueueSize: 1, Timeout: time.Minute, Retention: time.Minute})
+
+	job, err := service.SubmitPrediction(jobs, DefaultLeagueID, models.MethodExact, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	job = waitForJob(t, jobs, job.ID)
+	predictions, ok := job.Result.(*models.PredictionResponse)
+	if !ok || predictions.Method != models.MethodExact || predictions.Week != 5 {
+		t.Fatalf("Expected exact predictions after week 5, got %+v", job)
+	}
+	if job.LeagueID != DefaultLeagueID {
+		t.Errorf("Expected the job to belong to the default league, got %q", job.LeagueID)
+	}
+
+	if _, err := service.SubmitPrediction(jobs, "", "guess", 0); err == nil {
+		t.Error("Expected an unknown method to be rejected")
+	}
+}
@@ -1,17 +1,36 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"sort"
 	"stadia-backend/models"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// LeagueService manages league operations
+// LeagueService manages league operations. It is not safe for concurrent
+// use; LeagueRegistry.LeagueLock guards the leagues of the server.
 type LeagueService struct {
 	league            *models.League
 	simulationService *SimulationService
 	fixtureService    *FixtureService
 	predictionService *PredictionService
+	clinchService     *ClinchService
+	oddsService       *OddsService
+
+	// Method and runtime of the stored league predictions
+	predictionMethod  models.PredictionMethod
+	predictionRuntime time.Duration
+
+	// Saved what-if scenarios by ID
+	scenarios map[string]*models.Scenario
+
+	// Most teams a league may be initialized with; unlimited when 0
+	maxTeams int
 }
 
 // NewLeagueService creates a new league service
@@ -21,17 +40,40 @@ func NewLeagueService() *LeagueService {
 		simulationService: NewSimulationService(),
 		fixtureService:    NewFixtureService(),
 		predictionService: NewPredictionService(),
+		clinchService:     NewClinchService(),
+		oddsService:       NewOddsService(),
+		scenarios:         make(map[string]*models.Scenario),
 	}
 }
 
+// SetMaxTeams limits the number of teams a league may be initialized or
+// imported with. 0 removes the limit.
+func (ls *LeagueService) SetMaxTeams(maxTeams int) {
+	ls.maxTeams = maxTeams
+}
+
+// checkTeamCount checks that a league of n teams is within the limits
+func (ls *LeagueService) checkTeamCount(n int) error {
+	if n < 2 {
+		return errors.New("at least 2 teams are required")
+	}
+	if ls.maxTeams > 0 && n > ls.maxTeams {
+		return fmt.Errorf("at most %d teams are allowed, got %d", ls.maxTeams, n)
+	}
+	return nil
+}
+
 // InitializeLeague initializes the league with teams
 func (ls *LeagueService) InitializeLeague(teams []*models.Team) error {
-	if len(teams) < 2 {
-		return errors.New("at least 2 teams are required")
+	if err := ls.checkTeamCount(len(teams)); err != nil {
+		return err
 	}
 
 	// Reset league
 	ls.league = models.NewLeague("Champions League Group Stage")
+	ls.league.Settings.Model = defaultModel()
+	ls.applyModel()
+	ls.seedSimulation()
 
 	// Add teams
 	for _, team := range teams {
@@ -42,10 +84,86 @@ func (ls *LeagueService) InitializeLeague(teams []*models.Team) error {
 	ls.league.Fixtures = ls.fixtureService.GenerateFixturesOptimized(teams)
 	ls.league.TotalWeeks = len(ls.league.Fixtures)
 	ls.league.CurrentWeek = 0
+	ls.predictionMethod = ""
+	ls.predictionRuntime = 0
+
+	// Saved scenarios refer to matches of the previous league
+	ls.scenarios = make(map[string]*models.Scenario)
 
 	return nil
 }
 
+// InitializeLeagueWithFixtures initializes a league with a given fixture list,
+// such as an imported real competition. Matches already marked as played count
+// towards the standings and every leading week whose matches are all played
+// counts as a played week.
+func (ls *LeagueService) InitializeLeagueWithFixtures(ctx context.Context, name string, teams []*models.Team, fixtures [][]*models.Match) error {
+	if err := ls.checkTeamCount(len(teams)); err != nil {
+		return err
+	}
+	if len(fixtures) == 0 {
+		return errors.New("at least one week of fixtures is required")
+	}
+
+	known := make(map[string]bool, len(teams))
+	for _, team := range teams {
+		known[team.ID] = true
+	}
+	for _, weekMatches := range fixtures {
+		for _, match := range weekMatches {
+			if !known[match.HomeTeamID] || !known[match.AwayTeamID] {
+				return fmt.Errorf("match %s refers to an unknown team", match.ID)
+			}
+		}
+	}
+
+	if name == "" {
+		name = "Champions League Group Stage"
+	}
+	ls.league = models.NewLeague(name)
+	ls.league.Settings.Model = defaultModel()
+	ls.applyModel()
+	ls.seedSimulation()
+	for _, team := range teams {
+		team.ResetStats()
+		ls.league.AddTeam(team)
+	}
+
+	ls.league.Fixtures = fixtures
+	ls.league.TotalWeeks = len(fixtures)
+	ls.league.CurrentWeek = 0
+	ls.predictionMethod = ""
+	ls.predictionRuntime = 0
+	ls.scenarios = make(map[string]*models.Scenario)
+
+	for _, match := range ls.league.GetAllMatches() {
+		if match.IsPlayed() {
+			ls.league.GetTeam(match.HomeTeamID).UpdateStats(match.HomeScore, match.AwayScore)
+			ls.league.GetTeam(match.AwayTeamID).UpdateStats(match.AwayScore, match.HomeScore)
+		}
+	}
+
+	ls.advanceCompletedWeeks()
+	if ls.predictionsDue() {
+		ls.updatePredictions(ctx)
+	}
+
+	return nil
+}
+
+// advanceCompletedWeeks moves the current week past every following week whose
+// matches have all been played
+func (ls *LeagueService) advanceCompletedWeeks() {
+	for ls.league.CurrentWeek < ls.league.TotalWeeks {
+		for _, match := range ls.league.Fixtures[ls.league.CurrentWeek] {
+			if !match.IsPlayed() {
+				return
+			}
+		}
+		ls.league.CurrentWeek++
+	}
+}
+
 // GetLeague returns the current league state
 func (ls *LeagueService) GetLeague() *models.League {
 	return ls.league
@@ -54,7 +172,12 @@ func (ls *LeagueService) GetLeague() *models.League {
 // GetStandings returns the sorted league table
 func (ls *LeagueService) GetStandings() []*models.Team {
 	teams := ls.league.GetTeamsList()
+	sortStandings(teams)
+	return teams
+}
 
+// sortStandings sorts teams into table order
+func sortStandings(teams []*models.Team) {
 	// Sort by: Points (desc), Goal Difference (desc), Goals For (desc), Name (asc)
 	sort.Slice(teams, func(i, j int) bool {
 		if teams[i].Points != teams[j].Points {
@@ -68,12 +191,31 @@ func (ls *LeagueService) GetStandings() []*models.Team {
 		}
 		return teams[i].Name < teams[j].Name
 	})
+}
 
-	return teams
+// GetStandingsTable returns the sorted league table with each team's position
+// and its clinch or elimination status for the top positions
+func (ls *LeagueService) GetStandingsTable() []models.Standing {
+	teams := ls.GetStandings()
+	outlooks := ls.clinchService.Analyze(teams, ls.league.Fixtures)
+
+	standings := make([]models.Standing, len(teams))
+	for i, team := range teams {
+		standings[i] = models.Standing{
+			Team:     team,
+			Position: i + 1,
+			Outlook:  outlooks[team.ID],
+		}
+	}
+
+	return standings
 }
 
 // PlayNextWeek simulates all matches in the next week
-func (ls *LeagueService) PlayNextWeek() error {
+func (ls *LeagueService) PlayNextWeek(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "LeagueService.PlayNextWeek", attribute.Int("league.week", ls.league.CurrentWeek+1))
+	defer func() { endSpan(span, err) }()
+
 	if ls.league.CurrentWeek >= ls.league.TotalWeeks {
 		return errors.New("all weeks have been played")
 	}
@@ -81,10 +223,12 @@ func (ls *LeagueService) PlayNextWeek() error {
 	ls.league.CurrentWeek++
 	matches := ls.league.GetMatchesByWeek(ls.league.CurrentWeek)
 
+	simulated := 0
 	for _, match := range matches {
 		if match.IsPlayed() {
 			continue
 		}
+		simulated++
 
 		homeTeam := ls.league.GetTeam(match.HomeTeamID)
 		awayTeam := ls.league.GetTeam(match.AwayTeamID)
@@ -95,19 +239,25 @@ func (ls *LeagueService) PlayNextWeek() error {
 		homeTeam.UpdateStats(homeScore, awayScore)
 		awayTeam.UpdateStats(awayScore, homeScore)
 	}
+	instrumentation.MatchesSimulated(simulated)
+	span.SetAttributes(attribute.Int("league.simulated_matches", simulated))
+	slog.InfoContext(ctx, "Week played", "week", ls.league.CurrentWeek, "simulated_matches", simulated)
 
-	// Update predictions if we're past week 3
-	if ls.league.CurrentWeek >= 3 {
-		ls.updatePredictions()
+	// Update predictions once the league is far enough in
+	if ls.predictionsDue() {
+		ls.updatePredictions(ctx)
 	}
 
 	return nil
 }
 
 // PlayAllWeeks simulates all remaining weeks
-func (ls *LeagueService) PlayAllWeeks() error {
+func (ls *LeagueService) PlayAllWeeks(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "LeagueService.PlayAllWeeks", attribute.Int("league.week", ls.league.CurrentWeek))
+	defer func() { endSpan(span, err) }()
+
 	for ls.league.CurrentWeek < ls.league.TotalWeeks {
-		err := ls.PlayNextWeek()
+		err := ls.PlayNextWeek(ctx)
 		if err != nil {
 			return err
 		}
@@ -116,51 +266,57 @@ func (ls *LeagueService) PlayAllWeeks() error {
 }
 
 // UpdateMatchResult manually updates a match result
-func (ls *LeagueService) UpdateMatchResult(matchID string, homeScore, awayScore int) error {
+func (ls *LeagueService) UpdateMatchResult(ctx context.Context, matchID string, homeScore, awayScore int) (err error) {
+	ctx, span := startSpan(ctx, "LeagueService.UpdateMatchResult", attribute.String("league.match_id", matchID))
+	defer func() { endSpan(span, err) }()
+
 	if homeScore < 0 || awayScore < 0 {
 		return errors.New("scores cannot be negative")
 	}
 
 	// Find the match
-	var targetMatch *models.Match
-	for _, weekMatches := range ls.league.Fixtures {
-		for _, match := range weekMatches {
-			if match.ID == matchID {
-				targetMatch = match
-				break
-			}
-		}
-		if targetMatch != nil {
-			break
-		}
-	}
-
+	targetMatch := ls.league.GetMatch(matchID)
 	if targetMatch == nil {
 		return errors.New("match not found")
 	}
 
+	message := "Match result entered"
+	if targetMatch.IsPlayed() {
+		message = "Match corrected"
+	}
+	ls.applyResult(targetMatch, homeScore, awayScore)
+	slog.InfoContext(ctx, message,
+		"match_id", matchID,
+		"week", targetMatch.Week,
+		"home_score", homeScore,
+		"away_score", awayScore)
+
+	// Update predictions if applicable
+	if ls.predictionsDue() {
+		ls.updatePredictions(ctx)
+	}
+
+	return nil
+}
+
+// applyResult sets a match result and updates both teams' statistics,
+// replacing any previous result of the match
+func (ls *LeagueService) applyResult(match *models.Match, homeScore, awayScore int) {
 	// Get teams
-	homeTeam := ls.league.GetTeam(targetMatch.HomeTeamID)
-	awayTeam := ls.league.GetTeam(targetMatch.AwayTeamID)
+	homeTeam := ls.league.GetTeam(match.HomeTeamID)
+	awayTeam := ls.league.GetTeam(match.AwayTeamID)
 
 	// If match was already played, revert the old stats
-	if targetMatch.IsPlayed() {
-		ls.revertMatchStats(homeTeam, awayTeam, targetMatch.HomeScore, targetMatch.AwayScore)
+	if match.IsPlayed() {
+		ls.revertMatchStats(homeTeam, awayTeam, match.HomeScore, match.AwayScore)
 	}
 
 	// Update match
-	targetMatch.SetResult(homeScore, awayScore)
+	match.SetResult(homeScore, awayScore)
 
 	// Update team stats
 	homeTeam.UpdateStats(homeScore, awayScore)
 	awayTeam.UpdateStats(awayScore, homeScore)
-
-	// Update predictions if applicable
-	if ls.league.CurrentWeek >= 3 {
-		ls.updatePredictions()
-	}
-
-	return nil
 }
 
 // revertMatchStats reverts team statistics for a match
@@ -216,28 +372,183 @@ func (ls *LeagueService) ResetLeague() error {
 
 	ls.league.CurrentWeek = 0
 	ls.league.Predictions = make(map[string]float64)
+	ls.league.PredictionHistory = nil
+	ls.predictionMethod = ""
+	ls.predictionRuntime = 0
+
+	return nil
+}
+
+// predictionsDue reports whether the league is far enough in to store predictions
+func (ls *LeagueService) predictionsDue() bool {
+	return ls.league.CurrentWeek >= ls.league.Settings.PredictionStartWeek
+}
+
+// seedSimulation seeds match simulation for the league, choosing a seed if it has none
+func (ls *LeagueService) seedSimulation() {
+	if ls.league.Seed == 0 {
+		ls.league.Seed = time.Now().UnixNano()
+	}
+	ls.simulationService.Seed(ls.league.Seed)
+}
+
+// defaultModel returns the parameters of the default model preset
+func defaultModel() models.ModelParams {
+	params, err := CurrentSimulationSettings().Preset("")
+	if err != nil {
+		return models.DefaultModelParams()
+	}
+	return params
+}
+
+// applyModel makes match simulation and predictions use the league's model
+func (ls *LeagueService) applyModel() {
+	ls.simulationService.SetParams(ls.league.Settings.Model)
+	ls.predictionService.simulationService.SetParams(ls.league.Settings.Model)
+}
 
+// SetModel replaces the match model of the league, for example with a preset
+// from SimulationSettings.Preset, and recalculates stored predictions
+func (ls *LeagueService) SetModel(ctx context.Context, params models.ModelParams) error {
+	if err := params.Validate(); err != nil {
+		return fmt.Errorf("invalid model: %w", err)
+	}
+	ls.league.Settings.Model = params
+	ls.applyModel()
+	if ls.predictionsDue() && len(ls.league.Teams) > 0 {
+		ls.updatePredictions(ctx)
+	}
 	return nil
 }
 
+// Reseed replaces the league seed and restarts match simulation from it. A seed
+// of 0 chooses a new one.
+func (ls *LeagueService) Reseed(seed int64) {
+	ls.league.Seed = seed
+	ls.seedSimulation()
+}
+
 // updatePredictions updates championship predictions
-func (ls *LeagueService) updatePredictions() {
-	teams := ls.league.GetTeamsList()
-	predictions := ls.predictionService.CalculatePredictions(
-		teams,
+func (ls *LeagueService) updatePredictions(ctx context.Context) {
+	ctx, span := startSpan(ctx, "LeagueService.updatePredictions")
+	defer span.End()
+
+	result, err := ls.predict(ctx, ls.league.Settings.PredictionMethod)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to update predictions", "method", ls.league.Settings.PredictionMethod, "error", err)
+		return
+	}
+	logPredictions(ctx, ls.league.CurrentWeek, result)
+
+	ls.league.Predictions = result.Probabilities
+	ls.predictionMethod = result.Method
+	ls.predictionRuntime = result.Runtime
+
+	// Keep one snapshot per week; a changed result replaces that week's snapshot
+	snapshot := models.PredictionSnapshot{
+		Week:          ls.league.CurrentWeek,
+		Method:        result.Method,
+		Probabilities: result.Probabilities,
+	}
+	history := ls.league.PredictionHistory
+	if n := len(history); n > 0 && history[n-1].Week == snapshot.Week {
+		history[n-1] = snapshot
+	} else {
+		ls.league.PredictionHistory = append(history, snapshot)
+	}
+}
+
+// GetPredictions returns current predictions
+func (ls *LeagueService) GetPredictions() *models.PredictionResponse {
+	return ls.buildPredictionResponse(ls.league.Predictions, ls.predictionMethod, ls.predictionRuntime)
+}
+
+// CalculatePredictions calculates predictions for the current league state with
+// the given method, without replacing the stored league predictions
+func (ls *LeagueService) CalculatePredictions(ctx context.Context, method models.PredictionMethod) (_ *models.PredictionResponse, err error) {
+	ctx, span := startSpan(ctx, "LeagueService.CalculatePredictions")
+	defer func() { endSpan(span, err) }()
+
+	result, err := ls.predict(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	logPredictions(ctx, ls.league.CurrentWeek, result)
+
+	return ls.buildPredictionResponse(result.Probabilities, result.Method, result.Runtime), nil
+}
+
+// predict calculates championship probabilities for the current league state
+func (ls *LeagueService) predict(ctx context.Context, method models.PredictionMethod) (*PredictionResult, error) {
+	return ls.predictionService.Predict(
+		ctx,
+		method,
+		ls.league.GetTeamsList(),
 		ls.league.Fixtures,
 		ls.league.CurrentWeek,
 		ls.league.TotalWeeks,
 	)
+}
 
-	ls.league.Predictions = predictions
+// logPredictions logs how predictions were computed
+func logPredictions(ctx context.Context, week int, result *PredictionResult) {
+	slog.InfoContext(ctx, "Predictions computed",
+		"week", week,
+		"method", result.Method,
+		"runtime_ms", float64(result.Runtime.Microseconds())/1000)
 }
 
-// GetPredictions returns current predictions
-func (ls *LeagueService) GetPredictions() *models.PredictionResponse {
+// SubmitPrediction calculates predictions with the given method as a
+// background job of the league with the given ID, against a copy of the
+// current league state
+func (ls *LeagueService) SubmitPrediction(
+	jobs *JobService,
+	leagueID string,
+	method models.PredictionMethod,
+	timeout time.Duration,
+) (*models.Job, error) {
+	if len(ls.league.Teams) < 2 {
+		return nil, errors.New("league not initialized")
+	}
+	switch method {
+	case models.MethodAuto, models.MethodMonteCarlo, models.MethodHeuristic, models.MethodExact:
+	default:
+		return nil, fmt.Errorf("unknown prediction method %q", method)
+	}
+	detached := ls.Detached()
+
+	return jobs.Submit(models.JobPrediction, leagueID, timeout, func(ctx context.Context, progress func(float64)) (any, error) {
+		return detached.CalculatePredictions(ctx, method)
+	})
+}
+
+// Detached returns a league service over a copy of the league, with its own
+// simulation and prediction services, that can be used from another goroutine
+func (ls *LeagueService) Detached() *LeagueService {
+	detached := &LeagueService{
+		league:            ls.league.Clone(),
+		simulationService: NewSimulationService(),
+		fixtureService:    ls.fixtureService,
+		predictionService: NewPredictionService(),
+		clinchService:     ls.clinchService,
+		oddsService:       ls.oddsService,
+		predictionMethod:  ls.predictionMethod,
+		predictionRuntime: ls.predictionRuntime,
+		scenarios:         make(map[string]*models.Scenario),
+	}
+	detached.applyModel()
+	return detached
+}
+
+// buildPredictionResponse converts probabilities into a sorted prediction response
+func (ls *LeagueService) buildPredictionResponse(
+	probabilities map[string]float64,
+	method models.PredictionMethod,
+	runtime time.Duration,
+) *models.PredictionResponse {
 	predictions := make([]models.Prediction, 0)
 
-	for teamID, probability := range ls.league.Predictions {
+	for teamID, probability := range probabilities {
 		team := ls.league.GetTeam(teamID)
 		if team != nil {
 			predictions = append(predictions, models.Prediction{
@@ -248,13 +559,18 @@ func (ls *LeagueService) GetPredictions() *models.PredictionResponse {
 		}
 	}
 
-	// Sort by probability descending
+	// Sort by probability descending, then by name so ties keep a stable order
 	sort.Slice(predictions, func(i, j int) bool {
-		return predictions[i].Probability > predictions[j].Probability
+		if predictions[i].Probability != predictions[j].Probability {
+			return predictions[i].Probability > predictions[j].Probability
+		}
+		return predictions[i].TeamName < predictions[j].TeamName
 	})
 
 	return &models.PredictionResponse{
 		Week:        ls.league.CurrentWeek,
+		Method:      method,
+		RuntimeMs:   float64(runtime.Microseconds()) / 1000,
 		Predictions: predictions,
 	}
 }
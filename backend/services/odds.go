@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"stadia-backend/models"
+)
+
+const (
+	// minDecimalOdds is the shortest price offered, even for a certain outcome
+	minDecimalOdds = 1.01
+
+	// maxFractionDenominator bounds the denominator of fractional odds
+	maxFractionDenominator = 100
+
+	// maxMargin is the highest margin accepted, as a fraction
+	maxMargin = 0.5
+)
+
+// OddsService converts probabilities to betting odds and back
+type OddsService struct{}
+
+// NewOddsService creates a new odds service
+func NewOddsService() *OddsService {
+	return &OddsService{}
+}
+
+// Price returns the odds for a probability with a proportional margin applied,
+// or nil if the probability is zero
+func (o *OddsService) Price(probability, margin float64) *models.Odds {
+	if probability <= 0 {
+		return nil
+	}
+
+	decimal := 1 / (probability * (1 + margin))
+	decimal = math.Max(minDecimalOdds, math.Round(decimal*100)/100)
+
+	return &models.Odds{
+		Decimal:    decimal,
+		Fractional: o.FractionalOdds(decimal),
+		American:   o.AmericanOdds(decimal),
+	}
+}
+
+// FractionalOdds converts decimal odds to the simplest fraction within 1% of the profit
+func (o *OddsService) FractionalOdds(decimal float64) string {
+	profit := decimal - 1
+	bestNum, bestDen := 0, 1
+	bestErr := math.Inf(1)
+
+	for den := 1; den <= maxFractionDenominator; den++ {
+		num := int(math.Round(profit * float64(den)))
+		if num == 0 {
+			continue
+		}
+		err := math.Abs(float64(num)/float64(den) - profit)
+		if err < bestErr {
+			bestNum, bestDen, bestErr = num, den, err
+		}
+		if err <= profit*0.01 {
+			break
+		}
+	}
+
+	return fmt.Sprintf("%d/%d", bestNum, bestDen)
+}
+
+// AmericanOdds converts decimal odds to moneyline odds
+func (o *OddsService) AmericanOdds(decimal float64) string {
+	if decimal >= 2 {
+		return fmt.Sprintf("+%d", int(math.Round((decimal-1)*100)))
+	}
+	return fmt.Sprintf("-%d", int(math.Round(100/(decimal-1))))
+}
+
+// RemoveOverround converts a book of decimal odds to fair probabilities by
+// scaling the implied probabilities to sum to one. It also returns the
+// implied probabilities and the overround (their sum minus one).
+func (o *OddsService) RemoveOverround(decimals []float64) (fair, implied []float64, overround float64) {
+	implied = make([]float64, len(decimals))
+	total := 0.0
+	for i, decimal := range decimals {
+		implied[i] = 1 / decimal
+		total += implied[i]
+	}
+
+	fair = make([]float64, len(decimals))
+	for i := range implied {
+		fair[i] = implied[i] / total
+	}
+
+	return fair, implied, total - 1
+}
+
+// compare scores the model against a bookmaker book of decimal odds
+func (o *OddsService) compare(odds, model []float64, threshold float64) ([]models.SelectionComparison, float64) {
+	fair, implied, overround := o.RemoveOverround(odds)
+
+	selections := make([]models.SelectionComparison, len(odds))
+	for i := range odds {
+		edge := model[i]*odds[i] - 1
+		selections[i] = models.SelectionComparison{
+			BookmakerOdds:      odds[i],
+			ImpliedProbability: implied[i] * 100,
+			FairProbability:    fair[i] * 100,
+			ModelProbability:   model[i] * 100,
+			Difference:         (model[i] - fair[i]) * 100,
+			Edge:               edge * 100,
+			Value:              edge*100 >= threshold,
+		}
+	}
+
+	return selections, overround * 100
+}
+
+// validateMargin checks a margin given as a percentage and returns it as a fraction
+func validateMargin(margin float64) (float64, error) {
+	if margin < 0 || margin > maxMargin*100 {
+		return 0, fmt.Errorf("margin must be between 0 and %.0f percent", maxMargin*100)
+	}
+	return margin / 100, nil
+}
+
+// outrightProbabilities returns the championship probabilities for the current state
+func (ls *LeagueService) outrightProbabilities(ctx context.Context, method models.PredictionMethod) (*PredictionResult, error) {
+	if len(ls.league.Teams) < 2 {
+		return nil, errors.New("league not initialized")
+	}
+
+	return ls.predict(ctx, method)
+}
+
+// GetOdds prices every unplayed match and the outright winner from the model
+// probabilities, with the given margin in percent (0 for fair odds)
+func (ls *LeagueService) GetOdds(ctx context.Context, margin float64, method models.PredictionMethod) (*models.OddsResponse, error) {
+	fraction, err := validateMargin(margin)
+	if err != nil {
+		return nil, err
+	}
+
+	outright, err := ls.outrightProbabilities(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.OddsResponse{
+		Week:     ls.league.CurrentWeek,
+		Method:   outright.Method,
+		Margin:   margin,
+		Matches:  make([]models.MatchPrices, 0),
+		Outright: make([]models.OutrightPrice, 0, len(outright.Probabilities)),
+	}
+
+	price := func(probability float64) models.Price {
+		return models.Price{Probability: probability * 100, Odds: ls.oddsService.Price(probability, fraction)}
+	}
+
+	for _, match := range remainingMatches(ls.league.Fixtures) {
+		homeWin, draw, awayWin := ls.simulationService.OutcomeProbabilities(
+			ls.league.GetTeam(match.HomeTeamID),
+			ls.league.GetTeam(match.AwayTeamID),
+		)
+		response.Matches = append(response.Matches, models.MatchPrices{
+			MatchID:      match.ID,
+			Week:         match.Week,
+			HomeTeamName: match.HomeTeamName,
+			AwayTeamName: match.AwayTeamName,
+			HomeWin:      price(homeWin),
+			Draw:         price(draw),
+			AwayWin:      price(awayWin),
+		})
+	}
+
+	for _, team := range ls.league.GetTeamsList() {
+		response.Outright = append(response.Outright, models.OutrightPrice{
+			TeamID:   team.ID,
+			TeamName: team.Name,
+			Price:    price(outright.Probabilities[team.ID]),
+		})
+	}
+	sort.SliceStable(response.Outright, func(i, j int) bool {
+		return response.Outright[i].Probability > response.Outright[j].Probability
+	})
+
+	return response, nil
+}
+
+// CompareOdds compares bookmaker odds with the model. The overround is removed
+// from each book and selections whose expected return at the bookmaker's odds is
+// at least threshold percent are reported as value spots. Outright odds must
+// cover every team so the overround can be removed.
+func (ls *LeagueService) CompareOdds(
+	ctx context.Context,
+	matches []models.BookmakerMatchOdds,
+	outright []models.BookmakerOutrightOdds,
+	threshold float64,
+	method models.PredictionMethod,
+) (*models.OddsComparison, error) {
+	if len(matches) == 0 && len(outright) == 0 {
+		return nil, errors.New("no bookmaker odds given")
+	}
+
+	comparison := &models.OddsComparison{
+		Week:       ls.league.CurrentWeek,
+		Threshold:  threshold,
+		Matches:    make([]models.MatchComparison, 0, len(matches)),
+		ValueSpots: make([]models.SelectionComparison, 0),
+	}
+
+	seen := make(map[string]bool)
+	for _, odds := range matches {
+		match := ls.league.GetMatch(odds.MatchID)
+		if match == nil {
+			return nil, fmt.Errorf("match %s not found", odds.MatchID)
+		}
+		if match.IsPlayed() {
+			return nil, fmt.Errorf("match %s has already been played", odds.MatchID)
+		}
+		if seen[odds.MatchID] {
+			return nil, fmt.Errorf("match %s appears more than once", odds.MatchID)
+		}
+		seen[odds.MatchID] = true
+
+		homeWin, draw, awayWin := ls.simulationService.OutcomeProbabilities(
+			ls.league.GetTeam(match.HomeTeamID),
+			ls.league.GetTeam(match.AwayTeamID),
+		)
+		selections, overround := ls.oddsService.compare(
+			[]float64{odds.HomeWin, odds.Draw, odds.AwayWin},
+			[]float64{homeWin, draw, awayWin},
+			threshold,
+		)
+		names := []string{match.HomeTeamName, "Draw", match.AwayTeamName}
+		for i := range selections {
+			selections[i].MatchID = match.ID
+			selections[i].Selection = names[i]
+			selections[i].Outcome = outcomeNames[i]
+		}
+
+		comparison.Matches = append(comparison.Matches, models.MatchComparison{
+			MatchID:      match.ID,
+			Week:         match.Week,
+			HomeTeamName: match.HomeTeamName,
+			AwayTeamName: match.AwayTeamName,
+			Overround:    overround,
+			Selections:   selections,
+		})
+	}
+
+	if len(outright) > 0 {
+		result, err := ls.outrightProbabilities(ctx, method)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Method = result.Method
+
+		odds := make([]float64, len(outright))
+		model := make([]float64, len(outright))
+		seen := make(map[string]bool)
+		for i, o := range outright {
+			if ls.league.GetTeam(o.TeamID) == nil {
+				return nil, fmt.Errorf("team %s not found", o.TeamID)
+			}
+			if seen[o.TeamID] {
+				return nil, fmt.Errorf("team %s appears more than once", o.TeamID)
+			}
+			seen[o.TeamID] = true
+			odds[i] = o.Odds
+			model[i] = result.Probabilities[o.TeamID]
+		}
+		if len(seen) != len(ls.league.Teams) {
+			return nil, errors.New("outright odds must be given for every team")
+		}
+
+		selections, overround := ls.oddsService.compare(odds, model, threshold)
+		for i := range selections {
+			selections[i].TeamID = outright[i].TeamID
+			selections[i].Selection = ls.league.GetTeam(outright[i].TeamID).Name
+		}
+		comparison.Outright = &models.OutrightComparison{
+			Overround:  overround,
+			Selections: selections,
+		}
+	}
+
+	for _, match := range comparison.Matches {
+		for _, selection := range match.Selections {
+			if selection.Value {
+				comparison.ValueSpots = append(comparison.ValueSpots, selection)
+			}
+		}
+	}
+	if comparison.Outright != nil {
+		for _, selection := range comparison.Outright.Selections {
+			if selection.Value {
+				comparison.ValueSpots = append(comparison.ValueSpots, selection)
+			}
+		}
+	}
+	sort.SliceStable(comparison.ValueSpots, func(i, j int) bool {
+		return comparison.ValueSpots[i].Edge > comparison.ValueSpots[j].Edge
+	})
+
+	return comparison, nil
+}
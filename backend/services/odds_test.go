@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"math"
+	"stadia-backend/models"
+	"testing"
+)
+
+func TestOddsFormats(t *testing.T) {
+	service := NewOddsService()
+
+	tests := []struct {
+		probability float64
+		decimal     float64
+		fractional  string
+		american    string
+	}{
+		{0.5, 2.00, "1/1", "+100"},
+		{0.4, 2.50, "3/2", "+150"},
+		{0.8, 1.25, "1/4", "-400"},
+		{1.0, 1.01, "1/100", "-10000"},
+	}
+
+	for _, tt := range tests {
+		odds := service.Price(tt.probability, 0)
+		if odds.Decimal != tt.decimal || odds.Fractional != tt.fractional || odds.American != tt.american {
+			t.Errorf("Probability %.2f: expected %.2f %s %s, got %.2f %s %s", tt.probability,
+				tt.decimal, tt.fractional, tt.american, odds.Decimal, odds.Fractional, odds.American)
+		}
+	}
+
+	if service.Price(0, 0) != nil {
+		t.Error("An impossible outcome should have no odds")
+	}
+
+	// A 10% margin shortens every price
+	if odds := service.Price(0.5, 0.1); odds.Decimal != 1.82 {
+		t.Errorf("Expected 1.82 with a 10%% margin, got %.2f", odds.Decimal)
+	}
+}
+
+func TestRemoveOverround(t *testing.T) {
+	service := NewOddsService()
+
+	fair, implied, overround := service.RemoveOverround([]float64{1.8, 3.6, 4.5})
+	if math.Abs(overround-(1/1.8+1/3.6+1/4.5-1)) > 1e-9 {
+		t.Errorf("Unexpected overround %f", overround)
+	}
+	total := 0.0
+	for i := range fair {
+		total += fair[i]
+		if fair[i] >= implied[i] {
+			t.Errorf("Fair probability %f should be below implied %f", fair[i], implied[i])
+		}
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("Fair probabilities should sum to 1, got %f", total)
+	}
+}
+
+func TestCompareOdds(t *testing.T) {
+	service, teams := newLastWeekLeagueService()
+	league := service.GetLeague()
+	matchAD, matchBC := league.Fixtures[0][0], league.Fixtures[0][1]
+
+	// Team A has already won the group, so any price on it is value
+	comparison, err := service.CompareOdds(
+		context.Background(),
+		[]models.BookmakerMatchOdds{{MatchID: matchBC.ID, HomeWin: 2.1, Draw: 3.4, AwayWin: 3.6}},
+		[]models.BookmakerOutrightOdds{
+			{TeamID: teams[0].ID, Odds: 1.5},
+			{TeamID: teams[1].ID, Odds: 4},
+			{TeamID: teams[2].ID, Odds: 8},
+			{TeamID: teams[3].ID, Odds: 20},
+		},
+		5,
+		models.MethodAuto,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(comparison.Matches) != 1 || len(comparison.Matches[0].Selections) != 3 {
+		t.Fatalf("Expected one match with three selections, got %+v", comparison.Matches)
+	}
+	if comparison.Matches[0].Overround <= 0 {
+		t.Error("Expected a positive overround on the match book")
+	}
+	if len(comparison.ValueSpots) == 0 || comparison.ValueSpots[0].TeamID != teams[0].ID {
+		t.Fatalf("Expected Team A to be the best value spot, got %+v", comparison.ValueSpots)
+	}
+	if math.Abs(comparison.ValueSpots[0].Edge-50) > 1e-6 {
+		t.Errorf("Expected a 50%% edge at 1.5 on a certainty, got %.2f", comparison.ValueSpots[0].Edge)
+	}
+
+	if _, err := service.CompareOdds(context.Background(), nil, []models.BookmakerOutrightOdds{{TeamID: teams[0].ID, Odds: 1.5}}, 5, models.MethodAuto); err == nil {
+		t.Error("Expected an error for an incomplete outright book")
+	}
+
+	matchAD.SetResult(1, 0)
+	if _, err := service.CompareOdds(context.Background(), []models.BookmakerMatchOdds{{MatchID: matchAD.ID, HomeWin: 1.2, Draw: 6, AwayWin: 12}}, nil, 5, models.MethodAuto); err == nil {
+		t.Error("Expected an error for a played match")
+	}
+}
+
+func TestGetOdds(t *testing.T) {
+	service, _ := newLastWeekLeagueService()
+
+	odds, err := service.GetOdds(context.Background(), 5, models.MethodAuto)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(odds.Matches) != 2 || len(odds.Outright) != 4 {
+		t.Fatalf("Expected 2 matches and 4 outright prices, got %d and %d", len(odds.Matches), len(odds.Outright))
+	}
+
+	// Team A has already won; the rest cannot
+	if odds.Outright[0].TeamName != "Team A" || odds.Outright[0].Odds.Decimal != minDecimalOdds {
+		t.Errorf("Expected Team A at the shortest price, got %+v", odds.Outright[0])
+	}
+	if odds.Outright[1].Odds != nil {
+		t.Errorf("Expected no odds for a team that cannot win, got %+v", odds.Outright[1])
+	}
+
+	if _, err := service.GetOdds(context.Background(), -1, models.MethodAuto); err == nil {
+		t.Error("Expected an error for a negative margin")
+	}
+}
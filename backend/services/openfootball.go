@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// openFootballTeam is a team in the openfootball JSON formats: a plain name in
+// the current format, an object with a name in older files
+type openFootballTeam string
+
+// UnmarshalJSON accepts both team representations
+func (t *openFootballTeam) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = openFootballTeam(name)
+		return nil
+	}
+
+	var team struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &team); err != nil {
+		return err
+	}
+	*t = openFootballTeam(team.Name)
+	return nil
+}
+
+// openFootballMatch is a match in the openfootball JSON formats
+type openFootballMatch struct {
+	Round  string           `json:"round"`
+	Team1  openFootballTeam `json:"team1"`
+	Team2  openFootballTeam `json:"team2"`
+	Score1 *int             `json:"score1"` // Older files
+	Score2 *int             `json:"score2"`
+	Score  *struct {
+		FT []int `json:"ft"` // Full-time score
+	} `json:"score"`
+}
+
+// openFootballFile is an openfootball JSON file: a flat match list with round
+// names in the current format, matches grouped in rounds in older files
+type openFootballFile struct {
+	Name    string              `json:"name"`
+	Matches []openFootballMatch `json:"matches"`
+	Rounds  []struct {
+		Name    string              `json:"name"`
+		Matches []openFootballMatch `json:"matches"`
+	} `json:"rounds"`
+}
+
+// parseOpenFootballJSON reads an openfootball football.json file
+func parseOpenFootballJSON(r io.Reader) (*competition, error) {
+	var file openFootballFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid openfootball JSON: %w", err)
+	}
+
+	matches := file.Matches
+	for _, round := range file.Rounds {
+		for _, match := range round.Matches {
+			if match.Round == "" {
+				match.Round = round.Name
+			}
+			matches = append(matches, match)
+		}
+	}
+
+	parsed := &competition{name: file.Name}
+	rounds := make(map[string]int)
+	importErr := &ImportError{}
+	for i, m := range matches {
+		entry := i + 1
+		home, away := strings.TrimSpace(string(m.Team1)), strings.TrimSpace(string(m.Team2))
+		if home == "" || away == "" {
+			importErr.add(entry, "", "match %d has no teams", entry)
+			continue
+		}
+
+		round, ok := rounds[m.Round]
+		if !ok {
+			round = len(rounds)
+			rounds[m.Round] = round
+		}
+
+		match := competitionMatch{line: entry, round: round, home: home, away: away}
+		switch {
+		case m.Score != nil && len(m.Score.FT) == 2:
+			match.played, match.homeScore, match.awayScore = true, m.Score.FT[0], m.Score.FT[1]
+		case m.Score1 != nil && m.Score2 != nil:
+			match.played, match.homeScore, match.awayScore = true, *m.Score1, *m.Score2
+		}
+		if match.homeScore < 0 || match.awayScore < 0 {
+			importErr.add(entry, "", "scores cannot be negative")
+			continue
+		}
+		parsed.matches = append(parsed.matches, match)
+	}
+
+	if len(importErr.Rows) > 0 {
+		return nil, importErr
+	}
+	return parsed, nil
+}
+
+var (
+	// Round headings such as "Matchday 1", "» Round 2" or "▪ Week 3"
+	openFootballRound = regexp.MustCompile(`(?i)^[»▪\-\s]*(matchday|round|week|spieltag|jornada|giornata|journée|speeldag)\s+\d+`)
+
+	// Date lines such as "Sat Sep/12" or "Fri Aug 13 2021"
+	openFootballDate = regexp.MustCompile(`(?i)^((mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?,?\s+)?(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?[\s/]+\d`)
+
+	// Optional kick-off time in front of a match
+	openFootballTime = `^(?:\d{1,2}[.:]\d{2}\s+)?`
+
+	// "Fulham FC  0-3  Arsenal FC" with an optional half-time score
+	openFootballScoreBetween = regexp.MustCompile(openFootballTime + `(.+?)\s+(\d+)\s*-\s*(\d+)(?:\s*\(\d+\s*-\s*\d+\))?\s+(.+?)$`)
+
+	// "Fulham FC  v  Arsenal FC  0-3 (0-2)"
+	openFootballScoreAfter = regexp.MustCompile(openFootballTime + `(.+?)\s+vs?\.?\s+(.+?)\s+(\d+)\s*-\s*(\d+)(?:\s*\(\d+\s*-\s*\d+\))?$`)
+
+	// "Fulham FC  v  Arsenal FC" for matches not played yet
+	openFootballUnplayed = regexp.MustCompile(openFootballTime + `(.+?)\s+(?:vs?\.?|-)\s+(.+?)$`)
+)
+
+// parseOpenFootballTXT reads a Football.TXT file: a "= Name" title, round
+// headings and one match per line. Dates, comments and venues are ignored.
+func parseOpenFootballTXT(r io.Reader) (*competition, error) {
+	parsed := &competition{}
+	importErr := &ImportError{}
+	round := -1
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		// Venues follow an "@", comments a "#"
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if i := strings.Index(text, " @ "); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+
+		switch {
+		case text == "":
+			continue
+		case strings.HasPrefix(text, "="):
+			if parsed.name == "" {
+				parsed.name = strings.TrimSpace(strings.TrimLeft(text, "="))
+			}
+			continue
+		case strings.HasPrefix(text, "["), openFootballDate.MatchString(text):
+			continue
+		case openFootballRound.MatchString(text):
+			round++
+			continue
+		}
+
+		match := competitionMatch{line: line, round: max(round, 0)}
+		// A score after the teams is checked first, as its half-time score
+		// would otherwise be read as the away team
+		if m := openFootballScoreAfter.FindStringSubmatch(text); m != nil {
+			match.home, match.away = m[1], m[2]
+			match.played = true
+			match.homeScore, _ = strconv.Atoi(m[3])
+			match.awayScore, _ = strconv.Atoi(m[4])
+		} else if m := openFootballScoreBetween.FindStringSubmatch(text); m != nil {
+			match.home, match.away = m[1], m[4]
+			match.played = true
+			match.homeScore, _ = strconv.Atoi(m[2])
+			match.awayScore, _ = strconv.Atoi(m[3])
+		} else if m := openFootballUnplayed.FindStringSubmatch(text); m != nil {
+			match.home, match.away = m[1], m[2]
+		} else {
+			importErr.add(line, "", "cannot read %q", text)
+			continue
+		}
+
+		match.home, match.away = strings.TrimSpace(match.home), strings.TrimSpace(match.away)
+		parsed.matches = append(parsed.matches, match)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(importErr.Rows) > 0 {
+		return nil, importErr
+	}
+	if len(parsed.matches) == 0 {
+		return nil, errors.New("file has no matches")
+	}
+	return parsed, nil
+}
@@ -1,15 +1,43 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math"
 	"stadia-backend/models"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// PredictionService handles championship prediction calculations
+// PredictionService handles championship prediction calculations. Forecasts
+// of one league may run concurrently, as they only read the league.
 type PredictionService struct {
 	simulationService *SimulationService
+
+	// Guards the random source of simulationService during Monte Carlo runs
+	mu sync.Mutex
 }
 
+// PredictionResult holds championship probabilities along with how they were produced
+type PredictionResult struct {
+	Method        models.PredictionMethod
+	Probabilities map[string]float64 // Team ID -> Win probability
+	Runtime       time.Duration
+}
+
+// maxExactOutcomes bounds the number of win/draw/loss combinations the exact method enumerates
+const maxExactOutcomes = 531441 // 3^12, i.e. twelve remaining matches
+
+// ErrTooManyOutcomes is returned when the remaining state space is too large for exact enumeration
+var ErrTooManyOutcomes = errors.New("too many remaining outcomes for exact enumeration")
+
+// cancelCheckInterval is the number of simulations, or of tables the exact
+// solver evaluates, between checks whether the forecast was cancelled
+const cancelCheckInterval = 256
+
 // NewPredictionService creates a new prediction service
 func NewPredictionService() *PredictionService {
 	return &PredictionService{
@@ -17,18 +45,72 @@ func NewPredictionService() *PredictionService {
 	}
 }
 
+// Predict calculates championship probabilities with the requested method
+func (ps *PredictionService) Predict(
+	ctx context.Context,
+	method models.PredictionMethod,
+	teams []*models.Team,
+	fixtures [][]*models.Match,
+	currentWeek int,
+	totalWeeks int,
+) (result *PredictionResult, err error) {
+	ctx, span := startSpan(ctx, "PredictionService.Predict", attribute.String("prediction.method", string(method)))
+	defer func() {
+		if result != nil {
+			span.SetAttributes(attribute.String("prediction.method_used", string(result.Method)))
+		}
+		endSpan(span, err)
+	}()
+	start := time.Now()
+
+	var probabilities map[string]float64
+	switch method {
+	case models.MethodAuto:
+		// Exact when the remaining state space is small enough, Monte Carlo otherwise
+		probabilities, err = ps.CalculateExactPredictions(ctx, teams, fixtures)
+		if err == nil {
+			method = models.MethodExact
+			break
+		}
+		if !errors.Is(err, ErrTooManyOutcomes) {
+			return nil, err
+		}
+		method = models.MethodMonteCarlo
+		probabilities, err = ps.CalculatePredictions(ctx, teams, fixtures, currentWeek, totalWeeks)
+	case models.MethodMonteCarlo:
+		probabilities, err = ps.CalculatePredictions(ctx, teams, fixtures, currentWeek, totalWeeks)
+	case models.MethodHeuristic:
+		probabilities = ps.CalculateSimplePrediction(teams, fixtures)
+	case models.MethodExact:
+		probabilities, err = ps.CalculateExactPredictions(ctx, teams, fixtures)
+	default:
+		return nil, fmt.Errorf("unknown prediction method %q", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &PredictionResult{
+		Method:        method,
+		Probabilities: probabilities,
+		Runtime:       time.Since(start),
+	}, nil
+}
+
 // CalculatePredictions calculates championship probabilities for all teams
 // This uses Monte Carlo simulation to predict outcomes based on:
 // 1. Current points and standings
 // 2. Remaining matches
 // 3. Team strengths
 // 4. Historical performance in played matches
+// It stops with the context's error once ctx is done.
 func (ps *PredictionService) CalculatePredictions(
+	ctx context.Context,
 	teams []*models.Team,
 	fixtures [][]*models.Match,
 	currentWeek int,
 	totalWeeks int,
-) map[string]float64 {
+) (map[string]float64, error) {
 	predictions := make(map[string]float64)
 
 	// Initialize all teams with 0 probability
@@ -42,19 +124,29 @@ func (ps *PredictionService) CalculatePredictions(
 		if winner != nil {
 			predictions[winner.ID] = 1.0
 		}
-		return predictions
+		return predictions, nil
 	}
 
 	// Run Monte Carlo simulations
-	numSimulations := 10000
+	start := time.Now()
+	numSimulations := CurrentSimulationSettings().Simulations
+	_, span := startSpan(ctx, "PredictionService.CalculatePredictions", attribute.Int("prediction.simulations", numSimulations))
+	defer span.End()
 	wins := make(map[string]int)
 
 	for _, team := range teams {
 		wins[team.ID] = 0
 	}
 
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
 	for i := 0; i < numSimulations; i++ {
-		winner := ps.simulateRemainingMatches(teams, fixtures, currentWeek, totalWeeks)
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		winner := ps.simulateRemainingMatches(teams, fixtures)
 		if winner != nil {
 			wins[winner.ID]++
 		}
@@ -64,16 +156,15 @@ func (ps *PredictionService) CalculatePredictions(
 	for teamID, winCount := range wins {
 		predictions[teamID] = float64(winCount) / float64(numSimulations)
 	}
+	instrumentation.MonteCarloRun(MonteCarloPredictions, numSimulations, time.Since(start))
 
-	return predictions
+	return predictions, nil
 }
 
 // simulateRemainingMatches simulates all remaining matches and returns the winner
 func (ps *PredictionService) simulateRemainingMatches(
 	teams []*models.Team,
 	fixtures [][]*models.Match,
-	currentWeek int,
-	totalWeeks int,
 ) *models.Team {
 	// Create a deep copy of teams to avoid modifying original data
 	teamsCopy := make(map[string]*models.Team)
@@ -92,54 +183,23 @@ func (ps *PredictionService) simulateRemainingMatches(
 		}
 	}
 
-	// Simulate remaining matches
-	for week := currentWeek + 1; week <= totalWeeks; week++ {
-		weekMatches := fixtures[week-1]
-		for _, match := range weekMatches {
-			if match.IsPlayed() {
-				// Use actual result
-				homeTeam := teamsCopy[match.HomeTeamID]
-				awayTeam := teamsCopy[match.AwayTeamID]
-				homeTeam.UpdateStats(match.HomeScore, match.AwayScore)
-				awayTeam.UpdateStats(match.AwayScore, match.HomeScore)
-			} else {
-				// Simulate the match
-				homeTeam := teamsCopy[match.HomeTeamID]
-				awayTeam := teamsCopy[match.AwayTeamID]
-				homeScore, awayScore := ps.simulationService.SimulateMatch(homeTeam, awayTeam)
-				homeTeam.UpdateStats(homeScore, awayScore)
-				awayTeam.UpdateStats(awayScore, homeScore)
-			}
-		}
+	// Simulate remaining matches. Played matches are already part of the
+	// team statistics, wherever they sit in the fixture list.
+	for _, match := range remainingMatches(fixtures) {
+		homeTeam := teamsCopy[match.HomeTeamID]
+		awayTeam := teamsCopy[match.AwayTeamID]
+		homeScore, awayScore := ps.simulationService.SimulateMatch(homeTeam, awayTeam)
+		homeTeam.UpdateStats(homeScore, awayScore)
+		awayTeam.UpdateStats(awayScore, homeScore)
 	}
 
 	// Find the winner (team with most points)
-	var winner *models.Team
-	maxPoints := -1
 	teamsList := make([]*models.Team, 0, len(teamsCopy))
 	for _, team := range teamsCopy {
 		teamsList = append(teamsList, team)
 	}
 
-	// Sort teams to get the winner
-	for _, team := range teamsList {
-		if team.Points > maxPoints {
-			maxPoints = team.Points
-			winner = team
-		} else if team.Points == maxPoints && winner != nil {
-			// Tiebreaker: goal difference
-			if team.GoalDifference() > winner.GoalDifference() {
-				winner = team
-			} else if team.GoalDifference() == winner.GoalDifference() {
-				// Second tiebreaker: goals for
-				if team.GoalsFor > winner.GoalsFor {
-					winner = team
-				}
-			}
-		}
-	}
-
-	return winner
+	return ps.getLeagueLeader(teamsList)
 }
 
 // getLeagueLeader returns the current league leader
@@ -158,6 +218,8 @@ func (ps *PredictionService) getLeagueLeader(teams []*models.Team) *models.Team
 			} else if team.GoalDifference() == leader.GoalDifference() {
 				if team.GoalsFor > leader.GoalsFor {
 					leader = team
+				} else if team.GoalsFor == leader.GoalsFor && team.Name < leader.Name {
+					leader = team
 				}
 			}
 		}
@@ -166,51 +228,324 @@ func (ps *PredictionService) getLeagueLeader(teams []*models.Team) *models.Team
 	return leader
 }
 
-// CalculateSimplePrediction calculates a simpler prediction based on current form
-// This is a faster alternative to Monte Carlo simulation
-func (ps *PredictionService) CalculateSimplePrediction(
+// CalculateExactPredictions calculates championship probabilities by enumerating
+// every remaining match outcome, weighted by its model probability. Teams level
+// on points are separated by enumerating the scorelines of their remaining
+// matches, so goal difference and goals scored decide as in the standings.
+// Unlike Monte Carlo the result is deterministic for a given league state.
+func (ps *PredictionService) CalculateExactPredictions(
+	ctx context.Context,
 	teams []*models.Team,
-	currentWeek int,
-	totalWeeks int,
-) map[string]float64 {
+	fixtures [][]*models.Match,
+) (map[string]float64, error) {
+	positions, err := ps.CalculateExactPositions(ctx, teams, fixtures)
+	if err != nil {
+		return nil, err
+	}
+
 	predictions := make(map[string]float64)
-	totalProbability := 0.0
+	for teamID, probabilities := range positions {
+		predictions[teamID] = probabilities[0]
+	}
+	return predictions, nil
+}
 
-	// Calculate a score for each team based on:
-	// 1. Current points (60% weight)
-	// 2. Goal difference (20% weight)
-	// 3. Team power (20% weight)
-	scores := make(map[string]float64)
+// CalculateExactPositions returns, for every team, the exact probability of
+// finishing in each position (index 0 is first place)
+func (ps *PredictionService) CalculateExactPositions(
+	ctx context.Context,
+	teams []*models.Team,
+	fixtures [][]*models.Match,
+) (map[string][]float64, error) {
+	solver, err := newExactSolver(ctx, ps.simulationService, teams, fixtures)
+	if err != nil {
+		return nil, err
+	}
+	return solver.solve()
+}
+
+// PositionForecast holds finishing position probabilities for every team,
+// along with how they change with the outcome of each remaining match
+type PositionForecast struct {
+	Method    models.PredictionMethod
+	Positions map[string][]float64 // Team ID -> probability of each position, first place at index 0
+
+	// Matches lists the remaining matches. Outcomes[m] holds the model
+	// probabilities of a home win, draw and away win in Matches[m], and
+	// ByOutcome[m][o] the position probabilities given that outcome.
+	Matches   []*models.Match
+	Outcomes  [][3]float64
+	ByOutcome [][3]map[string][]float64
+}
+
+// CalculatePositionProbabilities returns the probability of each team finishing in
+// each position with the requested method, along with the method actually used.
+// The heuristic only estimates the winner, so it returns no positions.
+func (ps *PredictionService) CalculatePositionProbabilities(
+	ctx context.Context,
+	method models.PredictionMethod,
+	teams []*models.Team,
+	fixtures [][]*models.Match,
+) (map[string][]float64, models.PredictionMethod, error) {
+	switch method {
+	case models.MethodAuto:
+		forecast, err := ps.ForecastPositions(ctx, teams, fixtures)
+		if err != nil {
+			return nil, method, err
+		}
+		return forecast.Positions, forecast.Method, nil
+	case models.MethodMonteCarlo:
+		forecast, err := ps.simulatePositions(ctx, teams, fixtures, CurrentSimulationSettings().Simulations)
+		if err != nil {
+			return nil, method, err
+		}
+		return forecast.Positions, method, nil
+	case models.MethodExact:
+		positions, err := ps.CalculateExactPositions(ctx, teams, fixtures)
+		return positions, method, err
+	case models.MethodHeuristic:
+		return nil, method, nil
+	default:
+		return nil, method, fmt.Errorf("unknown prediction method %q", method)
+	}
+}
+
+// ForecastPositions calculates finishing position probabilities, exactly when the
+// remaining state space is small enough and by Monte Carlo simulation otherwise.
+// It stops with the context's error once ctx is done.
+func (ps *PredictionService) ForecastPositions(
+	ctx context.Context,
+	teams []*models.Team,
+	fixtures [][]*models.Match,
+) (*PositionForecast, error) {
+	solver, err := newExactSolver(ctx, ps.simulationService, teams, fixtures)
+	if err == nil {
+		solver.trackOutcomes = true
+		var positions map[string][]float64
+		positions, err = solver.solve()
+		if err == nil {
+			return exactForecast(teams, solver, positions), nil
+		}
+	}
+	if !errors.Is(err, ErrTooManyOutcomes) {
+		return nil, err
+	}
+
+	return ps.simulatePositions(ctx, teams, fixtures, CurrentSimulationSettings().Simulations)
+}
+
+// exactForecast builds a position forecast from a solved exact solver
+func exactForecast(teams []*models.Team, solver *exactSolver, positions map[string][]float64) *PositionForecast {
+	forecast := &PositionForecast{
+		Method:    models.MethodExact,
+		Positions: positions,
+		Matches:   solver.matches,
+		Outcomes:  solver.outcomes,
+		ByOutcome: make([][3]map[string][]float64, len(solver.matches)),
+	}
 
-	// remainingWeeks := totalWeeks - currentWeek
-	// maxPossiblePoints := float64(remainingWeeks * 3) // Max points from remaining matches
+	// Joint probabilities become conditional on the outcome
+	for m := range solver.matches {
+		for o := range solver.byOutcome[m] {
+			forecast.ByOutcome[m][o] = make(map[string][]float64)
+			for t, team := range teams {
+				conditional := make([]float64, len(teams))
+				if solver.outcomes[m][o] > 0 {
+					for position, p := range solver.byOutcome[m][o][t] {
+						conditional[position] = p / solver.outcomes[m][o]
+					}
+				}
+				forecast.ByOutcome[m][o][team.ID] = conditional
+			}
+		}
+	}
+
+	return forecast
+}
+
+// simulatePositions estimates a position forecast from Monte Carlo simulations
+func (ps *PredictionService) simulatePositions(
+	ctx context.Context,
+	teams []*models.Team,
+	fixtures [][]*models.Match,
+	numSimulations int,
+) (*PositionForecast, error) {
+	start := time.Now()
+	remaining := remainingMatches(fixtures)
 
+	forecast := &PositionForecast{
+		Method:    models.MethodMonteCarlo,
+		Positions: make(map[string][]float64),
+		Matches:   remaining,
+		Outcomes:  make([][3]float64, len(remaining)),
+		ByOutcome: make([][3]map[string][]float64, len(remaining)),
+	}
 	for _, team := range teams {
-		// Normalize current points (0-1)
-		currentPointsScore := float64(team.Points) / float64(totalWeeks*3)
+		forecast.Positions[team.ID] = make([]float64, len(teams))
+	}
 
-		// Normalize goal difference (-1 to 1, capped at ±10)
-		gdScore := math.Max(-10, math.Min(10, float64(team.GoalDifference()))) / 10.0
-		gdScore = (gdScore + 1) / 2 // Convert to 0-1 range
+	teamsByID := make(map[string]*models.Team)
+	for _, team := range teams {
+		teamsByID[team.ID] = team
+	}
+	counts := make([][3]int, len(remaining))
+	for m, match := range remaining {
+		homeWin, draw, awayWin := ps.simulationService.OutcomeProbabilities(
+			teamsByID[match.HomeTeamID],
+			teamsByID[match.AwayTeamID],
+		)
+		forecast.Outcomes[m] = [3]float64{homeWin, draw, awayWin}
+		for o := range forecast.ByOutcome[m] {
+			forecast.ByOutcome[m][o] = make(map[string][]float64)
+			for _, team := range teams {
+				forecast.ByOutcome[m][o][team.ID] = make([]float64, len(teams))
+			}
+		}
+	}
 
-		// Normalize team power (0-1)
-		powerScore := float64(team.Power) / 100.0
+	results := make([]int, len(remaining))
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for i := 0; i < numSimulations; i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
 
-		// Combine scores with weights
-		score := currentPointsScore*0.6 + gdScore*0.2 + powerScore*0.2
+		// Copy the teams so the simulation does not modify the originals
+		table := make([]*models.Team, len(teams))
+		copies := make(map[string]*models.Team)
+		for t, team := range teams {
+			teamCopy := *team
+			table[t] = &teamCopy
+			copies[team.ID] = &teamCopy
+		}
 
-		// Adjust for remaining matches - more weight on current position if fewer matches remain
-		matchesPlayedRatio := float64(currentWeek) / float64(totalWeeks)
-		score = score * (0.5 + 0.5*matchesPlayedRatio)
+		for m, match := range remaining {
+			homeTeam := copies[match.HomeTeamID]
+			awayTeam := copies[match.AwayTeamID]
+			homeScore, awayScore := ps.simulationService.SimulateMatch(homeTeam, awayTeam)
+			homeTeam.UpdateStats(homeScore, awayScore)
+			awayTeam.UpdateStats(awayScore, homeScore)
+			results[m] = matchOutcome(homeScore, awayScore)
+			counts[m][results[m]]++
+		}
 
-		scores[team.ID] = math.Max(0.01, score) // Minimum score to avoid division by zero
-		totalProbability += scores[team.ID]
+		sortStandings(table)
+		for position, team := range table {
+			forecast.Positions[team.ID][position]++
+			for m, outcome := range results {
+				forecast.ByOutcome[m][outcome][team.ID][position]++
+			}
+		}
+	}
+
+	// Turn the counts into probabilities
+	for _, positions := range forecast.Positions {
+		for position := range positions {
+			positions[position] /= float64(numSimulations)
+		}
+	}
+	for m := range remaining {
+		for o, byTeam := range forecast.ByOutcome[m] {
+			if counts[m][o] == 0 {
+				continue
+			}
+			for _, positions := range byTeam {
+				for position := range positions {
+					positions[position] /= float64(counts[m][o])
+				}
+			}
+		}
+	}
+	instrumentation.MonteCarloRun(MonteCarloPositions, numSimulations, time.Since(start))
+
+	return forecast, nil
+}
+
+// CalculateSimplePrediction calculates a simpler prediction based on expected points
+// This is a faster alternative to Monte Carlo simulation: each team's remaining
+// matches contribute their expected points, and the projected totals are turned
+// into probabilities with a softmax whose spread grows with the matches left.
+// Teams that can no longer reach the leader's current points get 0.
+func (ps *PredictionService) CalculateSimplePrediction(
+	teams []*models.Team,
+	fixtures [][]*models.Match,
+) map[string]float64 {
+	predictions := make(map[string]float64)
+	for _, team := range teams {
+		predictions[team.ID] = 0.0
+	}
+
+	remaining := remainingMatches(fixtures)
+	if len(remaining) == 0 {
+		winner := ps.getLeagueLeader(teams)
+		if winner != nil {
+			predictions[winner.ID] = 1.0
+		}
+		return predictions
+	}
+
+	teamsByID := make(map[string]*models.Team)
+	projected := make(map[string]float64)
+	maxPoints := make(map[string]int)
+	leaderPoints := 0
+	for _, team := range teams {
+		teamsByID[team.ID] = team
+		projected[team.ID] = float64(team.Points)
+		maxPoints[team.ID] = team.Points
+		leaderPoints = max(leaderPoints, team.Points)
+	}
+
+	// Add the expected points of every remaining match
+	for _, match := range remaining {
+		homeWin, draw, awayWin := ps.simulationService.OutcomeProbabilities(
+			teamsByID[match.HomeTeamID],
+			teamsByID[match.AwayTeamID],
+		)
+		projected[match.HomeTeamID] += 3*homeWin + draw
+		projected[match.AwayTeamID] += 3*awayWin + draw
+		maxPoints[match.HomeTeamID] += 3
+		maxPoints[match.AwayTeamID] += 3
+	}
+
+	// A single match has a standard deviation of roughly 1.2 points
+	matchesPerTeam := 2 * float64(len(remaining)) / float64(len(teams))
+	spread := 1.2 * math.Sqrt(math.Max(1, matchesPerTeam))
+
+	bestProjection := math.Inf(-1)
+	for _, points := range projected {
+		bestProjection = math.Max(bestProjection, points)
+	}
+
+	totalScore := 0.0
+	scores := make(map[string]float64)
+	for _, team := range teams {
+		if maxPoints[team.ID] < leaderPoints {
+			continue // Cannot catch the leader anymore
+		}
+		scores[team.ID] = math.Exp((projected[team.ID] - bestProjection) / spread)
+		totalScore += scores[team.ID]
 	}
 
 	// Normalize to probabilities (sum to 1)
 	for teamID, score := range scores {
-		predictions[teamID] = score / totalProbability
+		predictions[teamID] = score / totalScore
 	}
 
 	return predictions
 }
+
+// remainingMatches returns every match in the fixtures that has not been played yet
+func remainingMatches(fixtures [][]*models.Match) []*models.Match {
+	remaining := make([]*models.Match, 0)
+	for _, weekMatches := range fixtures {
+		for _, match := range weekMatches {
+			if !match.IsPlayed() {
+				remaining = append(remaining, match)
+			}
+		}
+	}
+	return remaining
+}
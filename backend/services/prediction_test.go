@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"stadia-backend/models"
+	"testing"
+)
+
+// newTestLeague creates a four-team league with the given number of weeks played.
+// Results are fixed so that every test sees the same league state.
+func newTestLeague(weeksPlayed int) ([]*models.Team, [][]*models.Match) {
+	teams := []*models.Team{
+		models.NewTeam("Team A", 85, ""),
+		models.NewTeam("Team B", 80, ""),
+		models.NewTeam("Team C", 70, ""),
+		models.NewTeam("Team D", 60, ""),
+	}
+	fixtures := NewFixtureService().GenerateFixturesOptimized(teams)
+
+	teamsByID := make(map[string]*models.Team)
+	for _, team := range teams {
+		teamsByID[team.ID] = team
+	}
+
+	scores := [][2]int{{2, 1}, {1, 1}, {0, 2}, {3, 0}, {1, 0}, {2, 2}, {0, 1}, {1, 3}}
+	for week := 0; week < weeksPlayed; week++ {
+		for i, match := range fixtures[week] {
+			score := scores[(week*2+i)%len(scores)]
+			match.SetResult(score[0], score[1])
+			teamsByID[match.HomeTeamID].UpdateStats(score[0], score[1])
+			teamsByID[match.AwayTeamID].UpdateStats(score[1], score[0])
+		}
+	}
+
+	return teams, fixtures
+}
+
+func assertSumsToOne(t *testing.T, method models.PredictionMethod, predictions map[string]float64) {
+	t.Helper()
+	total := 0.0
+	for _, p := range predictions {
+		if p < 0 || p > 1 {
+			t.Errorf("%s: probability out of range: %.4f", method, p)
+		}
+		total += p
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("%s: probabilities sum to %.6f, expected 1", method, total)
+	}
+}
+
+func TestPredictMethodsAgree(t *testing.T) {
+	service := NewPredictionService()
+	teams, fixtures := newTestLeague(4)
+
+	results := make(map[models.PredictionMethod]*PredictionResult)
+	for _, method := range []models.PredictionMethod{
+		models.MethodMonteCarlo,
+		models.MethodHeuristic,
+		models.MethodExact,
+	} {
+		result, err := service.Predict(context.Background(), method, teams, fixtures, 4, len(fixtures))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", method, err)
+		}
+		if result.Method != method {
+			t.Errorf("Expected method %s, got %s", method, result.Method)
+		}
+		assertSumsToOne(t, method, result.Probabilities)
+		results[method] = result
+		t.Logf("%s took %v", method, result.Runtime)
+	}
+
+	exact := results[models.MethodExact].Probabilities
+	for _, team := range teams {
+		// Allow for sampling error and the exact solver's goal cap
+		mc := results[models.MethodMonteCarlo].Probabilities[team.ID]
+		if math.Abs(mc-exact[team.ID]) > 0.03 {
+			t.Errorf("%s: Monte Carlo %.3f too far from exact %.3f", team.Name, mc, exact[team.ID])
+		}
+
+		heuristic := results[models.MethodHeuristic].Probabilities[team.ID]
+		if math.Abs(heuristic-exact[team.ID]) > 0.25 {
+			t.Errorf("%s: heuristic %.3f too far from exact %.3f", team.Name, heuristic, exact[team.ID])
+		}
+
+		// The heuristic must not rule out a team that still has a real chance
+		if exact[team.ID] > 0.05 && heuristic == 0 {
+			t.Errorf("%s: heuristic rules out a team with %.3f exact probability", team.Name, exact[team.ID])
+		}
+	}
+}
+
+func TestPredictFinishedLeague(t *testing.T) {
+	service := NewPredictionService()
+	teams, fixtures := newTestLeague(6)
+
+	for _, method := range []models.PredictionMethod{
+		models.MethodMonteCarlo,
+		models.MethodHeuristic,
+		models.MethodExact,
+	} {
+		result, err := service.Predict(context.Background(), method, teams, fixtures, 6, 6)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", method, err)
+		}
+
+		leader := service.getLeagueLeader(teams)
+		if result.Probabilities[leader.ID] != 1.0 {
+			t.Errorf("%s: leader should have probability 1, got %.3f", method, result.Probabilities[leader.ID])
+		}
+	}
+}
+
+func TestPredictUnknownMethod(t *testing.T) {
+	service := NewPredictionService()
+	teams, fixtures := newTestLeague(4)
+
+	if _, err := service.Predict(context.Background(), "coin_flip", teams, fixtures, 4, 6); err == nil {
+		t.Error("Expected error for unknown prediction method")
+	}
+}
+
+func TestPredictStopsWhenCancelled(t *testing.T) {
+	service := NewPredictionService()
+	teams, fixtures := newTestLeague(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, method := range []models.PredictionMethod{models.MethodMonteCarlo, models.MethodExact, models.MethodAuto} {
+		if _, err := service.Predict(ctx, method, teams, fixtures, 2, 6); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected the cancelled prediction to stop, got %v", method, err)
+		}
+	}
+	if _, err := service.ForecastPositions(ctx, teams, fixtures); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled forecast to stop, got %v", err)
+	}
+}
+
+func TestExactPredictionsTooManyMatches(t *testing.T) {
+	service := NewPredictionService()
+	teams, fixtures := newTestLeague(0)
+
+	// A whole season of ties is far beyond the exact solver's budget
+	if _, err := service.CalculateExactPredictions(context.Background(), teams, fixtures); err != ErrTooManyOutcomes {
+		t.Errorf("Expected ErrTooManyOutcomes for a full season, got %v", err)
+	}
+
+	extra := make([]*models.Match, 0)
+	for i := 0; i < 13; i++ {
+		extra = append(extra, models.NewMatch(teams[0].ID, teams[1].ID, teams[0].Name, teams[1].Name, 1))
+	}
+	if _, err := service.CalculateExactPredictions(context.Background(), teams, [][]*models.Match{extra}); err != ErrTooManyOutcomes {
+		t.Errorf("Expected ErrTooManyOutcomes for 13 remaining matches, got %v", err)
+	}
+}
+
+func TestExactPredictionsDeterministic(t *testing.T) {
+	service := NewPredictionService()
+	teams, fixtures := newTestLeague(5)
+
+	first, err := service.CalculateExactPredictions(context.Background(), teams, fixtures)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, _ := service.CalculateExactPredictions(context.Background(), teams, fixtures)
+
+	for teamID, probability := range first {
+		if second[teamID] != probability {
+			t.Errorf("Exact predictions differ between runs: %.6f vs %.6f", probability, second[teamID])
+		}
+	}
+}
+
+func TestExactPositionsBreakTiesOnGoalDifference(t *testing.T) {
+	service := NewPredictionService()
+
+	teamA := models.NewTeam("Team A", 70, "")
+	teamB := models.NewTeam("Team B", 70, "")
+	teamA.UpdateStats(5, 0)
+	teamB.UpdateStats(1, 0)
+
+	// Both on 3 points, A far ahead on goal difference, one match between them left
+	fixtures := [][]*models.Match{{models.NewMatch(teamB.ID, teamA.ID, teamB.Name, teamA.Name, 2)}}
+	positions, err := service.CalculateExactPositions(context.Background(), []*models.Team{teamA, teamB}, fixtures)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	homeWin, draw, _ := service.simulationService.OutcomeProbabilities(teamB, teamA)
+	// A only loses the title if B wins by five or more
+	if positions[teamA.ID][0] <= 1-homeWin {
+		t.Errorf("Team A should keep the title after most B wins: got %.3f", positions[teamA.ID][0])
+	}
+	if positions[teamA.ID][0] < draw {
+		t.Errorf("Team A wins the title on a draw: got %.3f, draw %.3f", positions[teamA.ID][0], draw)
+	}
+	for _, team := range []*models.Team{teamA, teamB} {
+		if math.Abs(positions[team.ID][0]+positions[team.ID][1]-1) > 1e-9 {
+			t.Errorf("%s: position probabilities do not sum to 1", team.Name)
+		}
+	}
+}
+
+func TestPredictAuto(t *testing.T) {
+	service := NewPredictionService()
+
+	teams, fixtures := newTestLeague(4)
+	result, err := service.Predict(context.Background(), models.MethodAuto, teams, fixtures, 4, 6)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Method != models.MethodExact {
+		t.Errorf("Expected exact method with two weeks left, got %s", result.Method)
+	}
+
+	teams, fixtures = newTestLeague(0)
+	result, err = service.Predict(context.Background(), models.MethodAuto, teams, fixtures, 0, 6)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Method != models.MethodMonteCarlo {
+		t.Errorf("Expected Monte Carlo fallback for a full season, got %s", result.Method)
+	}
+	assertSumsToOne(t, result.Method, result.Probabilities)
+}
+
+func TestOutcomeProbabilities(t *testing.T) {
+	service := NewSimulationService()
+
+	strong := models.NewTeam("Strong Team", 90, "")
+	weak := models.NewTeam("Weak Team", 30, "")
+
+	homeWin, draw, awayWin := service.OutcomeProbabilities(strong, weak)
+	if math.Abs(homeWin+draw+awayWin-1) > 1e-9 {
+		t.Errorf("Outcome probabilities sum to %.6f, expected 1", homeWin+draw+awayWin)
+	}
+	if homeWin <= awayWin {
+		t.Errorf("Strong home team should be favourite: home %.3f, away %.3f", homeWin, awayWin)
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitSweepInterval is how often clients that have been idle long
+// enough to refill their bucket are dropped
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitSettings configures a token bucket per client
+type RateLimitSettings struct {
+	Rate  float64 // Tokens added per second
+	Burst int     // Bucket size: requests a client may make at once
+}
+
+// rateLimitClient is the bucket of one client
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter limits how often each client may make requests with a token
+// bucket per client
+type RateLimiter struct {
+	settings  RateLimitSettings
+	mu        sync.Mutex
+	clients   map[string]*rateLimitClient
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a rate limiter
+func NewRateLimiter(settings RateLimitSettings) *RateLimiter {
+	settings.Burst = max(settings.Burst, 1)
+	return &RateLimiter{
+		settings:  settings,
+		clients:   make(map[string]*rateLimitClient),
+		lastSweep: time.Now(),
+	}
+}
+
+// SetSettings changes the rate and burst of every client, keeping the tokens
+// left in their buckets
+func (l *RateLimiter) SetSettings(settings RateLimitSettings) {
+	settings.Burst = max(settings.Burst, 1)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings = settings
+	for _, c := range l.clients {
+		c.limiter.SetLimitAt(now, rate.Limit(settings.Rate))
+		c.limiter.SetBurstAt(now, settings.Burst)
+	}
+}
+
+// Allow takes a token from a client's bucket. When the bucket is empty it
+// returns false and how long until the next token.
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &rateLimitClient{limiter: rate.NewLimiter(rate.Limit(l.settings.Rate), l.settings.Burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+
+	reservation := c.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, rateLimitSweepInterval
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep drops clients whose bucket has refilled since they were last seen,
+// as a new bucket would be the same. The caller must hold the lock.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := rateLimitSweepInterval
+	if l.settings.Rate > 0 {
+		refill = max(refill, time.Duration(float64(l.settings.Burst)/l.settings.Rate*float64(time.Second)))
+	}
+	for key, c := range l.clients {
+		if now.Sub(c.lastSeen) > refill {
+			delete(l.clients, key)
+		}
+	}
+}
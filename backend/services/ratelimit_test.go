@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurstAndRetry(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitSettings{Rate: 1, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("Expected request %d of the burst to be allowed", i+1)
+		}
+	}
+	ok, retryAfter := limiter.Allow("a")
+	if ok {
+		t.Fatal("Expected the request after the burst to be limited")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected to retry within a second, got %s", retryAfter)
+	}
+
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Error("Expected another client to have its own bucket")
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitSettings{Rate: 100, Burst: 1})
+
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	if ok, _ := limiter.Allow("a"); ok {
+		t.Fatal("Expected the second request to be limited")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Error("Expected the bucket to refill")
+	}
+}
+
+func TestRateLimiterSetSettings(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitSettings{Rate: 0.001, Burst: 1})
+
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	limiter.SetSettings(RateLimitSettings{Rate: 100, Burst: 1})
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Error("Expected an existing bucket to refill at the new rate")
+	}
+
+	limiter.SetSettings(RateLimitSettings{Rate: 0.001, Burst: 2})
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("b"); !ok {
+			t.Fatalf("Expected request %d of the new burst to be allowed", i+1)
+		}
+	}
+	if ok, _ := limiter.Allow("b"); ok {
+		t.Error("Expected the request after the new burst to be limited")
+	}
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"stadia-backend/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultLeagueID is the league served under /api/league
+const DefaultLeagueID = "default"
+
+var (
+	// ErrLeagueNotFound is returned for an unknown league ID
+	ErrLeagueNotFound = errors.New("league not found")
+
+	// ErrShareNotFound is returned for an unknown or revoked sharing link
+	ErrShareNotFound = errors.New("sharing link not found")
+
+	// ErrDefaultLeague is returned when deleting the default league
+	ErrDefaultLeague = errors.New("the default league cannot be deleted")
+)
+
+// registeredLeague is a league with its ownership and sharing links
+type registeredLeague struct {
+	info    models.LeagueInfo
+	service *LeagueService
+	shares  map[string]*models.ShareLink // By token
+
+	// Held while the service is used: exclusively to change the league,
+	// shared to read or copy it. mu only guards the registry itself.
+	lock sync.RWMutex
+}
+
+// LeagueRegistry holds every league, who owns it and who it is shared with.
+// The default league always exists.
+type LeagueRegistry struct {
+	mu       sync.RWMutex
+	leagues  map[string]*registeredLeague
+	maxTeams int // Team limit of every league; unlimited when 0
+}
+
+// NewLeagueRegistry creates a registry holding only the default league. Its
+// leagues may have at most maxTeams teams, or any number when 0.
+func NewLeagueRegistry(maxTeams int) *LeagueRegistry {
+	r := &LeagueRegistry{leagues: make(map[string]*registeredLeague), maxTeams: maxTeams}
+	r.leagues[DefaultLeagueID] = r.newRegisteredLeague(DefaultLeagueID, "Default League", "")
+	return r
+}
+
+// newRegisteredLeague creates an empty league
+func (r *LeagueRegistry) newRegisteredLeague(id, name, ownerID string) *registeredLeague {
+	service := NewLeagueService()
+	service.SetMaxTeams(r.maxTeams)
+	return &registeredLeague{
+		info: models.LeagueInfo{
+			ID:        id,
+			Name:      name,
+			OwnerID:   ownerID,
+			CreatedAt: time.Now().UTC(),
+		},
+		service: service,
+		shares:  make(map[string]*models.ShareLink),
+	}
+}
+
+// Create adds an empty league owned by a user
+func (r *LeagueRegistry) Create(ownerID, name string) (*models.LeagueInfo, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("league name is required")
+	}
+
+	league := r.newRegisteredLeague(uuid.New().String(), name, ownerID)
+	r.mu.Lock()
+	r.leagues[league.info.ID] = league
+	r.mu.Unlock()
+
+	info := league.info
+	return &info, nil
+}
+
+// Import creates a league owned by a user from a snapshot, as for
+// LeagueService.ImportSnapshot. Without a name it keeps the snapshot's.
+func (r *LeagueRegistry) Import(ownerID, name string, snapshot io.Reader) (*models.LeagueInfo, error) {
+	league := r.newRegisteredLeague(uuid.New().String(), strings.TrimSpace(name), ownerID)
+	if _, err := league.service.ImportSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	if league.info.Name == "" {
+		league.info.Name = strings.TrimSpace(league.service.GetLeague().Name)
+	}
+	if league.info.Name == "" {
+		return nil, errors.New("league name is required")
+	}
+
+	r.mu.Lock()
+	r.leagues[league.info.ID] = league
+	r.mu.Unlock()
+
+	info := league.info
+	return &info, nil
+}
+
+// Get returns a league's service and ownership
+func (r *LeagueRegistry) Get(id string) (*LeagueService, *models.LeagueInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	league, ok := r.leagues[id]
+	if !ok {
+		return nil, nil, ErrLeagueNotFound
+	}
+	info := league.info
+	return league.service, &info, nil
+}
+
+// LeagueLock returns the lock callers hold while they use a league's
+// service, as LeagueService is not safe for concurrent use. Take it
+// exclusively to change the league, and shared to read or copy it.
+func (r *LeagueRegistry) LeagueLock(id string) (*sync.RWMutex, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	league, ok := r.leagues[id]
+	if !ok {
+		return nil, ErrLeagueNotFound
+	}
+	return &league.lock, nil
+}
+
+// Default returns the service of the default league
+func (r *LeagueRegistry) Default() *LeagueService {
+	service, _, _ := r.Get(DefaultLeagueID)
+	return service
+}
+
+// List returns the leagues owned by a user, or every league when ownerID is
+// empty, oldest first
+func (r *LeagueRegistry) List(ownerID string) []*models.LeagueInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	leagues := make([]*models.LeagueInfo, 0, len(r.leagues))
+	for _, league := range r.leagues {
+		if ownerID != "" && league.info.OwnerID != ownerID {
+			continue
+		}
+		info := league.info
+		leagues = append(leagues, &info)
+	}
+	sortByCreation(leagues, func(info *models.LeagueInfo) time.Time { return info.CreatedAt })
+	return leagues
+}
+
+// Count returns the number of leagues, including the default league
+func (r *LeagueRegistry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.leagues)
+}
+
+// Delete removes a league with its sharing links
+func (r *LeagueRegistry) Delete(id string) error {
+	if id == DefaultLeagueID {
+		return ErrDefaultLeague
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.leagues[id]; !ok {
+		return ErrLeagueNotFound
+	}
+	delete(r.leagues, id)
+	return nil
+}
+
+// ClaimUnowned makes a user the owner of every league nobody owns yet. The
+// admin account of AuthSettings claims the default league this way.
+func (r *LeagueRegistry) ClaimUnowned(ownerID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, league := range r.leagues {
+		if league.info.OwnerID == "" {
+			league.info.OwnerID = ownerID
+		}
+	}
+}
+
+// ApplyPresets gives every league using one of the model presets of settings
+// the preset's current parameters, and recalculates its predictions. Leagues
+// on a preset that no longer exists keep their model. It returns the IDs of
+// the leagues whose model changed.
+func (r *LeagueRegistry) ApplyPresets(ctx context.Context, settings SimulationSettings) ([]string, error) {
+	r.mu.RLock()
+	leagues := make([]*registeredLeague, 0, len(r.leagues))
+	for _, league := range r.leagues {
+		leagues = append(leagues, league)
+	}
+	r.mu.RUnlock()
+
+	var changed []string
+	var errs []error
+	for _, league := range leagues {
+		league.lock.Lock()
+		current := league.service.GetLeague().Settings.Model
+		params, err := settings.Preset(current.Preset)
+		if current.Preset != "" && err == nil && params != current {
+			if err := league.service.SetModel(ctx, params); err != nil {
+				errs = append(errs, fmt.Errorf("league %s: %w", league.info.ID, err))
+			} else {
+				changed = append(changed, league.info.ID)
+			}
+		}
+		league.lock.Unlock()
+	}
+	sort.Strings(changed)
+	return changed, errors.Join(errs...)
+}
+
+// Role returns what a user, or the holder of a sharing token, may do with a
+// league: owner, viewer, or nothing when the role is empty
+func (r *LeagueRegistry) Role(id, userID, shareToken string) (models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	league, ok := r.leagues[id]
+	if !ok {
+		return "", ErrLeagueNotFound
+	}
+	switch {
+	case userID != "" && league.info.OwnerID == userID:
+		return models.RoleOwner, nil
+	case shareToken != "" && league.shares[shareToken] != nil:
+		return models.RoleViewer, nil
+	default:
+		return "", nil
+	}
+}
+
+// CreateShare creates a read-only sharing link for a league
+func (r *LeagueRegistry) CreateShare(id string) (*models.ShareLink, error) {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	league, ok := r.leagues[id]
+	if !ok {
+		return nil, ErrLeagueNotFound
+	}
+	share := &models.ShareLink{
+		Token:     hex.EncodeToString(token),
+		LeagueID:  id,
+		CreatedAt: time.Now().UTC(),
+	}
+	league.shares[share.Token] = share
+
+	copied := *share
+	return &copied, nil
+}
+
+// Shares returns the sharing links of a league, oldest first
+func (r *LeagueRegistry) Shares(id string) ([]*models.ShareLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	league, ok := r.leagues[id]
+	if !ok {
+		return nil, ErrLeagueNotFound
+	}
+	return league.shareList(), nil
+}
+
+// shareList returns copies of the sharing links, oldest first. The caller must hold the lock.
+func (league *registeredLeague) shareList() []*models.ShareLink {
+	shares := make([]*models.ShareLink, 0, len(league.shares))
+	for _, share := range league.shares {
+		copied := *share
+		shares = append(shares, &copied)
+	}
+	sortByCreation(shares, func(share *models.ShareLink) time.Time { return share.CreatedAt })
+	return shares
+}
+
+// RevokeShare deletes a sharing link, so its token no longer gives access
+func (r *LeagueRegistry) RevokeShare(id, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	league, ok := r.leagues[id]
+	if !ok {
+		return ErrLeagueNotFound
+	}
+	if league.shares[token] == nil {
+		return ErrShareNotFound
+	}
+	delete(league.shares, token)
+	return nil
+}
+
+// sortByCreation sorts items oldest first
+func sortByCreation[T any](items []T, createdAt func(T) time.Time) {
+	sort.SliceStable(items, func(i, j int) bool { return createdAt(items[i]).Before(createdAt(items[j])) })
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"stadia-backend/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistryRoles(t *testing.T) {
+	registry := NewLeagueRegistry(0)
+	info, err := registry.Create("alice", "Friday League")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	share, err := registry.CreateShare(info.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name, userID, token string
+		want                models.Role
+	}{
+		{"owner", "alice", "", models.RoleOwner},
+		{"other user", "bob", "", ""},
+		{"anonymous", "", "", ""},
+		{"share holder", "", share.Token, models.RoleViewer},
+		{"other user with share", "bob", share.Token, models.RoleViewer},
+		{"wrong token", "", "nope", ""},
+	}
+	for _, test := range tests {
+		if role, _ := registry.Role(info.ID, test.userID, test.token); role != test.want {
+			t.Errorf("%s: expected role %q, got %q", test.name, test.want, role)
+		}
+	}
+
+	if err := registry.RevokeShare(info.ID, share.Token); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if role, _ := registry.Role(info.ID, "", share.Token); role != "" {
+		t.Errorf("Expected a revoked share to give no access, got %q", role)
+	}
+	if _, err := registry.Role("missing", "alice", ""); !errors.Is(err, ErrLeagueNotFound) {
+		t.Errorf("Expected league not found, got %v", err)
+	}
+}
+
+func TestRegistryLeagues(t *testing.T) {
+	registry := NewLeagueRegistry(0)
+	first, _ := registry.Create("alice", "First")
+	registry.Create("bob", "Second")
+	if _, err := registry.Create("alice", "  "); err == nil {
+		t.Error("Expected a league without a name to be rejected")
+	}
+
+	if owned := registry.List("alice"); len(owned) != 1 || owned[0].ID != first.ID {
+		t.Errorf("Expected alice to own only the first league, got %+v", owned)
+	}
+	if all := registry.List(""); len(all) != 3 {
+		t.Errorf("Expected the default and 2 created leagues, got %d", len(all))
+	}
+
+	registry.ClaimUnowned("alice")
+	if role, _ := registry.Role(DefaultLeagueID, "alice", ""); role != models.RoleOwner {
+		t.Errorf("Expected alice to claim the default league, got %q", role)
+	}
+
+	if err := registry.Delete(DefaultLeagueID); !errors.Is(err, ErrDefaultLeague) {
+		t.Errorf("Expected the default league to be kept, got %v", err)
+	}
+	if err := registry.Delete(first.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, _, err := registry.Get(first.ID); !errors.Is(err, ErrLeagueNotFound) {
+		t.Errorf("Expected the deleted league to be gone, got %v", err)
+	}
+}
+
+func TestRegistryStateFile(t *testing.T) {
+	registry := NewLeagueRegistry(0)
+	registry.leagues[DefaultLeagueID].service = newPlayedLeagueService(t, 2)
+	info, _ := registry.Create("alice", "Empty")
+	share, _ := registry.CreateShare(info.ID)
+	accounts := models.Accounts{Users: []*models.UserRecord{{
+		User:         models.User{ID: "alice", Username: "alice", CreatedAt: time.Now().UTC()},
+		PasswordHash: "hash",
+	}}}
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	if err := registry.SaveStateFile(path, "1.2.3", accounts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	restored := NewLeagueRegistry(0)
+	restoredAccounts, err := restored.LoadStateFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(restoredAccounts.Users) != 1 || restoredAccounts.Users[0].PasswordHash != "hash" {
+		t.Errorf("Expected the user to be restored, got %+v", restoredAccounts.Users)
+	}
+	if restored.Default().GetLeague().CurrentWeek != 2 {
+		t.Error("Expected the default league to be restored")
+	}
+	if role, _ := restored.Role(info.ID, "", share.Token); role != models.RoleViewer {
+		t.Errorf("Expected the sharing link to be restored, got role %q", role)
+	}
+	if role, _ := restored.Role(info.ID, "alice", ""); role != models.RoleOwner {
+		t.Errorf("Expected the owner to be restored, got role %q", role)
+	}
+}
+
+func TestRegistryLoadsLeagueSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "league.json")
+	if err := newPlayedLeagueService(t, 3).SaveSnapshotFile(path, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	registry := NewLeagueRegistry(0)
+	accounts, err := registry.LoadStateFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(accounts.Users) != 0 || registry.Default().GetLeague().CurrentWeek != 3 {
+		t.Error("Expected a league snapshot file to be restored as the default league")
+	}
+}
+
+func TestRegistryMaxTeams(t *testing.T) {
+	league := NewLeagueRegistry(3).Default()
+	teams := []*models.Team{
+		models.NewTeam("Team A", 85, ""),
+		models.NewTeam("Team B", 80, ""),
+		models.NewTeam("Team C", 70, ""),
+		models.NewTeam("Team D", 60, ""),
+	}
+
+	if err := league.InitializeLeague(teams); err == nil {
+		t.Error("Expected more teams than the limit to be rejected")
+	}
+	if _, err := league.ImportSnapshot(bytes.NewReader(encodeSnapshot(t, newPlayedLeagueService(t, 1)))); err == nil {
+		t.Error("Expected a snapshot with more teams than the limit to be rejected")
+	}
+	if err := league.InitializeLeague(teams[:3]); err != nil {
+		t.Errorf("Expected a league at the limit to be allowed, got %v", err)
+	}
+}
+
+func TestRegistryLeagueLock(t *testing.T) {
+	registry := NewLeagueRegistry(0)
+	if err := registry.Default().InitializeLeague([]*models.Team{
+		models.NewTeam("Team A", 85, ""),
+		models.NewTeam("Team B", 80, ""),
+		models.NewTeam("Team C", 70, ""),
+		models.NewTeam("Team D", 60, ""),
+	}); err != nil {
+		t.Fatalf("Failed to initialize league: %v", err)
+	}
+	lock, err := registry.LeagueLock(DefaultLeagueID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := registry.LeagueLock("missing"); err != ErrLeagueNotFound {
+		t.Errorf("Expected ErrLeagueNotFound, got %v", err)
+	}
+
+	// Run with -race: writers hold the lock exclusively, readers share it
+	// while forecasting with the league's random source
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			lock.Lock()
+			defer lock.Unlock()
+			registry.Default().SaveScenario("Draw", nil)
+		}()
+		go func() {
+			defer wg.Done()
+			lock.RLock()
+			defer lock.RUnlock()
+			registry.Default().CalculatePredictions(context.Background(), models.MethodMonteCarlo)
+		}()
+		go func() {
+			defer wg.Done()
+			registry.Records()
+		}()
+		go func() {
+			// As the admin routes do: league first, then the registry
+			defer wg.Done()
+			lock.Lock()
+			defer lock.Unlock()
+			if _, err := registry.CreateShare(DefaultLeagueID); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(registry.Default().GetScenarios()); n != 4 {
+		t.Errorf("Expected 4 saved scenarios, got %d", n)
+	}
+	if shares, _ := registry.Shares(DefaultLeagueID); len(shares) != 4 {
+		t.Errorf("Expected 4 sharing links, got %d", len(shares))
+	}
+}
+
+func TestRegistryApplyPresets(t *testing.T) {
+	registry := NewLeagueRegistry(0)
+	registry.leagues[DefaultLeagueID].service = newPlayedLeagueService(t, 2)
+	settings := DefaultSimulationSettings
+	chaotic, _ := settings.Preset(models.PresetChaotic)
+	custom := chaotic
+	custom.Preset = ""
+	for name, model := range map[string]models.ModelParams{"Chaotic": chaotic, "Custom": custom} {
+		info, _ := registry.Create("alice", name)
+		service, _, _ := registry.Get(info.ID)
+		if err := service.SetModel(context.Background(), model); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	settings.Presets = models.BuiltinModelPresets()
+	realistic := settings.Presets[models.PresetRealistic]
+	realistic.HomeAdvantage = 25
+	settings.Presets[models.PresetRealistic] = realistic
+	changed, err := registry.ApplyPresets(context.Background(), settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != DefaultLeagueID {
+		t.Errorf("Expected only the default league to change, got %v", changed)
+	}
+	if model := registry.Default().GetLeague().Settings.Model; model != realistic {
+		t.Errorf("Expected the changed preset to be applied, got %+v", model)
+	}
+	for _, info := range registry.List("alice") {
+		service, _, _ := registry.Get(info.ID)
+		if model := service.GetLeague().Settings.Model; model.HomeAdvantage != chaotic.HomeAdvantage {
+			t.Errorf("Expected league %q to keep its model, got %+v", info.Name, model)
+		}
+	}
+
+	if changed, _ := registry.ApplyPresets(context.Background(), settings); len(changed) != 0 {
+		t.Errorf("Expected unchanged presets to change no league, got %v", changed)
+	}
+}
+
+func TestRegistryImport(t *testing.T) {
+	registry := NewLeagueRegistry(0)
+	data := encodeSnapshot(t, newPlayedLeagueService(t, 2))
+
+	info, err := registry.Import("alice", "", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.ID == DefaultLeagueID || info.OwnerID != "alice" || info.Name == "" {
+		t.Errorf("Expected a new league owned by alice named after the snapshot, got %+v", info)
+	}
+	service, _, err := registry.Get(info.ID)
+	if err != nil || service.GetLeague().CurrentWeek != 2 {
+		t.Error("Expected the snapshot to be restored in the new league")
+	}
+	if len(registry.Default().GetLeague().Teams) != 0 {
+		t.Error("Expected the default league to be left alone")
+	}
+
+	if named, _ := registry.Import("alice", "Copy", bytes.NewReader(data)); named == nil || named.Name != "Copy" {
+		t.Errorf("Expected the given name to be used, got %+v", named)
+	}
+	count := registry.Count()
+	if _, err := registry.Import("alice", "Broken", bytes.NewReader([]byte("{}"))); err == nil {
+		t.Error("Expected an invalid snapshot to be rejected")
+	}
+	if registry.Count() != count {
+		t.Error("Expected no league to be created from an invalid snapshot")
+	}
+}
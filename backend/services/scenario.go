@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stadia-backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrScenarioNotFound is returned when a saved scenario does not exist
+var ErrScenarioNotFound = errors.New("scenario not found")
+
+// maxComparedScenarios is the most saved scenarios one comparison evaluates,
+// as each one runs a full forecast
+const maxComparedScenarios = 5
+
+// ErrTooManyScenarios is returned when a comparison names more than
+// maxComparedScenarios scenarios
+var ErrTooManyScenarios = fmt.Errorf("at most %d scenarios can be compared at once", maxComparedScenarios)
+
+// EvaluateScenario applies hypothetical results to a copy of the league and
+// returns the resulting standings and predictions. The real league is not changed.
+func (ls *LeagueService) EvaluateScenario(ctx context.Context, results []models.ScenarioResult) (_ *models.ScenarioOutcome, err error) {
+	ctx, span := startSpan(ctx, "LeagueService.EvaluateScenario", attribute.Int("scenario.results", len(results)))
+	defer func() { endSpan(span, err) }()
+
+	if err := ls.validateScenario(results); err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = make([]models.ScenarioResult, 0)
+	}
+
+	league := ls.league.Clone()
+	for _, result := range results {
+		match := league.GetMatch(result.MatchID)
+		match.SetResult(result.HomeScore, result.AwayScore)
+		league.GetTeam(match.HomeTeamID).UpdateStats(result.HomeScore, result.AwayScore)
+		league.GetTeam(match.AwayTeamID).UpdateStats(result.AwayScore, result.HomeScore)
+	}
+
+	// Evaluate with the same services, but against the copy
+	scenario := &LeagueService{
+		league:            league,
+		simulationService: ls.simulationService,
+		fixtureService:    ls.fixtureService,
+		predictionService: ls.predictionService,
+		clinchService:     ls.clinchService,
+		oddsService:       ls.oddsService,
+	}
+	result, err := scenario.predict(ctx, models.MethodAuto)
+	if err != nil {
+		return nil, err
+	}
+	predictions := scenario.buildPredictionResponse(result.Probabilities, result.Method, result.Runtime)
+
+	return &models.ScenarioOutcome{
+		Results:     results,
+		Standings:   scenario.GetStandingsTable(),
+		Predictions: predictions,
+	}, nil
+}
+
+// validateScenario checks that every result targets a distinct unplayed match
+func (ls *LeagueService) validateScenario(results []models.ScenarioResult) error {
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.HomeScore < 0 || result.AwayScore < 0 {
+			return errors.New("scores cannot be negative")
+		}
+
+		match := ls.league.GetMatch(result.MatchID)
+		if match == nil {
+			return fmt.Errorf("match %s not found", result.MatchID)
+		}
+		if match.IsPlayed() {
+			return fmt.Errorf("match %s has already been played", result.MatchID)
+		}
+		if seen[result.MatchID] {
+			return fmt.Errorf("match %s appears more than once", result.MatchID)
+		}
+		seen[result.MatchID] = true
+	}
+	return nil
+}
+
+// SaveScenario stores a named scenario for later evaluation and comparison
+func (ls *LeagueService) SaveScenario(name string, results []models.ScenarioResult) (*models.Scenario, error) {
+	if name == "" {
+		return nil, errors.New("scenario name is required")
+	}
+	if err := ls.validateScenario(results); err != nil {
+		return nil, err
+	}
+
+	scenario := &models.Scenario{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Results:   results,
+		CreatedAt: time.Now(),
+	}
+	ls.scenarios[scenario.ID] = scenario
+
+	return scenario, nil
+}
+
+// GetScenarios returns all saved scenarios, oldest first
+func (ls *LeagueService) GetScenarios() []*models.Scenario {
+	scenarios := make([]*models.Scenario, 0, len(ls.scenarios))
+	for _, scenario := range ls.scenarios {
+		scenarios = append(scenarios, scenario)
+	}
+
+	sort.Slice(scenarios, func(i, j int) bool {
+		return scenarios[i].CreatedAt.Before(scenarios[j].CreatedAt)
+	})
+
+	return scenarios
+}
+
+// DeleteScenario removes a saved scenario
+func (ls *LeagueService) DeleteScenario(id string) error {
+	if _, ok := ls.scenarios[id]; !ok {
+		return ErrScenarioNotFound
+	}
+	delete(ls.scenarios, id)
+	return nil
+}
+
+// EvaluateSavedScenario evaluates a saved scenario against the current league.
+// It fails if one of its matches has been played since it was saved.
+func (ls *LeagueService) EvaluateSavedScenario(ctx context.Context, id string) (*models.ScenarioOutcome, error) {
+	scenario, ok := ls.scenarios[id]
+	if !ok {
+		return nil, ErrScenarioNotFound
+	}
+
+	outcome, err := ls.EvaluateScenario(ctx, scenario.Results)
+	if err != nil {
+		return nil, fmt.Errorf("scenario %q: %w", scenario.Name, err)
+	}
+	outcome.ScenarioID = scenario.ID
+	outcome.Name = scenario.Name
+
+	return outcome, nil
+}
+
+// CompareScenarios evaluates saved scenarios side by side with the current league
+func (ls *LeagueService) CompareScenarios(ctx context.Context, ids []string) (*models.ScenarioComparison, error) {
+	if len(ids) > maxComparedScenarios {
+		return nil, ErrTooManyScenarios
+	}
+
+	baseline, err := ls.EvaluateScenario(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	baseline.Name = "Current"
+
+	comparison := &models.ScenarioComparison{
+		Baseline:  baseline,
+		Scenarios: make([]*models.ScenarioOutcome, 0, len(ids)),
+	}
+	for _, id := range ids {
+		outcome, err := ls.EvaluateSavedScenario(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Scenarios = append(comparison.Scenarios, outcome)
+	}
+
+	return comparison, nil
+}
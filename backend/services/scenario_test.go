@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"stadia-backend/models"
+	"testing"
+)
+
+// newPlayedLeagueService creates a league service with four teams and the given weeks played
+func newPlayedLeagueService(t *testing.T, weeks int) *LeagueService {
+	t.Helper()
+	service := NewLeagueService()
+	err := service.InitializeLeague([]*models.Team{
+		models.NewTeam("Team A", 85, ""),
+		models.NewTeam("Team B", 80, ""),
+		models.NewTeam("Team C", 70, ""),
+		models.NewTeam("Team D", 60, ""),
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize league: %v", err)
+	}
+	for i := 0; i < weeks; i++ {
+		if err := service.PlayNextWeek(context.Background()); err != nil {
+			t.Fatalf("Failed to play week %d: %v", i+1, err)
+		}
+	}
+	return service
+}
+
+func TestEvaluateScenarioLeavesLeagueUntouched(t *testing.T) {
+	service := newPlayedLeagueService(t, 4)
+	match := service.GetLeague().GetMatchesByWeek(5)[0]
+	homePoints := service.GetLeague().GetTeam(match.HomeTeamID).Points
+
+	outcome, err := service.EvaluateScenario(context.Background(), []models.ScenarioResult{
+		{MatchID: match.ID, HomeScore: 3, AwayScore: 0},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, standing := range outcome.Standings {
+		if standing.ID == match.HomeTeamID && standing.Points != homePoints+3 {
+			t.Errorf("Scenario should give the home team %d points, got %d", homePoints+3, standing.Points)
+		}
+	}
+	if outcome.Predictions == nil || len(outcome.Predictions.Predictions) != 4 {
+		t.Error("Scenario should include predictions for every team")
+	}
+
+	if match.IsPlayed() {
+		t.Error("Evaluating a scenario must not play the real match")
+	}
+	if service.GetLeague().GetTeam(match.HomeTeamID).Points != homePoints {
+		t.Error("Evaluating a scenario must not change real team stats")
+	}
+}
+
+func TestEvaluateScenarioValidation(t *testing.T) {
+	service := newPlayedLeagueService(t, 4)
+	played := service.GetLeague().GetMatchesByWeek(1)[0]
+	unplayed := service.GetLeague().GetMatchesByWeek(6)[0]
+
+	tests := []struct {
+		name    string
+		results []models.ScenarioResult
+	}{
+		{"unknown match", []models.ScenarioResult{{MatchID: "missing"}}},
+		{"played match", []models.ScenarioResult{{MatchID: played.ID}}},
+		{"duplicate match", []models.ScenarioResult{{MatchID: unplayed.ID}, {MatchID: unplayed.ID}}},
+		{"negative score", []models.ScenarioResult{{MatchID: unplayed.ID, HomeScore: -1}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := service.EvaluateScenario(context.Background(), tt.results); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestSavedScenarios(t *testing.T) {
+	service := newPlayedLeagueService(t, 4)
+	match := service.GetLeague().GetMatchesByWeek(5)[0]
+
+	homeWin, err := service.SaveScenario("Home win", []models.ScenarioResult{{MatchID: match.ID, HomeScore: 2}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	awayWin, _ := service.SaveScenario("Away win", []models.ScenarioResult{{MatchID: match.ID, AwayScore: 2}})
+
+	if len(service.GetScenarios()) != 2 {
+		t.Errorf("Expected 2 saved scenarios, got %d", len(service.GetScenarios()))
+	}
+
+	comparison, err := service.CompareScenarios(context.Background(), []string{homeWin.ID, awayWin.ID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(comparison.Scenarios) != 2 || comparison.Scenarios[0].Name != "Home win" {
+		t.Error("Comparison should list the scenarios in the requested order")
+	}
+	tooMany := make([]string, maxComparedScenarios+1)
+	for i := range tooMany {
+		tooMany[i] = homeWin.ID
+	}
+	if _, err := service.CompareScenarios(context.Background(), tooMany); err != ErrTooManyScenarios {
+		t.Errorf("Expected ErrTooManyScenarios, got %v", err)
+	}
+
+	// Once the match is played the scenario no longer applies
+	if err := service.PlayNextWeek(context.Background()); err != nil {
+		t.Fatalf("Failed to play week: %v", err)
+	}
+	if _, err := service.EvaluateSavedScenario(context.Background(), homeWin.ID); err == nil {
+		t.Error("Expected an error for a scenario on a played match")
+	}
+
+	if err := service.DeleteScenario(homeWin.ID); err != nil {
+		t.Errorf("Unexpected error deleting scenario: %v", err)
+	}
+	if err := service.DeleteScenario(homeWin.ID); err != ErrScenarioNotFound {
+		t.Errorf("Expected ErrScenarioNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"stadia-backend/models"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxSeasonSimulations bounds the number of seasons simulated in one batch
+	maxSeasonSimulations = 100000
+
+	// defaultQualifyingPlaces is used when no qualifying places are given, as
+	// in a group stage where the top two go through
+	defaultQualifyingPlaces = 2
+
+	// seasonProgressInterval is the number of seasons between progress reports
+	seasonProgressInterval = 500
+)
+
+// seasonSimulation is a batch of seasons prepared from a copy of the league,
+// so it can run while the league itself changes
+type seasonSimulation struct {
+	options   models.SeasonSimulationOptions
+	week      int
+	teams     []*models.Team
+	remaining []*models.Match
+	model     models.ModelParams
+}
+
+// histogram counts the values of a whole-number statistic
+type histogram map[int]int
+
+// distribution summarises the n values counted in a histogram
+func (h histogram) distribution(n int) models.Distribution {
+	values := make([]int, 0, len(h))
+	sum := 0
+	for value, count := range h {
+		values = append(values, value)
+		sum += value * count
+	}
+	sort.Ints(values)
+
+	// Nearest-rank percentile
+	percentile := func(q float64) int {
+		rank := int(math.Ceil(q * float64(n)))
+		seen := 0
+		for _, value := range values {
+			seen += h[value]
+			if seen >= rank {
+				return value
+			}
+		}
+		return values[len(values)-1]
+	}
+
+	return models.Distribution{
+		Mean:   float64(sum) / float64(n),
+		Min:    values[0],
+		P5:     percentile(0.05),
+		P25:    percentile(0.25),
+		Median: percentile(0.5),
+		P75:    percentile(0.75),
+		P95:    percentile(0.95),
+		Max:    values[len(values)-1],
+	}
+}
+
+// prepareSeasonSimulation validates the options, fills in their defaults and
+// copies the league state the seasons start from
+func (ls *LeagueService) prepareSeasonSimulation(options models.SeasonSimulationOptions) (*seasonSimulation, error) {
+	if len(ls.league.Teams) < 2 {
+		return nil, errors.New("league not initialized")
+	}
+	if options.Seasons < 1 || options.Seasons > maxSeasonSimulations {
+		return nil, fmt.Errorf("number of seasons must be between 1 and %d", maxSeasonSimulations)
+	}
+	if options.QualifyingPlaces == 0 {
+		options.QualifyingPlaces = min(defaultQualifyingPlaces, len(ls.league.Teams))
+	}
+	if options.QualifyingPlaces < 1 || options.QualifyingPlaces > len(ls.league.Teams) {
+		return nil, fmt.Errorf("qualifying places must be between 1 and %d", len(ls.league.Teams))
+	}
+
+	league := ls.league.Clone()
+	if options.FromStart {
+		league.CurrentWeek = 0
+		for _, team := range league.Teams {
+			team.ResetStats()
+		}
+		for _, match := range league.GetAllMatches() {
+			match.HomeScore, match.AwayScore, match.Status = 0, 0, models.StatusNotPlayed
+		}
+	}
+	if options.Seed == 0 {
+		options.Seed = league.Seed + int64(league.CurrentWeek)
+	}
+
+	return &seasonSimulation{
+		options:   options,
+		week:      league.CurrentWeek,
+		teams:     league.GetTeamsList(),
+		remaining: remainingMatches(league.Fixtures),
+		model:     league.Settings.Model,
+	}, nil
+}
+
+// run plays out the remaining matches once per season and summarises the final
+// tables. progress, when given, is called with the percentage of seasons done.
+// The simulation stops with the context's error once ctx is done.
+func (s *seasonSimulation) run(ctx context.Context, progress func(percent float64)) (_ *models.SeasonSimulationSummary, err error) {
+	ctx, span := startSpan(ctx, "LeagueService.SimulateSeasons", attribute.Int("simulation.seasons", s.options.Seasons))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	n := s.options.Seasons
+
+	simulation := NewSimulationService()
+	simulation.SetParams(s.model)
+	simulation.Seed(s.options.Seed)
+
+	type tally struct {
+		positions      []int
+		positionSum    int
+		points         histogram
+		goalDifference histogram
+	}
+	tallies := make(map[string]*tally, len(s.teams))
+	for _, team := range s.teams {
+		tallies[team.ID] = &tally{
+			positions:      make([]int, len(s.teams)),
+			points:         make(histogram),
+			goalDifference: make(histogram),
+		}
+	}
+	winningPoints := make(histogram)
+
+	table := make([]*models.Team, len(s.teams))
+	copies := make(map[string]*models.Team, len(s.teams))
+	for i := 1; i <= n; i++ {
+		// Copy the teams so the simulation does not modify the originals
+		for t, team := range s.teams {
+			teamCopy := *team
+			table[t] = &teamCopy
+			copies[team.ID] = &teamCopy
+		}
+
+		for _, match := range s.remaining {
+			homeTeam, awayTeam := copies[match.HomeTeamID], copies[match.AwayTeamID]
+			homeScore, awayScore := simulation.SimulateMatch(homeTeam, awayTeam)
+			homeTeam.UpdateStats(homeScore, awayScore)
+			awayTeam.UpdateStats(awayScore, homeScore)
+		}
+
+		sortStandings(table)
+		for position, team := range table {
+			t := tallies[team.ID]
+			t.positions[position]++
+			t.positionSum += position + 1
+			t.points[team.Points]++
+			t.goalDifference[team.GoalDifference()]++
+		}
+		winningPoints[table[0].Points]++
+
+		if i%seasonProgressInterval == 0 || i == n {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if progress != nil {
+				progress(float64(i) * 100 / float64(n))
+			}
+		}
+	}
+
+	summary := &models.SeasonSimulationSummary{
+		Seasons:          n,
+		Week:             s.week,
+		Seed:             s.options.Seed,
+		QualifyingPlaces: s.options.QualifyingPlaces,
+		WinningPoints:    winningPoints.distribution(n),
+		Teams:            make([]models.TeamSeasonSummary, 0, len(s.teams)),
+	}
+	for _, team := range s.teams {
+		t := tallies[team.ID]
+		positions := make([]float64, len(s.teams))
+		qualified := 0.0
+		for p, count := range t.positions {
+			positions[p] = float64(count) * 100 / float64(n)
+			if p < s.options.QualifyingPlaces {
+				qualified += float64(count)
+			}
+		}
+		summary.Teams = append(summary.Teams, models.TeamSeasonSummary{
+			TeamID:                   team.ID,
+			TeamName:                 team.Name,
+			ChampionProbability:      positions[0],
+			QualificationProbability: qualified * 100 / float64(n),
+			Positions:                positions,
+			AveragePosition:          float64(t.positionSum) / float64(n),
+			Points:                   t.points.distribution(n),
+			GoalDifference:           t.goalDifference.distribution(n),
+		})
+	}
+	sort.Slice(summary.Teams, func(i, j int) bool {
+		if summary.Teams[i].AveragePosition != summary.Teams[j].AveragePosition {
+			return summary.Teams[i].AveragePosition < summary.Teams[j].AveragePosition
+		}
+		return summary.Teams[i].TeamName < summary.Teams[j].TeamName
+	})
+	runtime := time.Since(start)
+	summary.RuntimeMs = float64(runtime.Microseconds()) / 1000
+	instrumentation.MonteCarloRun(MonteCarloSeasons, n, runtime)
+
+	return summary, nil
+}
+
+// SimulateSeasons plays out the season many times and summarises the final
+// tables. The league itself is not changed. Without a seed, the league seed
+// offset by the current week is used, so the same league state always gives
+// the same summary.
+func (ls *LeagueService) SimulateSeasons(options models.SeasonSimulationOptions) (*models.SeasonSimulationSummary, error) {
+	simulation, err := ls.prepareSeasonSimulation(options)
+	if err != nil {
+		return nil, err
+	}
+	return simulation.run(context.Background(), nil)
+}
+
+// SubmitSeasonSimulation validates the options, copies the current league state
+// and simulates the seasons as a background job of the league with the given ID
+func (ls *LeagueService) SubmitSeasonSimulation(
+	jobs *JobService,
+	leagueID string,
+	options models.SeasonSimulationOptions,
+	timeout time.Duration,
+) (*models.Job, error) {
+	simulation, err := ls.prepareSeasonSimulation(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs.Submit(models.JobSeasonSimulation, leagueID, timeout, func(ctx context.Context, progress func(float64)) (any, error) {
+		return simulation.run(ctx, progress)
+	})
+}
+
+// SeasonSummaryTable flattens a season simulation summary into one row per
+// team, with the position percentages in the columns position_1, position_2
+// and so on, so it can be written with WriteExport
+func SeasonSummaryTable(summary *models.SeasonSimulationSummary) *models.ExportTable {
+	columns := []string{
+		"team_id", "team", "champion_probability", "qualification_probability", "average_position",
+		"points_mean", "points_min", "points_p5", "points_median", "points_p95", "points_max",
+		"goal_difference_mean", "goal_difference_median",
+	}
+	for p := range summary.Teams {
+		columns = append(columns, fmt.Sprintf("position_%d", p+1))
+	}
+
+	table := &models.ExportTable{Columns: columns, Rows: make([][]any, 0, len(summary.Teams))}
+	for _, team := range summary.Teams {
+		row := []any{
+			team.TeamID, team.TeamName, team.ChampionProbability, team.QualificationProbability, team.AveragePosition,
+			team.Points.Mean, team.Points.Min, team.Points.P5, team.Points.Median, team.Points.P95, team.Points.Max,
+			team.GoalDifference.Mean, team.GoalDifference.Median,
+		}
+		for _, percent := range team.Positions {
+			row = append(row, percent)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
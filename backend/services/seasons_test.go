@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"math"
+	"stadia-backend/models"
+	"testing"
+	"time"
+)
+
+func TestSimulateSeasons(t *testing.T) {
+	service := newPlayedLeagueService(t, 2)
+	tracked := service.GetLeague().GetTeamsList()[0]
+	before := tracked.Points
+
+	summary, err := service.SimulateSeasons(models.SeasonSimulationOptions{Seasons: 2000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Seasons != 2000 || summary.Week != 2 || summary.QualifyingPlaces != 2 || len(summary.Teams) != 4 {
+		t.Fatalf("Unexpected summary %+v", summary)
+	}
+
+	champions, qualified := 0.0, 0.0
+	for i, team := range summary.Teams {
+		total := 0.0
+		for _, p := range team.Positions {
+			total += p
+		}
+		if math.Abs(total-100) > 1e-6 {
+			t.Errorf("%s: position percentages sum to %f", team.TeamName, total)
+		}
+		points := team.Points
+		if points.Min > points.P5 || points.P5 > points.P25 || points.P25 > points.Median ||
+			points.Median > points.P75 || points.P75 > points.P95 || points.P95 > points.Max {
+			t.Errorf("%s: percentiles out of order %+v", team.TeamName, points)
+		}
+		if i > 0 && team.AveragePosition < summary.Teams[i-1].AveragePosition {
+			t.Error("Expected teams ordered by average position")
+		}
+		champions += team.ChampionProbability
+		qualified += team.QualificationProbability
+	}
+	if math.Abs(champions-100) > 1e-6 || math.Abs(qualified-200) > 1e-6 {
+		t.Errorf("Expected title rates to sum to 100 and qualification rates to 200, got %f and %f", champions, qualified)
+	}
+
+	if service.GetLeague().CurrentWeek != 2 || tracked.Points != before {
+		t.Error("Simulating seasons should leave the league untouched")
+	}
+}
+
+func TestSimulateSeasonsFromStart(t *testing.T) {
+	service := newPlayedLeagueService(t, 6)
+
+	summary, err := service.SimulateSeasons(models.SeasonSimulationOptions{Seasons: 500, FromStart: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Week != 0 {
+		t.Errorf("Expected the seasons to start from week 0, got %d", summary.Week)
+	}
+	if summary.WinningPoints.Min == summary.WinningPoints.Max {
+		t.Error("Replayed seasons should not all end the same way")
+	}
+}
+
+func TestSimulateSeasonsIsReproducible(t *testing.T) {
+	service := newPlayedLeagueService(t, 1)
+	options := models.SeasonSimulationOptions{Seasons: 200, Seed: 42}
+
+	first, _ := service.SimulateSeasons(options)
+	second, _ := service.SimulateSeasons(options)
+	for i := range first.Teams {
+		if first.Teams[i].TeamID != second.Teams[i].TeamID || first.Teams[i].Points != second.Teams[i].Points {
+			t.Fatal("The same seed should give the same summary")
+		}
+	}
+
+	invalid := []models.SeasonSimulationOptions{
+		{Seasons: 0},
+		{Seasons: maxSeasonSimulations + 1},
+		{Seasons: 10, QualifyingPlaces: 5},
+	}
+	for _, options := range invalid {
+		if _, err := service.SimulateSeasons(options); err == nil {
+			t.Errorf("Expected an error for %+v", options)
+		}
+	}
+}
+
+func TestSubmitSeasonSimulation(t *testing.T) {
+	service := newPlayedLeagueService(t, 3)
+	jobs := NewJobService(JobSettings{Workers: 1, QueueSize: 1, Timeout: time.Minute, Retention: time.Minute})
+
+	job, err := service.SubmitSeasonSimulation(jobs, "", models.SeasonSimulationOptions{Seasons: 1000}, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if job.Kind != models.JobSeasonSimulation {
+		t.Errorf("Expected a season simulation job, got %s", job.Kind)
+	}
+
+	// Changing the league does not affect a simulation that has been submitted
+	service.PlayAllWeeks(context.Background())
+
+	job = waitForJob(t, jobs, job.ID)
+	summary, ok := job.Result.(*models.SeasonSimulationSummary)
+	if job.Status != models.JobCompleted || !ok {
+		t.Fatalf("Expected a completed job with a summary, got %+v", job)
+	}
+	if summary.Week != 3 || summary.QualifyingPlaces != 2 || summary.Seed == 0 {
+		t.Errorf("Expected a summary from week 3 with defaults filled in, got %+v", summary)
+	}
+
+	if _, err := service.SubmitSeasonSimulation(jobs, "", models.SeasonSimulationOptions{}, 0); err == nil {
+		t.Error("Expected invalid options to be rejected before submitting")
+	}
+}
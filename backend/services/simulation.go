@@ -1,24 +1,91 @@
 package services
 
 import (
+	"fmt"
+	"maps"
 	"math"
 	"math/rand"
+	"slices"
 	"stadia-backend/models"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// SimulationSettings tune the Monte Carlo forecasts of every league and hold
+// the match model presets leagues choose from. They may change while the
+// server runs.
+type SimulationSettings struct {
+	Simulations   int                           // Monte Carlo runs per forecast, and seasons per batch simulation unless given
+	DefaultPreset string                        // Model preset of leagues initialized without one
+	Presets       map[string]models.ModelParams // Match model presets by lower-case name
+}
+
+// DefaultSimulationSettings are used until SetSimulationSettings is called
+var DefaultSimulationSettings = SimulationSettings{
+	Simulations:   10000,
+	DefaultPreset: models.PresetRealistic,
+	Presets:       models.BuiltinModelPresets(),
+}
+
+// simulationSettings holds the settings in use
+var simulationSettings atomic.Pointer[SimulationSettings]
+
+// SetSimulationSettings changes the simulation settings of every league.
+// Forecasts already running keep their number of simulations. Leagues keep
+// their model until LeagueRegistry.ApplyPresets gives them the new presets.
+func SetSimulationSettings(settings SimulationSettings) {
+	simulationSettings.Store(&settings)
+}
+
+// CurrentSimulationSettings returns the simulation settings in use
+func CurrentSimulationSettings() SimulationSettings {
+	if settings := simulationSettings.Load(); settings != nil {
+		return *settings
+	}
+	return DefaultSimulationSettings
+}
+
+// Preset returns the parameters of a model preset, or of the default preset
+// when name is empty
+func (s SimulationSettings) Preset(name string) (models.ModelParams, error) {
+	if name == "" {
+		name = s.DefaultPreset
+	}
+	name = strings.ToLower(name)
+	params, ok := s.Presets[name]
+	if !ok {
+		names := slices.Sorted(maps.Keys(s.Presets))
+		return models.ModelParams{}, fmt.Errorf("unknown model preset %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	params.Preset = name
+	return params, nil
+}
+
 // SimulationService handles match simulation logic
 type SimulationService struct {
-	rand *rand.Rand
+	rand   *rand.Rand
+	params models.ModelParams
 }
 
-// NewSimulationService creates a new simulation service
+// NewSimulationService creates a new simulation service with the realistic model
 func NewSimulationService() *SimulationService {
 	return &SimulationService{
-		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		params: models.DefaultModelParams(),
 	}
 }
 
+// SetParams replaces the parameters of the match model
+func (s *SimulationService) SetParams(params models.ModelParams) {
+	s.params = params
+}
+
+// Seed resets the random source so that the same seed replays the same matches
+func (s *SimulationService) Seed(seed int64) {
+	s.rand.Seed(seed)
+}
+
 // SimulateMatch simulates a match between two teams based on their power
 // Factors considered:
 // 1. Team power difference
@@ -26,9 +93,7 @@ func NewSimulationService() *SimulationService {
 // 3. Randomness (for unpredictability)
 func (s *SimulationService) SimulateMatch(homeTeam, awayTeam *models.Team) (homeScore, awayScore int) {
 	// Calculate effective power with home advantage
-	homeAdvantage := 10.0 // Home team gets 10% boost
-	homePower := float64(homeTeam.Power) * (1 + homeAdvantage/100)
-	awayPower := float64(awayTeam.Power)
+	homePower, awayPower := s.effectivePowers(homeTeam, awayTeam)
 
 	// Calculate expected goals based on power
 	homeExpectedGoals := s.calculateExpectedGoals(homePower, awayPower)
@@ -41,31 +106,101 @@ func (s *SimulationService) SimulateMatch(homeTeam, awayTeam *models.Team) (home
 	return homeScore, awayScore
 }
 
+// effectivePowers returns the power of both sides after applying home advantage
+func (s *SimulationService) effectivePowers(homeTeam, awayTeam *models.Team) (homePower, awayPower float64) {
+	homePower = float64(homeTeam.Power) * (1 + s.params.HomeAdvantage/100) // Home team gets a percentage boost
+	awayPower = float64(awayTeam.Power)
+	return homePower, awayPower
+}
+
 // calculateExpectedGoals calculates expected goals based on team power
 func (s *SimulationService) calculateExpectedGoals(attackPower, defensePower float64) float64 {
+	// Add some randomness, from NoiseMin to NoiseMax
+	randomFactor := s.params.NoiseMin + s.rand.Float64()*(s.params.NoiseMax-s.params.NoiseMin)
+	return s.expectedGoals(attackPower, defensePower, randomFactor)
+}
+
+// expectedGoals calculates expected goals for a given random factor
+func (s *SimulationService) expectedGoals(attackPower, defensePower, randomFactor float64) float64 {
 	// Normalize power values (0-100) to reasonable goal expectations (0-4)
 	powerRatio := attackPower / defensePower
 
-	// Base expected goals
-	baseGoals := 1.5
-
 	// Adjust based on power ratio
 	// Strong team vs weak team: higher expected goals
 	// Equal teams: around base goals
-	expectedGoals := baseGoals * math.Pow(powerRatio, 0.4)
-
-	// Add some randomness
-	randomFactor := 0.8 + s.rand.Float64()*0.4 // 0.8 to 1.2
+	expectedGoals := s.params.BaseGoals * math.Pow(powerRatio, s.params.PowerExponent)
 	expectedGoals *= randomFactor
 
 	// Cap maximum expected goals
-	if expectedGoals > 4.5 {
-		expectedGoals = 4.5
+	if expectedGoals > s.params.GoalCap {
+		expectedGoals = s.params.GoalCap
 	}
 
 	return expectedGoals
 }
 
+// goalDistribution returns the probability of scoring 0..maxGoals goals.
+// The random factor is integrated out numerically and the tail beyond
+// maxGoals is folded into the last bucket, so the result sums to 1.
+func (s *SimulationService) goalDistribution(attackPower, defensePower float64, maxGoals int) []float64 {
+	const samples = 8
+	dist := make([]float64, maxGoals+1)
+
+	for i := 0; i < samples; i++ {
+		// Midpoints of the random factor band
+		randomFactor := s.params.NoiseMin + (s.params.NoiseMax-s.params.NoiseMin)*(float64(i)+0.5)/samples
+		lambda := s.expectedGoals(attackPower, defensePower, randomFactor)
+
+		p := math.Exp(-lambda)
+		cumulative := 0.0
+		for k := 0; k < maxGoals; k++ {
+			dist[k] += p / samples
+			cumulative += p
+			p *= lambda / float64(k+1)
+		}
+		dist[maxGoals] += (1 - cumulative) / samples
+	}
+
+	return dist
+}
+
+// ScoreDistribution returns the probability of every scoreline between two teams.
+// dist[h][a] is the probability of the home team scoring h and the away team a;
+// goals beyond maxGoals are folded into the last row and column.
+func (s *SimulationService) ScoreDistribution(homeTeam, awayTeam *models.Team, maxGoals int) [][]float64 {
+	homePower, awayPower := s.effectivePowers(homeTeam, awayTeam)
+	homeGoals := s.goalDistribution(homePower, awayPower, maxGoals)
+	awayGoals := s.goalDistribution(awayPower, homePower, maxGoals)
+
+	dist := make([][]float64, maxGoals+1)
+	for h := range dist {
+		dist[h] = make([]float64, maxGoals+1)
+		for a := range dist[h] {
+			dist[h][a] = homeGoals[h] * awayGoals[a]
+		}
+	}
+
+	return dist
+}
+
+// OutcomeProbabilities returns the model probabilities of a home win, a draw and an away win
+func (s *SimulationService) OutcomeProbabilities(homeTeam, awayTeam *models.Team) (homeWin, draw, awayWin float64) {
+	dist := s.ScoreDistribution(homeTeam, awayTeam, 15)
+	for h := range dist {
+		for a := range dist[h] {
+			switch {
+			case h > a:
+				homeWin += dist[h][a]
+			case h == a:
+				draw += dist[h][a]
+			default:
+				awayWin += dist[h][a]
+			}
+		}
+	}
+	return homeWin, draw, awayWin
+}
+
 // generateGoals generates actual goals using a Poisson-like distribution
 func (s *SimulationService) generateGoals(expectedGoals float64) int {
 	// Simple Poisson approximation
@@ -104,11 +239,10 @@ func (s *SimulationService) CalculateWinProbability(team1Power, team2Power int)
 	// Use logistic function to calculate win probability
 	powerDiff := float64(team1Power - team2Power)
 
-	// Sigmoid function scaled for football matches
-	// A 20-point difference gives about 70% win probability
-	// A 40-point difference gives about 90% win probability
-	scalingFactor := 0.05
-	probability := 1.0 / (1.0 + math.Exp(-scalingFactor*powerDiff))
+	// Sigmoid function scaled for football matches. With the realistic scale
+	// of 0.05, a 20-point difference gives about 70% win probability and a
+	// 40-point difference about 90%.
+	probability := 1.0 / (1.0 + math.Exp(-s.params.WinProbabilityScale*powerDiff))
 
 	return probability
 }
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"math"
 	"stadia-backend/models"
+	"strings"
 	"testing"
 )
 
@@ -127,3 +129,53 @@ func BenchmarkSimulateMatch(b *testing.B) {
 		service.SimulateMatch(team1, team2)
 	}
 }
+
+func TestModelParams(t *testing.T) {
+	service := NewSimulationService()
+	home := models.NewTeam("Home", 70, "")
+	away := models.NewTeam("Away", 70, "")
+
+	params := models.DefaultModelParams()
+	params.HomeAdvantage = 0
+	service.SetParams(params)
+	homeWin, _, awayWin := service.OutcomeProbabilities(home, away)
+	if math.Abs(homeWin-awayWin) > 1e-9 {
+		t.Errorf("Expected equal teams without home advantage to be even, got %.3f and %.3f", homeWin, awayWin)
+	}
+
+	// Upsets are likelier in the chaotic model
+	strong := models.NewTeam("Strong", 90, "")
+	weak := models.NewTeam("Weak", 30, "")
+	upset := func(preset string) float64 {
+		params, err := DefaultSimulationSettings.Preset(preset)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		service.SetParams(params)
+		_, _, awayWin := service.OutcomeProbabilities(strong, weak)
+		return awayWin
+	}
+	if realistic, chaotic := upset(models.PresetRealistic), upset(models.PresetChaotic); chaotic <= realistic {
+		t.Errorf("Expected more upsets in the chaotic model, got %.3f against %.3f", chaotic, realistic)
+	}
+
+	service.SetParams(models.BuiltinModelPresets()[models.PresetChaotic])
+	if scale := service.CalculateWinProbability(70, 50); math.Abs(scale-1/(1+math.Exp(-0.4))) > 1e-9 {
+		t.Errorf("Expected the win probability to use the preset scale, got %.3f", scale)
+	}
+}
+
+func TestSimulationSettingsPreset(t *testing.T) {
+	settings := DefaultSimulationSettings
+	params, err := settings.Preset("")
+	if err != nil || params != models.DefaultModelParams() {
+		t.Errorf("Expected the realistic preset by default, got %+v, %v", params, err)
+	}
+	params, err = settings.Preset("Chaotic")
+	if err != nil || params.Preset != models.PresetChaotic {
+		t.Errorf("Expected preset names to be case-insensitive, got %+v, %v", params, err)
+	}
+	if _, err := settings.Preset("boring"); err == nil || !strings.Contains(err.Error(), "chaotic, high-scoring, realistic") {
+		t.Errorf("Expected an error listing the presets, got %v", err)
+	}
+}
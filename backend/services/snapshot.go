@@ -0,0 +1,266 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"stadia-backend/models"
+	"time"
+)
+
+// snapshotMigrations upgrade a decoded snapshot from the version it is keyed
+// by to the next one. Snapshots are migrated as raw JSON objects so that older
+// layouts do not need Go types of their own.
+var snapshotMigrations = map[int]func(map[string]any) (map[string]any, error){
+	1: migrateSnapshotV1,
+}
+
+// migrateSnapshotV1 wraps the plain league JSON of version 1 in the snapshot
+// envelope. Version 1 leagues had no settings or seed, so the defaults apply.
+func migrateSnapshotV1(raw map[string]any) (map[string]any, error) {
+	settings := models.DefaultLeagueSettings()
+	raw["settings"] = map[string]any{
+		"predictionMethod":    settings.PredictionMethod,
+		"predictionStartWeek": settings.PredictionStartWeek,
+	}
+
+	return map[string]any{
+		"kind":          models.SnapshotKind,
+		"schemaVersion": 2,
+		"league":        raw,
+		"scenarios":     []any{},
+	}, nil
+}
+
+// ExportSnapshot returns a snapshot of the whole league, including saved scenarios
+func (ls *LeagueService) ExportSnapshot() *models.LeagueSnapshot {
+	return &models.LeagueSnapshot{
+		Kind:          models.SnapshotKind,
+		SchemaVersion: models.SnapshotSchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		League:        ls.league.Clone(),
+		Scenarios:     ls.GetScenarios(),
+	}
+}
+
+// DecodeSnapshot reads a snapshot of any supported schema version and migrates
+// it to the current one
+func DecodeSnapshot(r io.Reader) (*models.LeagueSnapshot, error) {
+	// Numbers are kept as written so that 64-bit seeds survive the migration
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var raw map[string]any
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	version := 1
+	if value, ok := raw["schemaVersion"]; ok {
+		number, ok := value.(json.Number)
+		parsed, err := number.Int64()
+		if !ok || err != nil || parsed < 1 {
+			return nil, errors.New("schemaVersion must be a positive whole number")
+		}
+		version = int(parsed)
+		if kind, _ := raw["kind"].(string); kind != models.SnapshotKind {
+			return nil, fmt.Errorf("not a league snapshot: kind is %q", kind)
+		}
+	} else if _, ok := raw["fixtures"]; !ok {
+		return nil, errors.New("not a league snapshot: schemaVersion is missing")
+	}
+
+	if version > models.SnapshotSchemaVersion {
+		return nil, fmt.Errorf("snapshot schema version %d is newer than the supported version %d",
+			version, models.SnapshotSchemaVersion)
+	}
+
+	for ; version < models.SnapshotSchemaVersion; version++ {
+		migrated, err := snapshotMigrations[version](raw)
+		if err != nil {
+			return nil, fmt.Errorf("migrating snapshot from version %d: %w", version, err)
+		}
+		raw = migrated
+	}
+
+	// Round-trip through JSON to decode the migrated object into the current types
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot models.LeagueSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snapshot.League == nil {
+		return nil, errors.New("invalid snapshot: league is missing")
+	}
+
+	return &snapshot, nil
+}
+
+// validateSnapshot checks that a snapshot describes a consistent league
+func validateSnapshot(snapshot *models.LeagueSnapshot) error {
+	league := snapshot.League
+	if len(league.Teams) < 2 {
+		return errors.New("at least 2 teams are required")
+	}
+	for id, team := range league.Teams {
+		if team == nil || team.ID != id {
+			return fmt.Errorf("team %s does not match its key", id)
+		}
+		if team.Name == "" {
+			return fmt.Errorf("team %s has no name", id)
+		}
+		if team.Power < 1 || team.Power > 100 {
+			return fmt.Errorf("team %s: power must be between 1 and 100", team.Name)
+		}
+	}
+
+	if len(league.Fixtures) == 0 {
+		return errors.New("at least one week of fixtures is required")
+	}
+	matchIDs := make(map[string]bool)
+	for w, weekMatches := range league.Fixtures {
+		for _, match := range weekMatches {
+			if match == nil || match.ID == "" || matchIDs[match.ID] {
+				return fmt.Errorf("week %d has a match without a unique ID", w+1)
+			}
+			matchIDs[match.ID] = true
+			if match.Week != w+1 {
+				return fmt.Errorf("match %s is listed in week %d but has week %d", match.ID, w+1, match.Week)
+			}
+			if league.Teams[match.HomeTeamID] == nil || league.Teams[match.AwayTeamID] == nil {
+				return fmt.Errorf("match %s refers to an unknown team", match.ID)
+			}
+			if match.Status != models.StatusPlayed && match.Status != models.StatusNotPlayed {
+				return fmt.Errorf("match %s has unknown status %q", match.ID, match.Status)
+			}
+			if match.HomeScore < 0 || match.AwayScore < 0 {
+				return fmt.Errorf("match %s has a negative score", match.ID)
+			}
+		}
+	}
+
+	if league.CurrentWeek < 0 || league.CurrentWeek > len(league.Fixtures) {
+		return fmt.Errorf("current week must be between 0 and %d", len(league.Fixtures))
+	}
+	switch league.Settings.PredictionMethod {
+	case models.MethodAuto, models.MethodMonteCarlo, models.MethodHeuristic, models.MethodExact:
+	default:
+		return fmt.Errorf("unknown prediction method %q", league.Settings.PredictionMethod)
+	}
+	if league.Settings.PredictionStartWeek < 0 {
+		return errors.New("prediction start week cannot be negative")
+	}
+	if model := league.Settings.Model; model != (models.ModelParams{}) {
+		if err := model.Validate(); err != nil {
+			return fmt.Errorf("invalid model: %w", err)
+		}
+	}
+
+	for _, scenario := range snapshot.Scenarios {
+		if scenario == nil || scenario.ID == "" {
+			return errors.New("scenario without an ID")
+		}
+		for _, result := range scenario.Results {
+			if !matchIDs[result.MatchID] {
+				return fmt.Errorf("scenario %q refers to unknown match %s", scenario.Name, result.MatchID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestoreSnapshot replaces the league with the one in a snapshot. Team
+// statistics are recalculated from the match results rather than trusted, and
+// match simulation is seeded with the snapshot's seed, so playing on from the
+// same snapshot always gives the same results.
+func (ls *LeagueService) RestoreSnapshot(snapshot *models.LeagueSnapshot) error {
+	if err := validateSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	league := snapshot.League.Clone()
+	league.TotalWeeks = len(league.Fixtures)
+	if league.Settings.Model == (models.ModelParams{}) {
+		// Saved before models could be chosen, so played with the realistic one
+		league.Settings.Model = models.DefaultModelParams()
+	}
+	if league.Predictions == nil {
+		league.Predictions = make(map[string]float64)
+	}
+	for _, team := range league.Teams {
+		team.ResetStats()
+	}
+	for _, match := range league.GetAllMatches() {
+		if match.IsPlayed() {
+			league.GetTeam(match.HomeTeamID).UpdateStats(match.HomeScore, match.AwayScore)
+			league.GetTeam(match.AwayTeamID).UpdateStats(match.AwayScore, match.HomeScore)
+		}
+	}
+
+	ls.league = league
+	ls.applyModel()
+	ls.seedSimulation()
+	ls.predictionMethod = ""
+	if n := len(league.PredictionHistory); n > 0 {
+		ls.predictionMethod = league.PredictionHistory[n-1].Method
+	}
+	ls.predictionRuntime = 0
+
+	ls.scenarios = make(map[string]*models.Scenario, len(snapshot.Scenarios))
+	for _, scenario := range snapshot.Scenarios {
+		ls.scenarios[scenario.ID] = scenario
+	}
+
+	return nil
+}
+
+// ImportSnapshot decodes, migrates and restores a snapshot
+func (ls *LeagueService) ImportSnapshot(r io.Reader) (*models.LeagueSnapshot, error) {
+	snapshot, err := DecodeSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := ls.checkTeamCount(len(snapshot.League.Teams)); err != nil {
+		return nil, err
+	}
+	if err := ls.RestoreSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	return ls.ExportSnapshot(), nil
+}
+
+// SaveSnapshotFile writes a snapshot of the league to a file. The file is
+// replaced only once the snapshot has been written in full.
+func (ls *LeagueService) SaveSnapshotFile(path, appVersion string) error {
+	snapshot := ls.ExportSnapshot()
+	snapshot.AppVersion = appVersion
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// LoadSnapshotFile restores the league from a snapshot file
+func (ls *LeagueService) LoadSnapshotFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := ls.ImportSnapshot(file); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
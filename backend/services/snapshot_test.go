@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"stadia-backend/models"
+	"strings"
+	"testing"
+)
+
+// encodeSnapshot exports a league service's snapshot as JSON
+func encodeSnapshot(t *testing.T, service *LeagueService) []byte {
+	t.Helper()
+	data, err := json.Marshal(service.ExportSnapshot())
+	if err != nil {
+		t.Fatalf("Failed to encode snapshot: %v", err)
+	}
+	return data
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	original := newPlayedLeagueService(t, 4)
+	original.SaveScenario("Draw", []models.ScenarioResult{
+		{MatchID: original.GetLeague().GetMatchesByWeek(5)[0].ID, HomeScore: 1, AwayScore: 1},
+	})
+	data := encodeSnapshot(t, original)
+
+	restored := NewLeagueService()
+	if _, err := restored.ImportSnapshot(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	before, after := original.GetLeague(), restored.GetLeague()
+	if after.CurrentWeek != 4 || after.Seed != before.Seed || len(after.PredictionHistory) != 2 {
+		t.Errorf("Expected week 4, the same seed and 2 stored predictions, got %d, %d, %d",
+			after.CurrentWeek, after.Seed, len(after.PredictionHistory))
+	}
+	for _, match := range before.GetAllMatches() {
+		copied := after.GetMatch(match.ID)
+		if copied == nil || copied.Status != match.Status || copied.HomeScore != match.HomeScore {
+			t.Errorf("Match %s was not restored", match.ID)
+		}
+	}
+	for id, team := range before.Teams {
+		if after.Teams[id].Points != team.Points {
+			t.Errorf("%s: expected %d points, got %d", team.Name, team.Points, after.Teams[id].Points)
+		}
+	}
+	if len(restored.GetScenarios()) != 1 {
+		t.Error("Expected the saved scenario to be restored")
+	}
+	if restored.GetPredictions().Method != original.GetPredictions().Method {
+		t.Error("Expected the stored prediction method to be restored")
+	}
+}
+
+func TestSnapshotReplaysWithSeed(t *testing.T) {
+	data := encodeSnapshot(t, newPlayedLeagueService(t, 2))
+
+	results := make([]string, 2)
+	for i := range results {
+		service := NewLeagueService()
+		if _, err := service.ImportSnapshot(bytes.NewReader(data)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		service.PlayAllWeeks(context.Background())
+		for _, match := range service.GetLeague().GetAllMatches() {
+			results[i] += fmt.Sprintf("%d-%d ", match.HomeScore, match.AwayScore)
+		}
+	}
+
+	if results[0] != results[1] {
+		t.Error("Playing on from the same snapshot should give the same results")
+	}
+}
+
+func TestSnapshotKeepsModel(t *testing.T) {
+	original := newPlayedLeagueService(t, 2)
+	chaotic := models.BuiltinModelPresets()[models.PresetChaotic]
+	if err := original.SetModel(context.Background(), chaotic); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	invalid := chaotic
+	invalid.NoiseMin = 0
+	if err := original.SetModel(context.Background(), invalid); err == nil {
+		t.Error("Expected an invalid model to be rejected")
+	}
+
+	restored := NewLeagueService()
+	if _, err := restored.ImportSnapshot(bytes.NewReader(encodeSnapshot(t, original))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if model := restored.GetLeague().Settings.Model; model != chaotic {
+		t.Errorf("Expected the chaotic model to be restored, got %+v", model)
+	}
+}
+
+func TestSnapshotMigratesPlainLeague(t *testing.T) {
+	original := newPlayedLeagueService(t, 3)
+
+	// The plain league JSON of version 1 has no settings, seed or prediction history
+	var legacy map[string]any
+	data, _ := json.Marshal(original.GetLeague())
+	json.Unmarshal(data, &legacy)
+	delete(legacy, "settings")
+	delete(legacy, "seed")
+	delete(legacy, "predictionHistory")
+	data, _ = json.Marshal(legacy)
+
+	restored := NewLeagueService()
+	snapshot, err := restored.ImportSnapshot(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if snapshot.SchemaVersion != models.SnapshotSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", models.SnapshotSchemaVersion, snapshot.SchemaVersion)
+	}
+	if snapshot.League.Settings != models.DefaultLeagueSettings() || snapshot.League.Seed == 0 {
+		t.Errorf("Expected default settings and a new seed, got %+v and %d", snapshot.League.Settings, snapshot.League.Seed)
+	}
+	if snapshot.League.CurrentWeek != 3 {
+		t.Errorf("Expected week 3, got %d", snapshot.League.CurrentWeek)
+	}
+}
+
+func TestSnapshotRejectsInvalid(t *testing.T) {
+	service := newPlayedLeagueService(t, 1)
+	valid := string(encodeSnapshot(t, service))
+	league := service.GetLeague()
+
+	tests := map[string]string{
+		"newer version": strings.Replace(valid, `"schemaVersion":2`, `"schemaVersion":3`, 1),
+		"wrong kind":    strings.Replace(valid, `"kind":"stadia.league"`, `"kind":"other"`, 1),
+		"not a league":  `{"name": "x"}`,
+		"unknown team":  strings.Replace(valid, league.GetMatchesByWeek(1)[0].HomeTeamID, "missing", 1),
+		"invalid json":  `{`,
+	}
+
+	for name, data := range tests {
+		if _, err := service.ImportSnapshot(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if service.GetLeague() != league {
+		t.Error("A rejected snapshot should leave the league unchanged")
+	}
+}
+
+func TestSnapshotFileRoundTrip(t *testing.T) {
+	original := newPlayedLeagueService(t, 2)
+	path := filepath.Join(t.TempDir(), "league.json")
+
+	if err := original.SaveSnapshotFile(path, "1.2.3"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	restored := NewLeagueService()
+	if err := restored.LoadSnapshotFile(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restored.GetLeague().CurrentWeek != 2 || restored.GetLeague().Seed != original.GetLeague().Seed {
+		t.Error("Expected the saved league to be restored")
+	}
+
+	if err := restored.LoadSnapshotFile(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing file error, got %v", err)
+	}
+}
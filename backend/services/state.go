@@ -0,0 +1,140 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"stadia-backend/models"
+	"time"
+)
+
+// Records returns every league with its ownership, sharing links and, once
+// initialized, a snapshot of its state, for the state file
+func (r *LeagueRegistry) Records() []*models.LeagueRecord {
+	// Leagues are locked after releasing the registry, as requests holding a
+	// league's lock may wait for the registry to change its sharing links
+	r.mu.RLock()
+	leagues := make([]*registeredLeague, 0, len(r.leagues))
+	records := make([]*models.LeagueRecord, 0, len(r.leagues))
+	for _, league := range r.leagues {
+		leagues = append(leagues, league)
+		records = append(records, &models.LeagueRecord{LeagueInfo: league.info, Shares: league.shareList()})
+	}
+	r.mu.RUnlock()
+
+	for i, league := range leagues {
+		league.lock.RLock()
+		if len(league.service.GetLeague().Teams) > 0 {
+			records[i].Snapshot = league.service.ExportSnapshot()
+		}
+		league.lock.RUnlock()
+	}
+	sortByCreation(records, func(record *models.LeagueRecord) time.Time { return record.CreatedAt })
+	return records
+}
+
+// Restore replaces every league with the ones from the state file. Nothing
+// changes if any league fails to restore.
+func (r *LeagueRegistry) Restore(records []*models.LeagueRecord) error {
+	leagues := make(map[string]*registeredLeague, len(records)+1)
+	for _, record := range records {
+		if record == nil || record.ID == "" {
+			return errors.New("league without an ID")
+		}
+		league := r.newRegisteredLeague(record.ID, record.Name, record.OwnerID)
+		league.info.CreatedAt = record.CreatedAt
+		for _, share := range record.Shares {
+			league.shares[share.Token] = share
+		}
+		if record.Snapshot != nil {
+			if err := league.service.RestoreSnapshot(record.Snapshot); err != nil {
+				return fmt.Errorf("league %s: %w", record.ID, err)
+			}
+		}
+		leagues[record.ID] = league
+	}
+	if leagues[DefaultLeagueID] == nil {
+		leagues[DefaultLeagueID] = r.newRegisteredLeague(DefaultLeagueID, "Default League", "")
+	}
+
+	r.mu.Lock()
+	r.leagues = leagues
+	r.mu.Unlock()
+	return nil
+}
+
+// SaveStateFile writes the accounts and every league to a state file. The
+// file is replaced only once the state has been written in full.
+func (r *LeagueRegistry) SaveStateFile(path, appVersion string, accounts models.Accounts) error {
+	state := &models.ServerState{
+		Kind:          models.StateKind,
+		SchemaVersion: models.StateSchemaVersion,
+		AppVersion:    appVersion,
+		SavedAt:       time.Now().UTC(),
+		Accounts:      accounts,
+		Leagues:       r.Records(),
+	}
+	if state.Users == nil {
+		state.Users = []*models.UserRecord{}
+	}
+	if state.APIKeys == nil {
+		state.APIKeys = []*models.APIKeyRecord{}
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// PingStateFile checks that the state file can be saved, by writing a
+// temporary file next to it
+func PingStateFile(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".stadia-ping-*")
+	if err != nil {
+		return fmt.Errorf("state file directory is not writable: %w", err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// LoadStateFile restores every league from a state file and returns the
+// accounts in it. A league snapshot file, as written before leagues had
+// owners, is restored as the default league.
+func (r *LeagueRegistry) LoadStateFile(path string) (models.Accounts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return models.Accounts{}, err
+	}
+
+	var header struct {
+		Kind          string `json:"kind"`
+		SchemaVersion int    `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return models.Accounts{}, fmt.Errorf("reading %s: invalid state: %w", path, err)
+	}
+	if header.Kind != models.StateKind {
+		return models.Accounts{}, r.Default().LoadSnapshotFile(path)
+	}
+	if header.SchemaVersion > models.StateSchemaVersion {
+		return models.Accounts{}, fmt.Errorf("reading %s: state schema version %d is newer than the supported version %d",
+			path, header.SchemaVersion, models.StateSchemaVersion)
+	}
+
+	var state models.ServerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return models.Accounts{}, fmt.Errorf("reading %s: invalid state: %w", path, err)
+	}
+	if err := r.Restore(state.Leagues); err != nil {
+		return models.Accounts{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return state.Accounts, nil
+}
@@ -0,0 +1,65 @@
+// Package tracing exports OpenTelemetry traces over OTLP
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Settings configures the trace exporter
+type Settings struct {
+	Endpoint    string  // OTLP/HTTP collector, as host:port
+	Insecure    bool    // Export over plain HTTP instead of HTTPS
+	SampleRatio float64 // Share of traces recorded, from 0 to 1
+	ServiceName string
+	Version     string
+}
+
+// Setup exports traces to an OTLP collector and makes the exporting tracer
+// provider and W3C trace context propagation the global defaults. The returned
+// function flushes the spans not yet exported and stops exporting.
+func Setup(ctx context.Context, settings Settings) (func(context.Context) error, error) {
+	if settings.Endpoint == "" {
+		return nil, errors.New("tracing endpoint is required")
+	}
+	if settings.SampleRatio < 0 || settings.SampleRatio > 1 {
+		return nil, fmt.Errorf("sample ratio %g is not between 0 and 1", settings.SampleRatio)
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(settings.Endpoint)}
+	if settings.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace exporter: %w", err)
+	}
+
+	provider := NewProvider(sdktrace.NewBatchSpanProcessor(exporter, sdktrace.WithBatchTimeout(5*time.Second)), settings)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider that hands spans to a processor, for
+// example a synchronous one over an in-memory exporter in tests. Child spans
+// follow the sampling decision of their parent.
+func NewProvider(processor sdktrace.SpanProcessor, settings Settings) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(settings.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(settings.ServiceName),
+			semconv.ServiceVersion(settings.Version),
+		)),
+	)
+}
@@ -0,0 +1,22 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetupValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+	}{
+		{"no endpoint", Settings{SampleRatio: 1}},
+		{"negative ratio", Settings{Endpoint: "localhost:4318", SampleRatio: -0.1}},
+		{"ratio above one", Settings{Endpoint: "localhost:4318", SampleRatio: 1.5}},
+	}
+	for _, test := range tests {
+		if _, err := Setup(context.Background(), test.settings); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}